@@ -0,0 +1,51 @@
+// Package cmd Chromatogram top level command
+package cmd
+
+import (
+	"os"
+
+	"philosopher/lib/chr"
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+
+	"github.com/spf13/cobra"
+)
+
+// chromatogramCmd represents the chromatogram command
+var chromatogramCmd = &cobra.Command{
+	Use:   "chromatogram",
+	Short: "Export TIC, base peak and extracted ion chromatograms",
+	Run: func(cmd *cobra.Command, args []string) {
+
+		m.FunctionInitCheckUp()
+
+		msg.Executing("Chromatogram ", Version)
+
+		chr.Run(m, args)
+
+		// store parameters on meta data
+		m.Serialize()
+
+		// clean tmp
+		met.CleanTemp(m.Temp)
+
+		msg.Done()
+		return
+	},
+}
+
+func init() {
+
+	if len(os.Args) > 1 && os.Args[1] == "chromatogram" {
+
+		m.Restore(sys.Meta())
+
+		chromatogramCmd.Flags().Float64SliceVarP(&m.Chromatogram.XIC, "xic", "", []float64{}, "m/z values for the extracted ion chromatograms (comma separated)")
+		chromatogramCmd.Flags().Float64VarP(&m.Chromatogram.Tol, "ppm", "", 10, "m/z tolerance in ppm for the extracted ion chromatograms")
+		chromatogramCmd.Flags().BoolVarP(&m.Chromatogram.Stored, "stored", "", false, "include the chromatograms stored in the mzML files")
+		chromatogramCmd.Flags().IntVarP(&m.Chromatogram.Controller, "controller", "", 0, "include the data from an additional instrument controller on RAW files")
+	}
+
+	RootCmd.AddCommand(chromatogramCmd)
+}
@@ -38,14 +38,33 @@ func init() {
 
 		databaseCmd.Flags().StringVarP(&m.Database.ID, "id", "", "", "UniProt proteome ID")
 		databaseCmd.Flags().StringVarP(&m.Database.Annot, "annotate", "", "", "process a ready-to-use database")
-		databaseCmd.Flags().StringVarP(&m.Database.Enz, "enzyme", "", "trypsin", "enzyme for digestion (trypsin, lys_c, lys_n, glu_c, chymotrypsin)")
+		databaseCmd.Flags().StringVarP(&m.Database.Enz, "enzyme", "", "trypsin", "enzyme for digestion (trypsin, trypsin/p, lys_c, lys_n, arg_c, asp_n, glu_c, chymotrypsin, lysarginase, pepsin), combinations like trypsin+asp_n, or a YAML/JSON rules file")
 		databaseCmd.Flags().StringVarP(&m.Database.Tag, "prefix", "", "rev_", "define a decoy prefix")
+		databaseCmd.Flags().StringVarP(&m.Database.DecoyMethod, "decoy-method", "", "reverse", "method for generating decoys (reverse, pseudo-reverse, shuffle)")
+		databaseCmd.Flags().Int64VarP(&m.Database.DecoySeed, "decoy-seed", "", 1, "random seed for the shuffle decoy method")
 		databaseCmd.Flags().StringVarP(&m.Database.Add, "add", "", "", "add custom sequences (UniProt FASTA format only)")
 		databaseCmd.Flags().StringVarP(&m.Database.Custom, "custom", "", "", "use a pre-formatted custom database")
 		databaseCmd.Flags().BoolVarP(&m.Database.Crap, "contam", "", false, "add common contaminants")
+		databaseCmd.Flags().StringVarP(&m.Database.ContamFiles, "contam-files", "", "", "add the contaminants from custom FASTA files, given as a comma-separated list")
+		databaseCmd.Flags().StringVarP(&m.Database.ContamTag, "contam-tag", "", "con_", "prefix tag added to contaminant sequences")
+		databaseCmd.Flags().BoolVarP(&m.Database.Collapse, "collapse", "", false, "collapse entries with identical sequences into one protein with aliases")
+		databaseCmd.Flags().BoolVarP(&m.Database.CollapseIL, "collapse-il", "", false, "collapse entries with sequences that are identical when I and L are not distinguished")
 		databaseCmd.Flags().BoolVarP(&m.Database.Rev, "reviewed", "", false, "use only reviwed sequences from Swiss-Prot")
 		databaseCmd.Flags().BoolVarP(&m.Database.Iso, "isoform", "", false, "add isoform sequences")
 		databaseCmd.Flags().BoolVarP(&m.Database.NoD, "nodecoys", "", false, "don't add decoys to the database")
+		databaseCmd.Flags().StringVarP(&m.Database.HeaderRegex, "header-regex", "", "", "regular expression with named groups (id, entry_name, protein_name, gene_names, organism, description) for parsing the FASTA headers")
+		databaseCmd.Flags().StringVarP(&m.Database.HeaderTemplates, "header-templates", "", "", "YAML file with a list of FASTA header templates")
+		databaseCmd.Flags().StringVarP(&m.Database.Variants, "variants", "", "", "add variant entries from a table with protein ID, position, reference and alternative residues, or protein ID and protein change")
+		databaseCmd.Flags().StringVarP(&m.Database.Annotation, "annotation", "", "", "add GO terms, keywords and sequence features from a local UniProt text (.dat) or XML file")
+		databaseCmd.Flags().BoolVarP(&m.Database.Stats, "stats", "", false, "report statistics of the database in the workspace")
+		databaseCmd.Flags().BoolVarP(&m.Database.Digest.Report, "digest", "", false, "report the theoretical peptides of each protein")
+		databaseCmd.Flags().IntVarP(&m.Database.Digest.MissedCleavages, "missed", "", 2, "maximum number of missed cleavages for the digestion report")
+		databaseCmd.Flags().IntVarP(&m.Database.Digest.MinLength, "minlength", "", 7, "minimum peptide length for the digestion report")
+		databaseCmd.Flags().IntVarP(&m.Database.Digest.MaxLength, "maxlength", "", 50, "maximum peptide length for the digestion report (0 for no limit)")
+		databaseCmd.Flags().Float64VarP(&m.Database.Digest.MinMass, "minmass", "", 0, "minimum peptide mass for the digestion report")
+		databaseCmd.Flags().Float64VarP(&m.Database.Digest.MaxMass, "maxmass", "", 0, "maximum peptide mass for the digestion report (0 for no limit)")
+		databaseCmd.Flags().BoolVarP(&m.Database.Digest.ClipNTermM, "clipm", "", true, "also digest the proteins without their N-terminal methionine")
+		databaseCmd.Flags().StringVarP(&m.Database.Digest.Specificity, "specificity", "", "specific", "digestion specificity (specific, semi, nonspecific)")
 	}
 
 	RootCmd.AddCommand(databaseCmd)
@@ -84,6 +84,10 @@ func init() {
 		filterCmd.Flags().BoolVarP(&m.Filter.Picked, "picked", "", false, "apply the picked FDR algorithm before the protein scoring")
 		filterCmd.Flags().BoolVarP(&m.Filter.Mapmods, "mapmods", "", false, "map modifications")
 		filterCmd.Flags().BoolVarP(&m.Filter.Inference, "inference", "", false, "extremely fast and efficient protein inference compatible with 2D and Sequential filters")
+		filterCmd.Flags().StringVarP(&m.Filter.DBCheck, "dbcheck", "", "error", "action when the searched database is not the workspace database (error, warning, none)")
+		filterCmd.Flags().StringVarP(&m.Filter.Secondary, "secondary", "", "none", "policy for lower-ranked hits (none, best, fdr)")
+		filterCmd.Flags().BoolVarP(&m.Filter.Stream, "stream", "", false, "read the pepXML files one spectrum at a time to filter very large result files with bounded memory")
+		filterCmd.Flags().StringVarP(&m.Filter.Percolator, "percolator", "", "", "comma separated Percolator pout or mokapot PSM files replacing the pepXML probabilities")
 		filterCmd.Flags().BoolVarP(&m.Filter.Fo, "fo", "", false, "")
 		filterCmd.Flags().MarkHidden("fo")
 		filterCmd.Flags().MarkHidden("mods")
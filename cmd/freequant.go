@@ -22,7 +22,6 @@ var freequant = &cobra.Command{
 
 		m.FunctionInitCheckUp()
 
-		m.Quantify.Format = "mzML"
 		if len(m.Quantify.Dir) < 1 {
 			msg.InputNotFound(errors.New("You need to provide the path to the mz files and the correct extension"), "fatal")
 		}
@@ -32,8 +31,11 @@ var freequant = &cobra.Command{
 		if strings.EqualFold(m.Quantify.Format, "mzml") {
 			m.Quantify.Format = "mzML"
 		} else if strings.EqualFold(m.Quantify.Format, "mzxml") {
-			msg.InputNotFound(errors.New("Only the mzML format is supported"), "fatal")
 			m.Quantify.Format = "mzXML"
+		} else if strings.EqualFold(m.Quantify.Format, "raw") {
+			m.Quantify.Format = "raw"
+		} else if strings.EqualFold(m.Quantify.Format, "mgf") {
+			m.Quantify.Format = "mgf"
 		} else {
 			msg.InputNotFound(errors.New("Unknown file format"), "fatal")
 		}
@@ -63,7 +65,10 @@ func init() {
 		m.Restore(sys.Meta())
 
 		freequant.Flags().StringVarP(&m.Quantify.Dir, "dir", "", "", "folder path containing the raw files")
+		freequant.Flags().StringVarP(&m.Quantify.Format, "format", "", "mzML", "format of the raw files (mzML, mzXML, raw)")
 		freequant.Flags().Float64VarP(&m.Quantify.Tol, "tol", "", 10, "m/z tolerance in ppm")
+		freequant.Flags().Float64VarP(&m.Quantify.MobTol, "mobtol", "", 0, "ion mobility (1/K0) tolerance, 0 ignores the ion mobility")
+		freequant.Flags().IntVarP(&m.Quantify.Threads, "threads", "", 1, "number of threads used for decoding the spectra")
 		freequant.Flags().Float64VarP(&m.Quantify.PTWin, "ptw", "", 0.4, "specify the time windows for the peak (minute)")
 		freequant.Flags().BoolVarP(&m.Quantify.Isolated, "isolated", "", true, "use the isolated ion instead of the selected ion for quantification")
 		filterCmd.Flags().MarkHidden("isolated")
@@ -22,8 +22,6 @@ var labelquantCmd = &cobra.Command{
 
 		m.FunctionInitCheckUp()
 
-		m.Quantify.Format = "mzML"
-
 		if len(m.Quantify.Format) < 1 || len(m.Quantify.Dir) < 1 {
 			msg.InputNotFound(errors.New("You need to provide the path to the mz files and the correct extension"), "fatal")
 		}
@@ -37,8 +35,11 @@ var labelquantCmd = &cobra.Command{
 		if strings.EqualFold(strings.ToLower(m.Quantify.Format), "mzml") {
 			m.Quantify.Format = "mzML"
 		} else if strings.EqualFold(m.Quantify.Format, "mzxml") {
-			msg.InputNotFound(errors.New("Only the mzML format is supported"), "fatal")
 			m.Quantify.Format = "mzXML"
+		} else if strings.EqualFold(m.Quantify.Format, "raw") {
+			m.Quantify.Format = "raw"
+		} else if strings.EqualFold(m.Quantify.Format, "mgf") {
+			m.Quantify.Format = "mgf"
 		} else {
 			msg.InputNotFound(errors.New("Unknown file format"), "fatal")
 		}
@@ -65,9 +66,11 @@ func init() {
 		labelquantCmd.Flags().StringVarP(&m.Quantify.Annot, "annot", "", "", "annotation file with custom names for the TMT channels")
 		labelquantCmd.Flags().StringVarP(&m.Quantify.Plex, "plex", "", "", "number of reporter ion channels")
 		labelquantCmd.Flags().StringVarP(&m.Quantify.Dir, "dir", "", "", "folder path containing the raw files")
+		labelquantCmd.Flags().StringVarP(&m.Quantify.Format, "format", "", "mzML", "format of the raw files (mzML, mzXML, raw, mgf)")
 		labelquantCmd.Flags().StringVarP(&m.Quantify.Brand, "brand", "", "", "isobaric labeling brand (tmt, itraq)")
 		labelquantCmd.Flags().Float64VarP(&m.Quantify.Tol, "tol", "", 20, "m/z tolerance in ppm")
 		labelquantCmd.Flags().IntVarP(&m.Quantify.Level, "level", "", 2, "ms level for the quantification")
+		labelquantCmd.Flags().IntVarP(&m.Quantify.Threads, "threads", "", 1, "number of threads used for decoding the spectra")
 		labelquantCmd.Flags().Float64VarP(&m.Quantify.Purity, "purity", "", 0.5, "ion purity threshold")
 		labelquantCmd.Flags().Float64VarP(&m.Quantify.MinProb, "minprob", "", 0.7, "only use PSMs with the specified minimum probability score")
 		labelquantCmd.Flags().Float64VarP(&m.Quantify.RemoveLow, "removelow", "", 0.0, "ignore the lower % of PSMs based on their summed abundances. 0 means no removal, entry value must be a decimal")
@@ -44,6 +44,11 @@ func init() {
 		reportCmd.Flags().BoolVarP(&m.Report.Decoys, "decoys", "", false, "add decoy observations to reports")
 		reportCmd.Flags().BoolVarP(&m.Report.MSstats, "msstats", "", false, "create an output compatible with MSstats")
 		reportCmd.Flags().BoolVarP(&m.Report.MZID, "mzid", "", false, "create a mzID output")
+		reportCmd.Flags().BoolVarP(&m.Report.MGF, "mgf", "", false, "export the spectra of the reported PSMs as MGF")
+		reportCmd.Flags().BoolVarP(&m.Report.MzML, "mzml", "", false, "export the spectra of the reported PSMs as indexed mzML")
+		reportCmd.Flags().BoolVarP(&m.Report.PepXML, "pepxml", "", false, "export the reported PSMs as pepXML")
+		reportCmd.Flags().BoolVarP(&m.Report.ProtXML, "protxml", "", false, "export the filtered protein groups as protXML")
+		reportCmd.Flags().StringVarP(&m.Report.Dir, "dir", "", "", "folder path containing the raw files")
 	}
 
 	RootCmd.AddCommand(reportCmd)
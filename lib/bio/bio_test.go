@@ -1,6 +1,9 @@
 package bio_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	. "philosopher/lib/bio"
 	"philosopher/lib/tes"
 	"testing"
@@ -145,3 +148,116 @@ func TestEnzymes(t *testing.T) {
 		t.Errorf("Enzyme is incorrect, got %s, want %s", e.Name, "glu_c")
 	}
 }
+
+func TestEnzymeCleaves(t *testing.T) {
+
+	var e Enzyme
+
+	e.Synth("trypsin")
+	seq := "AKPLRGEK"
+	want := []bool{false, false, false, false, true, false, false, false}
+	for i := range seq {
+		if got := e.Cleaves(seq, i); got != want[i] {
+			t.Errorf("trypsin Cleaves(%s, %d) = %v, want %v", seq, i, got, want[i])
+		}
+	}
+
+	e.Synth("lys_n")
+	if !e.Cleaves("AKPK", 0) || e.Cleaves("AKPK", 1) || !e.Cleaves("AKPK", 2) {
+		t.Errorf("lys_n should cleave before lysine")
+	}
+}
+
+func TestDigest(t *testing.T) {
+
+	var e Enzyme
+	e.Synth("trypsin")
+
+	seq := "MAKPEPTIDERGGK"
+
+	got := e.Digest(seq, Digestion{MissedCleavages: 0, MinLength: 1})
+	want := []string{"MAKPEPTIDER", "GGK"}
+	if len(got) != len(want) {
+		t.Fatalf("Digest() returned %d peptides, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Sequence != want[i] {
+			t.Errorf("Digest() peptide %d = %s, want %s", i, got[i].Sequence, want[i])
+		}
+	}
+
+	if got[1].Start != 12 || got[1].End != 14 {
+		t.Errorf("Digest() positions = %d-%d, want 12-14", got[1].Start, got[1].End)
+	}
+
+	// one missed cleavage and the clipped methionine add MAKPEPTIDERGGK, AKPEPTIDER and AKPEPTIDERGGK
+	got = e.Digest(seq, Digestion{MissedCleavages: 1, MinLength: 1, ClipNTermM: true})
+	if len(got) != 5 {
+		t.Errorf("Digest() with missed cleavages returned %d peptides, want 5", len(got))
+	}
+
+	// PEPTID, PEPTIDE, PEPTIDEK, EPTIDEK and PTIDEK
+	got = e.Digest("PEPTIDEK", Digestion{Specificity: "semi", MinLength: 6})
+	if len(got) != 5 {
+		t.Errorf("semi-specific Digest() returned %d peptides, want 5", len(got))
+	}
+
+	got = e.Digest("PEPTIDEK", Digestion{Specificity: "nonspecific", MinLength: 6})
+	if len(got) != 6 {
+		t.Errorf("nonspecific Digest() returned %d peptides, want 6", len(got))
+	}
+
+	// GGK mass is 2 x 57.02146 + 128.09496 + 18.01056
+	got = e.Digest(seq, Digestion{MinLength: 1, MinMass: 250, MaxMass: 300})
+	if len(got) != 1 || got[0].Sequence != "GGK" {
+		t.Errorf("Digest() with mass range = %v, want GGK", got)
+	}
+}
+
+func TestEnzymeRules(t *testing.T) {
+
+	var e Enzyme
+
+	e.Synth("trypsin+asp_n")
+	if e.Name != "trypsin+asp_n" || e.Sense != "" {
+		t.Errorf("Enzyme combination is incorrect, got %s with sense %q", e.Name, e.Sense)
+	}
+
+	if e.CleavageSense("AKDE", 0) != "" || e.CleavageSense("AGDE", 1) != "N" || e.CleavageSense("AKRE", 1) != "C" {
+		t.Errorf("trypsin+asp_n cleavage sites are incorrect")
+	}
+
+	e.Synth("trypsin")
+	if got := e.NTT("K", "APEPTIDER", "P"); got != 1 {
+		t.Errorf("NTT() = %d, want 1", got)
+	}
+	if got := e.NTT("-", "PEPTIDER", "A"); got != 2 {
+		t.Errorf("NTT() = %d, want 2", got)
+	}
+
+	want := "trypsin (cleaves after K and R, not before P)"
+	if got := e.Description(); got != want {
+		t.Errorf("Description() = %s, want %s", got, want)
+	}
+
+	dir, err := ioutil.TempDir("", "enzyme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "enzyme.json")
+	rules := `{"name": "custom", "rules": [{"cut_before": "d", "no_cut_if_preceded_by": "P"}]}`
+	if err := ioutil.WriteFile(f, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Synth(f)
+	if e.Name != "custom" || e.Sense != "N" {
+		t.Errorf("Enzyme from file is incorrect, got %s with sense %q", e.Name, e.Sense)
+	}
+
+	if !e.Cleaves("ADE", 0) || e.Cleaves("PDE", 0) {
+		t.Errorf("custom enzyme cleavage sites are incorrect")
+	}
+}
@@ -0,0 +1,152 @@
+package bio
+
+import (
+	"strings"
+)
+
+// Digestion options for the in-silico digestion of protein sequences. A zero maximum
+// length or mass means no upper limit
+type Digestion struct {
+	MissedCleavages int
+	MinLength       int
+	MaxLength       int
+	MinMass         float64
+	MaxMass         float64
+	ClipNTermM      bool
+	Specificity     string
+}
+
+// Peptide is a product of the in-silico digestion, the positions are 1-based and
+// the mass is the monoisotopic neutral mass
+type Peptide struct {
+	Sequence        string
+	Start           int
+	End             int
+	MissedCleavages int
+	Mass            float64
+}
+
+// residueMasses holds the monoisotopic residue masses indexed by the one letter code
+var residueMasses = map[byte]float64{
+	'U': 150.953633405,
+	'O': 237.147726925,
+}
+
+func init() {
+
+	names := []string{"Alanine", "Arginine", "Asparagine", "Aspartic Acid", "Cysteine", "Glutamine", "Glutamic Acid",
+		"Glycine", "Histidine", "Isoleucine", "Leucine", "Lysine", "Methionine", "Phenylalanine", "Proline", "Serine",
+		"Threonine", "Tryptophan", "Tyrosine", "Valine"}
+
+	for _, i := range names {
+		aa := New(i)
+		residueMasses[aa.Code[0]] = aa.MonoIsotopeMass
+	}
+}
+
+// PeptideMass returns the monoisotopic neutral mass of a peptide sequence, the second
+// value is false when the sequence contains ambiguous residues like B, J, X or Z
+func PeptideMass(seq string) (float64, bool) {
+
+	var mass = Water
+
+	for i := 0; i < len(seq); i++ {
+		m, ok := residueMasses[seq[i]]
+		if !ok {
+			return 0, false
+		}
+		mass += m
+	}
+
+	return mass, true
+}
+
+// Digest cleaves a protein sequence with the enzyme. Specific digestion requires both
+// peptide termini to be cleavage sites or protein termini, semi-specific digestion
+// requires only one of them, and non-specific digestion accepts any sub-sequence.
+// Missed cleavages are not limited on non-specific digestion. When the N-terminal
+// methionine is clipped the second residue also counts as a protein terminus.
+// Peptides with ambiguous residues are left out when a mass range is given
+func (e Enzyme) Digest(seq string, d Digestion) []Peptide {
+
+	var peptides []Peptide
+
+	seq = strings.ToUpper(seq)
+	n := len(seq)
+
+	// isStart and isEnd mark the positions between residues where a peptide can
+	// start or end, sites counts the cleavage sites up to each position
+	isStart := make([]bool, n+1)
+	isEnd := make([]bool, n+1)
+	sites := make([]int, n+1)
+
+	isStart[0] = true
+	isEnd[n] = true
+
+	if d.ClipNTermM && n > 1 && seq[0] == 'M' {
+		isStart[1] = true
+	}
+
+	for i := 0; i < n; i++ {
+		sites[i+1] = sites[i]
+		if e.Cleaves(seq, i) {
+			isStart[i+1] = true
+			isEnd[i+1] = true
+			sites[i+1]++
+		}
+	}
+
+	hasMassRange := d.MinMass > 0 || d.MaxMass > 0
+
+	for s := 0; s < n; s++ {
+
+		if d.Specificity != "semi" && d.Specificity != "nonspecific" && !isStart[s] {
+			continue
+		}
+
+		for end := s + 1; end <= n; end++ {
+
+			length := end - s
+			if d.MaxLength > 0 && length > d.MaxLength {
+				break
+			}
+
+			// sites found inside the peptide, the one at its C-terminal end does not count
+			missed := sites[end-1] - sites[s]
+			if d.Specificity != "nonspecific" && missed > d.MissedCleavages {
+				break
+			}
+
+			if length < d.MinLength {
+				continue
+			}
+
+			switch d.Specificity {
+			case "semi":
+				if !isStart[s] && !isEnd[end] {
+					continue
+				}
+			case "nonspecific":
+			default:
+				if !isEnd[end] {
+					continue
+				}
+			}
+
+			p := Peptide{Sequence: seq[s:end], Start: s + 1, End: end, MissedCleavages: missed}
+
+			mass, ok := PeptideMass(p.Sequence)
+			p.Mass = mass
+
+			if hasMassRange {
+				if !ok || mass < d.MinMass || (d.MaxMass > 0 && mass > d.MaxMass) {
+					continue
+				}
+			}
+
+			peptides = append(peptides, p)
+		}
+	}
+
+	return peptides
+}
@@ -2,44 +2,223 @@ package bio
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"strings"
 
 	"philosopher/lib/msg"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 // Enzyme struct
 type Enzyme struct {
-	Name    string
-	Pattern string
-	Join    string
+	Name  string `yaml:"name"`
+	Sense string `yaml:"-"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule describes one cleavage specificity of an enzyme. Residues in CutAfter are cut
+// on their C-terminal side unless the next residue is in NoCutIfFollowedBy, residues
+// in CutBefore are cut on their N-terminal side unless the previous residue is in
+// NoCutIfPrecededBy
+type Rule struct {
+	CutAfter          string `yaml:"cut_after"`
+	CutBefore         string `yaml:"cut_before"`
+	NoCutIfFollowedBy string `yaml:"no_cut_if_followed_by"`
+	NoCutIfPrecededBy string `yaml:"no_cut_if_preceded_by"`
+}
+
+// enzymes are the built-in enzyme definitions
+var enzymes = map[string][]Rule{
+	"trypsin":      {{CutAfter: "KR", NoCutIfFollowedBy: "P"}},
+	"trypsin/p":    {{CutAfter: "KR"}},
+	"lys_c":        {{CutAfter: "K", NoCutIfFollowedBy: "P"}},
+	"lys_n":        {{CutBefore: "K"}},
+	"arg_c":        {{CutAfter: "R", NoCutIfFollowedBy: "P"}},
+	"asp_n":        {{CutBefore: "D"}},
+	"glu_c":        {{CutAfter: "DE", NoCutIfFollowedBy: "P"}},
+	"chymotrypsin": {{CutAfter: "FWYL", NoCutIfFollowedBy: "P"}},
+	"lysarginase":  {{CutBefore: "KR"}},
+	"pepsin":       {{CutAfter: "FL"}},
 }
 
-// Synth is an enzyme builder
+// Synth is an enzyme builder. The name can be one of the built-in enzymes, a combination
+// of them joined by a plus sign like trypsin+asp_n, or a YAML or JSON file with the rules
 func (e *Enzyme) Synth(t string) {
 
-	if strings.EqualFold(strings.ToLower(t), "trypsin") {
-		e.Name = "trypsin"
-		e.Pattern = "KR[^P]"
-		e.Join = "KR"
-	} else if strings.EqualFold(strings.ToLower(t), "lys_c") {
-		e.Name = "lys_c"
-		e.Pattern = "K[^P]"
-		e.Join = "K"
-	} else if strings.EqualFold(strings.ToLower(t), "lys_n") {
-		e.Name = "lys_n"
-		e.Pattern = "K"
-		e.Join = "K"
-	} else if strings.EqualFold(strings.ToLower(t), "chymotrypsin") {
-		e.Name = "chymotrypsin"
-		e.Pattern = "FWYL[^P]"
-		e.Join = "K"
-	} else if strings.EqualFold(strings.ToLower(t), "glu_c") {
-		e.Name = "glu_c"
-		e.Pattern = "DE[^P]"
-		e.Join = "K"
-	} else {
-		msg.Custom(errors.New("Enzyme not supported"), "warning")
+	*e = Enzyme{}
+
+	if _, err := os.Stat(t); err == nil && (strings.HasSuffix(t, ".yml") || strings.HasSuffix(t, ".yaml") || strings.HasSuffix(t, ".json")) {
+
+		enz, err := LoadEnzyme(t)
+		if err != nil {
+			msg.ReadFile(err, "fatal")
+		}
+
+		*e = enz
+
+		return
 	}
 
+	var names []string
+
+	for _, i := range strings.Split(strings.ToLower(t), "+") {
+
+		rules, ok := enzymes[strings.TrimSpace(i)]
+		if !ok {
+			msg.Custom(errors.New("Enzyme not supported"), "warning")
+			*e = Enzyme{}
+			return
+		}
+
+		names = append(names, strings.TrimSpace(i))
+		e.Rules = append(e.Rules, rules...)
+	}
+
+	e.Name = strings.Join(names, "+")
+	e.Sense = e.sense()
+
 	return
 }
+
+// LoadEnzyme reads an enzyme definition from a YAML or JSON file, JSON is read
+// by the YAML parser with the same keys
+func LoadEnzyme(f string) (Enzyme, error) {
+
+	var e Enzyme
+
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		return e, err
+	}
+
+	err = yaml.Unmarshal(b, &e)
+	if err != nil {
+		return e, err
+	}
+
+	if len(e.Name) == 0 || len(e.Rules) == 0 {
+		return e, errors.New("the enzyme definition needs a name and at least one rule")
+	}
+
+	for i := range e.Rules {
+		e.Rules[i].CutAfter = strings.ToUpper(e.Rules[i].CutAfter)
+		e.Rules[i].CutBefore = strings.ToUpper(e.Rules[i].CutBefore)
+		e.Rules[i].NoCutIfFollowedBy = strings.ToUpper(e.Rules[i].NoCutIfFollowedBy)
+		e.Rules[i].NoCutIfPrecededBy = strings.ToUpper(e.Rules[i].NoCutIfPrecededBy)
+	}
+
+	e.Sense = e.sense()
+
+	return e, nil
+}
+
+// sense is C when all rules cut after the residues, N when all of them cut before,
+// and empty when the enzyme combines both
+func (e Enzyme) sense() string {
+
+	var after, before bool
+
+	for _, i := range e.Rules {
+		if len(i.CutAfter) > 0 {
+			after = true
+		}
+		if len(i.CutBefore) > 0 {
+			before = true
+		}
+	}
+
+	if after && !before {
+		return "C"
+	} else if before && !after {
+		return "N"
+	}
+
+	return ""
+}
+
+// CleavageSense tells how the enzyme cuts the sequence between the positions i and i+1.
+// It returns C when the cleavage residue is at i, N when it is at i+1, and an empty
+// string when the enzyme does not cut there
+func (e Enzyme) CleavageSense(seq string, i int) string {
+
+	if i < 0 || i+1 >= len(seq) {
+		return ""
+	}
+
+	for _, r := range e.Rules {
+		if strings.IndexByte(r.CutAfter, seq[i]) >= 0 && strings.IndexByte(r.NoCutIfFollowedBy, seq[i+1]) < 0 {
+			return "C"
+		}
+		if strings.IndexByte(r.CutBefore, seq[i+1]) >= 0 && strings.IndexByte(r.NoCutIfPrecededBy, seq[i]) < 0 {
+			return "N"
+		}
+	}
+
+	return ""
+}
+
+// Cleaves reports if the enzyme cuts the sequence between the positions i and i+1
+func (e Enzyme) Cleaves(seq string, i int) bool {
+	return len(e.CleavageSense(seq, i)) > 0
+}
+
+// NTT returns the number of enzymatic termini of a peptide given its flanking residues,
+// a dash marks the protein terminus
+func (e Enzyme) NTT(prev, peptide, next string) int {
+
+	var ntt int
+
+	if len(peptide) == 0 {
+		return ntt
+	}
+
+	if prev == "-" || (len(prev) > 0 && e.Cleaves(prev[len(prev)-1:]+peptide, 0)) {
+		ntt++
+	}
+
+	if next == "-" || (len(next) > 0 && e.Cleaves(peptide+next[:1], len(peptide)-1)) {
+		ntt++
+	}
+
+	return ntt
+}
+
+// Description describes the cleavage rules, e.g. trypsin (cleaves after K and R, not before P)
+func (e Enzyme) Description() string {
+
+	var rules []string
+
+	for _, i := range e.Rules {
+		if len(i.CutAfter) > 0 {
+			rule := "cleaves after " + residueList(i.CutAfter)
+			if len(i.NoCutIfFollowedBy) > 0 {
+				rule += ", not before " + residueList(i.NoCutIfFollowedBy)
+			}
+			rules = append(rules, rule)
+		}
+		if len(i.CutBefore) > 0 {
+			rule := "cleaves before " + residueList(i.CutBefore)
+			if len(i.NoCutIfPrecededBy) > 0 {
+				rule += ", not after " + residueList(i.NoCutIfPrecededBy)
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	return fmt.Sprintf("%s (%s)", e.Name, strings.Join(rules, "; "))
+}
+
+// residueList writes the residues as a list, e.g. F, W and Y
+func residueList(r string) string {
+
+	residues := strings.Split(r, "")
+
+	if len(residues) == 1 {
+		return residues[0]
+	}
+
+	return strings.Join(residues[:len(residues)-1], ", ") + " and " + residues[len(residues)-1]
+}
@@ -0,0 +1,217 @@
+// Package chr extracts chromatographic traces from the spectra files for quality control
+package chr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/mzn"
+
+	"github.com/sirupsen/logrus"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// Run is the chromatogram main entry point
+func Run(m met.Data, args []string) {
+
+	if len(args) < 1 {
+		msg.InputNotFound(errors.New("You need to provide at least one mzML, mzXML or RAW file"), "fatal")
+	}
+
+	for _, f := range args {
+
+		logrus.Info("Extracting chromatograms from ", filepath.Base(f))
+
+		traces := Extract(f, m.Chromatogram.XIC, m.Chromatogram.Tol)
+
+		if m.Chromatogram.Stored {
+			if strings.EqualFold(filepath.Ext(f), ".mzML") {
+				mzn.ForEachChromatogram(f, func(chrom mzn.Chromatogram) {
+					traces = append(traces, chrom)
+				})
+			} else {
+				logrus.Warn("Only mzML files have stored chromatograms, skipping ", filepath.Base(f))
+			}
+		}
+
+		if m.Chromatogram.Controller > 0 {
+			if strings.EqualFold(filepath.Ext(f), ".raw") {
+				traces = append(traces, mzn.RawChromatogram(f, m.Chromatogram.Controller))
+			} else {
+				logrus.Warn("Instrument controllers are only available on RAW files, skipping ", filepath.Base(f))
+			}
+		}
+
+		base := strings.TrimSuffix(filepath.Base(f), filepath.Ext(f))
+
+		writeTSV(fmt.Sprintf("%s.chromatogram.tsv", base), traces)
+		plotTraces(fmt.Sprintf("%s.chromatogram.svg", base), base, traces)
+	}
+
+	return
+}
+
+// Extract builds the total ion current, the base peak and the extracted ion chromatograms
+// from the MS1 scans. Each XIC sums the intensities within tol ppm of the target m/z
+func Extract(f string, xic []float64, tol float64) []mzn.Chromatogram {
+
+	var traces = make([]mzn.Chromatogram, 2+len(xic))
+
+	traces[0].ID = "TIC"
+	traces[1].ID = "BPC"
+	for i := range xic {
+		traces[2+i].ID = fmt.Sprintf("XIC %.4f", xic[i])
+	}
+
+	mzn.ForEachSpectrum(f, mzn.SpectrumFilter{Levels: []string{"1"}}, func(spec mzn.Spectrum) {
+
+		spec.Decode()
+
+		var tic, bpc float64
+		for _, i := range spec.Intensity.DecodedStream {
+			tic += i
+			if i > bpc {
+				bpc = i
+			}
+		}
+
+		intensities := append([]float64{tic, bpc}, xicIntensities(spec, xic, tol)...)
+
+		for i := range traces {
+			traces[i].Time = append(traces[i].Time, spec.ScanStartTime)
+			traces[i].Intensity = append(traces[i].Intensity, intensities[i])
+		}
+	})
+
+	if len(traces[0].Time) == 0 {
+		msg.NoSpectraFound(errors.New(f), "fatal")
+	}
+
+	return traces
+}
+
+// xicIntensities sums the peaks inside the ppm window of each target m/z
+func xicIntensities(spec mzn.Spectrum, xic []float64, tol float64) []float64 {
+
+	var intensities = make([]float64, len(xic))
+
+	mz := spec.Mz.DecodedStream
+
+	// peaks are not guaranteed to be sorted on every file format
+	if !sort.Float64sAreSorted(mz) {
+		order := make([]int, len(mz))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return mz[order[i]] < mz[order[j]] })
+
+		sortedMz := make([]float64, len(mz))
+		sortedInt := make([]float64, len(mz))
+		for i, j := range order {
+			sortedMz[i] = mz[j]
+			sortedInt[i] = spec.Intensity.DecodedStream[j]
+		}
+		spec.Mz.DecodedStream = sortedMz
+		spec.Intensity.DecodedStream = sortedInt
+		mz = sortedMz
+	}
+
+	for i, target := range xic {
+
+		delta := target * tol / 1e6
+
+		for j := sort.SearchFloat64s(mz, target-delta); j < len(mz) && mz[j] <= target+delta; j++ {
+			if j < len(spec.Intensity.DecodedStream) {
+				intensities[i] += spec.Intensity.DecodedStream[j]
+			}
+		}
+	}
+
+	return intensities
+}
+
+// writeTSV prints all traces in a long table format
+func writeTSV(output string, traces []mzn.Chromatogram) {
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+
+	fmt.Fprintln(w, "Trace\tRetention Time\tIntensity")
+
+	for _, i := range traces {
+		for j := range i.Time {
+			if j < len(i.Intensity) {
+				fmt.Fprintf(w, "%s\t%.4f\t%.4f\n", i.ID, i.Time[j], i.Intensity[j])
+			}
+		}
+	}
+
+	if e := w.Flush(); e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	return
+}
+
+// plotTraces draws all traces in one SVG file, each one scaled to its own maximum
+// so that the low abundant XICs are visible next to the TIC
+func plotTraces(output, title string, traces []mzn.Chromatogram) {
+
+	p, e := plot.New()
+	if e != nil {
+		msg.Plotter(e, "fatal")
+	}
+
+	p.Title.Text = title
+	p.X.Label.Text = "Retention Time (min)"
+	p.Y.Label.Text = "Relative Intensity (%)"
+
+	var lines []interface{}
+
+	for _, i := range traces {
+
+		var max float64
+		for _, j := range i.Intensity {
+			max = math.Max(max, j)
+		}
+
+		pts := make(plotter.XYs, 0, len(i.Time))
+		for j := range i.Time {
+			if j < len(i.Intensity) {
+				var y float64
+				if max > 0 {
+					y = 100 * i.Intensity[j] / max
+				}
+				pts = append(pts, plotter.XY{X: i.Time[j], Y: y})
+			}
+		}
+
+		lines = append(lines, i.ID, pts)
+	}
+
+	if e := plotutil.AddLines(p, lines...); e != nil {
+		msg.Plotter(e, "fatal")
+	}
+
+	if e := p.Save(12*vg.Inch, 6*vg.Inch, output); e != nil {
+		msg.Plotter(e, "fatal")
+	}
+
+	return
+}
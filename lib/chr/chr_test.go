@@ -0,0 +1,23 @@
+package chr
+
+import (
+	"reflect"
+	"testing"
+
+	"philosopher/lib/mzn"
+)
+
+func TestXicIntensities(t *testing.T) {
+
+	var spec mzn.Spectrum
+	spec.Mz.DecodedStream = []float64{500.0040, 300.0, 500.0, 500.0060, 700.0}
+	spec.Intensity.DecodedStream = []float64{20, 1, 10, 40, 5}
+
+	// 10 ppm of 500 is 0.005, the peak at 500.006 falls outside the window
+	got := xicIntensities(spec, []float64{500.0, 700.0, 900.0}, 10)
+	want := []float64{30, 5, 0}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("xicIntensities() = %v, want %v", got, want)
+	}
+}
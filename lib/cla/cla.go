@@ -85,3 +85,18 @@ func IsDecoyIon(names map[string]int, tag string) bool {
 
 	return class
 }
+
+// IsContaminant identifies a Protein as a contaminant based on the contaminant tag,
+// decoys of contaminants carry the decoy tag before the contaminant tag
+func IsContaminant(name, decoyTag, contamTag string) bool {
+
+	if len(contamTag) == 0 {
+		return false
+	}
+
+	if len(decoyTag) > 0 {
+		name = strings.TrimPrefix(name, decoyTag)
+	}
+
+	return strings.HasPrefix(name, contamTag)
+}
@@ -0,0 +1,417 @@
+package dat
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// Annotation holds the UniProt knowledge about a protein entry
+type Annotation struct {
+	GO             []string
+	Keywords       []string
+	PTMSites       []Feature
+	SignalPeptides []Feature
+	Chains         []Feature
+}
+
+// Feature is a sequence annotation spanning the 1-based positions Begin to End
+type Feature struct {
+	Description string
+	Begin       int
+	End         int
+}
+
+// featureKinds maps the UniProt feature keys of the text and XML formats to the
+// annotation they are stored in
+var featureKinds = map[string]string{
+	"MOD_RES":                     "ptm",
+	"CARBOHYD":                    "ptm",
+	"LIPID":                       "ptm",
+	"CROSSLNK":                    "ptm",
+	"SIGNAL":                      "signal",
+	"CHAIN":                       "chain",
+	"modified residue":            "ptm",
+	"glycosylation site":          "ptm",
+	"lipid moiety-binding region": "ptm",
+	"cross-link":                  "ptm",
+	"signal peptide":              "signal",
+	"chain":                       "chain",
+}
+
+// addFeature stores the feature according to its kind
+func (a *Annotation) addFeature(kind string, f Feature) {
+
+	switch featureKinds[kind] {
+	case "ptm":
+		a.PTMSites = append(a.PTMSites, f)
+	case "signal":
+		a.SignalPeptides = append(a.SignalPeptides, f)
+	case "chain":
+		a.Chains = append(a.Chains, f)
+	}
+
+	return
+}
+
+// ReadUniProtAnnotation parses a local UniProt export in the text (.dat, .txt) or the
+// XML format, and returns the annotations indexed by every accession of the entries
+func ReadUniProtAnnotation(f string) (map[string]Annotation, error) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		return nil, e
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(f))
+
+	if ext == ".xml" {
+		return readUniProtXML(file)
+	}
+
+	return readUniProtText(file)
+}
+
+// evidenceReg matches the evidence tags of the text format, e.g. {ECO:0000269|PubMed:123}
+var evidenceReg = regexp.MustCompile(`\s*\{[^}]*\}`)
+
+// readUniProtText parses the UniProt flat file format, both the current feature table
+// layout with position ranges like 1..20 and the older column layout are accepted
+func readUniProtText(r io.Reader) (map[string]Annotation, error) {
+
+	var annotations = make(map[string]Annotation)
+
+	var accessions []string
+	var ann Annotation
+	var keywords string
+
+	// the feature being read, its qualifiers come on the following lines. Descriptions
+	// continue on the next lines on the older layout, or until the note is closed
+	var kind string
+	var feature Feature
+	var inFeature, continuation bool
+
+	closeFeature := func() {
+		if inFeature {
+			feature.Description = strings.TrimSpace(feature.Description)
+			ann.addFeature(kind, feature)
+		}
+		inFeature = false
+		continuation = false
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+
+	for scanner.Scan() {
+
+		line := scanner.Text()
+
+		if len(line) < 2 {
+			continue
+		}
+
+		code := line[:2]
+		content := ""
+		if len(line) > 5 {
+			content = line[5:]
+		}
+
+		switch code {
+		case "AC":
+			for _, i := range strings.Split(content, ";") {
+				if i = strings.TrimSpace(i); len(i) > 0 {
+					accessions = append(accessions, i)
+				}
+			}
+		case "DR":
+			part := strings.Split(content, "; ")
+			if len(part) >= 3 && part[0] == "GO" {
+				ann.GO = append(ann.GO, part[1]+" "+part[2])
+			}
+		case "KW":
+			keywords += " " + content
+		case "FT":
+			key := content
+			if len(key) > 16 {
+				key = key[:16]
+			}
+
+			qualifier := strings.TrimSpace(content)
+
+			if len(strings.TrimSpace(key)) > 0 {
+				closeFeature()
+				kind, feature, continuation, inFeature = parseFeatureLine(content)
+			} else if inFeature && strings.HasPrefix(qualifier, "/note=\"") {
+				note := strings.TrimPrefix(qualifier, "/note=\"")
+				continuation = !strings.HasSuffix(note, "\"")
+				feature.Description = strings.TrimSuffix(note, "\"")
+			} else if inFeature && continuation && !strings.HasPrefix(qualifier, "/") {
+				if strings.HasSuffix(qualifier, "\"") {
+					continuation = false
+				}
+				feature.Description += " " + strings.TrimSuffix(qualifier, "\"")
+			} else {
+				continuation = false
+			}
+		case "//":
+			closeFeature()
+
+			for _, i := range strings.Split(evidenceReg.ReplaceAllString(keywords, ""), ";") {
+				if i = strings.TrimSuffix(strings.TrimSpace(i), "."); len(i) > 0 {
+					ann.Keywords = append(ann.Keywords, i)
+				}
+			}
+
+			for _, i := range accessions {
+				annotations[i] = ann
+			}
+
+			accessions = nil
+			ann = Annotation{}
+			keywords = ""
+		}
+	}
+
+	if e := scanner.Err(); e != nil {
+		return nil, e
+	}
+
+	return annotations, nil
+}
+
+// parseFeatureLine reads the key and the location of a feature table line, and tells if
+// the line uses the older layout where the description follows the positions
+func parseFeatureLine(content string) (string, Feature, bool, bool) {
+
+	var f Feature
+
+	fields := strings.Fields(content)
+	if len(fields) < 2 || len(featureKinds[fields[0]]) == 0 {
+		return "", f, false, false
+	}
+
+	key := fields[0]
+
+	var okBegin, okEnd bool
+
+	if strings.Contains(fields[1], "..") {
+		// current layout, e.g. SIGNAL 1..20
+		pos := strings.SplitN(fields[1], "..", 2)
+		f.Begin, okBegin = parsePosition(pos[0])
+		f.End, okEnd = parsePosition(pos[1])
+		return key, f, false, okBegin && okEnd
+	}
+
+	if len(fields) >= 3 {
+		if _, e := strconv.Atoi(strings.Trim(fields[2], "<>?")); e == nil {
+			// older layout, e.g. MOD_RES 15 15 Phosphoserine.
+			f.Begin, okBegin = parsePosition(fields[1])
+			f.End, okEnd = parsePosition(fields[2])
+			f.Description = strings.TrimSuffix(evidenceReg.ReplaceAllString(strings.Join(fields[3:], " "), ""), ".")
+			return key, f, true, okBegin && okEnd
+		}
+	}
+
+	// current layout with a single position, e.g. MOD_RES 15
+	f.Begin, okBegin = parsePosition(fields[1])
+	f.End = f.Begin
+
+	return key, f, false, okBegin
+}
+
+// parsePosition reads a feature position, uncertain positions are rejected and the
+// features located on other entries, like P12345:10, are left out
+func parsePosition(s string) (int, bool) {
+
+	if strings.Contains(s, ":") {
+		return 0, false
+	}
+
+	p, e := strconv.Atoi(strings.Trim(s, "<>"))
+	if e != nil {
+		return 0, false
+	}
+
+	return p, true
+}
+
+// uniProtEntry is the part of the UniProt XML entry element used for the annotation
+type uniProtEntry struct {
+	Accession   []string `xml:"accession"`
+	DBReference []struct {
+		Type     string `xml:"type,attr"`
+		ID       string `xml:"id,attr"`
+		Property []struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"property"`
+	} `xml:"dbReference"`
+	Keyword []string `xml:"keyword"`
+	Feature []struct {
+		Type        string `xml:"type,attr"`
+		Description string `xml:"description,attr"`
+		Location    struct {
+			Begin    uniProtPosition `xml:"begin"`
+			End      uniProtPosition `xml:"end"`
+			Position uniProtPosition `xml:"position"`
+		} `xml:"location"`
+	} `xml:"feature"`
+}
+
+// uniProtPosition is a position on the sequence, unknown positions have no value
+type uniProtPosition struct {
+	Position string `xml:"position,attr"`
+}
+
+// readUniProtXML streams the UniProt XML format one entry at a time
+func readUniProtXML(r io.Reader) (map[string]Annotation, error) {
+
+	var annotations = make(map[string]Annotation)
+
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	decoder.CharsetReader = charset.NewReader
+
+	for {
+
+		t, e := decoder.Token()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			return nil, e
+		}
+
+		se, ok := t.(xml.StartElement)
+		if !ok || se.Name.Local != "entry" {
+			continue
+		}
+
+		var entry uniProtEntry
+		if e := decoder.DecodeElement(&entry, &se); e != nil {
+			return nil, e
+		}
+
+		var ann Annotation
+
+		for _, i := range entry.DBReference {
+			if i.Type != "GO" {
+				continue
+			}
+			for _, j := range i.Property {
+				if j.Type == "term" {
+					ann.GO = append(ann.GO, i.ID+" "+j.Value)
+				}
+			}
+		}
+
+		ann.Keywords = append(ann.Keywords, entry.Keyword...)
+
+		for _, i := range entry.Feature {
+
+			if len(featureKinds[i.Type]) == 0 {
+				continue
+			}
+
+			f := Feature{Description: i.Description}
+
+			var okBegin, okEnd bool
+			if len(i.Location.Position.Position) > 0 {
+				f.Begin, okBegin = parsePosition(i.Location.Position.Position)
+				f.End, okEnd = f.Begin, okBegin
+			} else {
+				f.Begin, okBegin = parsePosition(i.Location.Begin.Position)
+				f.End, okEnd = parsePosition(i.Location.End.Position)
+			}
+
+			if okBegin && okEnd {
+				ann.addFeature(i.Type, f)
+			}
+		}
+
+		for _, i := range entry.Accession {
+			annotations[i] = ann
+		}
+	}
+
+	return annotations, nil
+}
+
+// Annotate attaches the UniProt annotation to the target records. Isoforms only get
+// the GO terms and keywords, the positions of the features refer to the canonical sequence
+func (d *Base) Annotate(f string) {
+
+	annotations, e := ReadUniProtAnnotation(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	for i := range d.Records {
+
+		if d.Records[i].IsDecoy {
+			continue
+		}
+
+		if ann, ok := annotations[d.Records[i].ID]; ok {
+			d.Records[i].Annotation = ann
+			continue
+		}
+
+		// isoform accessions like P12345-2
+		if j := strings.LastIndex(d.Records[i].ID, "-"); j > 0 {
+			if ann, ok := annotations[d.Records[i].ID[:j]]; ok {
+				d.Records[i].Annotation = Annotation{GO: ann.GO, Keywords: ann.Keywords}
+			}
+		}
+	}
+
+	return
+}
+
+// PTMSiteList formats the known modification sites, e.g. S15(Phosphoserine). Only the
+// sites between the 1-based positions begin and end are listed, a zero end lists all
+func (a Annotation) PTMSiteList(sequence string, begin, end int) []string {
+
+	var sites []string
+
+	for _, i := range a.PTMSites {
+
+		if i.Begin < begin || (end > 0 && i.Begin > end) {
+			continue
+		}
+
+		var residue string
+		if i.Begin > 0 && i.Begin <= len(sequence) {
+			residue = sequence[i.Begin-1 : i.Begin]
+		}
+
+		sites = append(sites, residue+strconv.Itoa(i.Begin)+"("+i.Description+")")
+	}
+
+	return sites
+}
+
+// FeatureList formats a list of features as positions and descriptions, e.g. 1-20 or 21-110 Insulin
+func FeatureList(features []Feature) []string {
+
+	var list []string
+
+	for _, i := range features {
+		item := strconv.Itoa(i.Begin) + "-" + strconv.Itoa(i.End)
+		if len(i.Description) > 0 {
+			item += " " + i.Description
+		}
+		list = append(list, item)
+	}
+
+	return list
+}
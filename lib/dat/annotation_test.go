@@ -0,0 +1,106 @@
+package dat
+
+import (
+	"strings"
+	"testing"
+)
+
+const uniProtText = `ID   INS_HUMAN               Reviewed;         110 AA.
+AC   P01308; Q5EEX2;
+DR   GO; GO:0005576; C:extracellular region; TAS:Reactome.
+DR   GO; GO:0005179; F:hormone activity; IDA:UniProtKB.
+KW   Carbohydrate metabolism; Cleavage on pair of basic residues;
+KW   Hormone {ECO:0000269|PubMed:123}.
+FT   SIGNAL          1..24
+FT                   /evidence="ECO:0000269|PubMed:14426955"
+FT   CHAIN           25..54
+FT                   /note="Insulin B
+FT                   chain"
+FT                   /id="PRO_0000015819"
+FT   MOD_RES         30
+FT                   /note="Phosphoserine"
+FT   MOD_RES         P12345:10
+FT                   /note="Phosphothreonine"
+//
+ID   OLD_HUMAN               Reviewed;         50 AA.
+AC   P99999;
+FT   MOD_RES      15     15       Phosphoserine (By similarity).
+//
+`
+
+const uniProtXML = `<?xml version="1.0" encoding="UTF-8"?>
+<uniprot xmlns="http://uniprot.org/uniprot">
+<entry dataset="Swiss-Prot">
+<accession>P01308</accession>
+<name>INS_HUMAN</name>
+<dbReference type="GO" id="GO:0005576"><property type="term" value="C:extracellular region"/></dbReference>
+<dbReference type="PDB" id="1A7F"/>
+<keyword id="KW-0119">Carbohydrate metabolism</keyword>
+<feature type="signal peptide"><location><begin position="1"/><end position="24"/></location></feature>
+<feature type="modified residue" description="Phosphoserine"><location><position position="30"/></location></feature>
+<feature type="chain" description="Insulin B chain"><location><begin position="25"/><end status="unknown"/></location></feature>
+</entry>
+</uniprot>
+`
+
+func TestReadUniProtText(t *testing.T) {
+
+	annotations, e := readUniProtText(strings.NewReader(uniProtText))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	ins, ok := annotations["Q5EEX2"]
+	if !ok {
+		t.Fatalf("secondary accession not indexed")
+	}
+
+	if len(ins.GO) != 2 || ins.GO[0] != "GO:0005576 C:extracellular region" {
+		t.Errorf("GO = %v", ins.GO)
+	}
+
+	if len(ins.Keywords) != 3 || ins.Keywords[2] != "Hormone" {
+		t.Errorf("Keywords = %v", ins.Keywords)
+	}
+
+	if len(ins.SignalPeptides) != 1 || ins.SignalPeptides[0].End != 24 {
+		t.Errorf("SignalPeptides = %v", ins.SignalPeptides)
+	}
+
+	if len(ins.Chains) != 1 || ins.Chains[0].Description != "Insulin B chain" {
+		t.Errorf("Chains = %v", ins.Chains)
+	}
+
+	if len(ins.PTMSites) != 1 || ins.PTMSites[0].Begin != 30 || ins.PTMSites[0].Description != "Phosphoserine" {
+		t.Errorf("PTMSites = %v", ins.PTMSites)
+	}
+
+	old := annotations["P99999"]
+	if len(old.PTMSites) != 1 || old.PTMSites[0].Begin != 15 || old.PTMSites[0].Description != "Phosphoserine (By similarity)" {
+		t.Errorf("older layout PTMSites = %v", old.PTMSites)
+	}
+}
+
+func TestReadUniProtXML(t *testing.T) {
+
+	annotations, e := readUniProtXML(strings.NewReader(uniProtXML))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	ins := annotations["P01308"]
+
+	if len(ins.GO) != 1 || len(ins.Keywords) != 1 || len(ins.SignalPeptides) != 1 {
+		t.Errorf("annotation = %+v", ins)
+	}
+
+	// the chain has an unknown end and is left out
+	if len(ins.Chains) != 0 {
+		t.Errorf("Chains = %v", ins.Chains)
+	}
+
+	seq := strings.Repeat("A", 29) + "S"
+	if got := ins.PTMSiteList(seq, 25, 35); len(got) != 1 || got[0] != "S30(Phosphoserine)" {
+		t.Errorf("PTMSiteList() = %v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package dat
+
+import (
+	"sort"
+	"strings"
+)
+
+// Collapse merges the records with identical sequences into one canonical record that
+// lists the others as aliases. With il the sequences are compared without distinguishing
+// I and L. The canonical record is the first non-contaminant header in alphabetical order.
+// Decoys follow their targets, the decoy of an alias becomes an alias of the decoy of the
+// canonical record, so that shuffled decoys keep their pairing. It returns the number of target
+// aliases, the decoys collapsed with them are not counted
+func (d *Base) Collapse(tag string, il bool) int {
+
+	var groups = make(map[string][]int)
+	var decoys = make(map[string]int)
+	var keys []string
+
+	for i, j := range d.Records {
+
+		if j.IsDecoy {
+			decoys[j.PartHeader] = i
+			continue
+		}
+
+		if len(j.Sequence) == 0 {
+			continue
+		}
+
+		seq := j.Sequence
+		if il {
+			seq = strings.Replace(seq, "I", "L", -1)
+		}
+
+		if _, ok := groups[seq]; !ok {
+			keys = append(keys, seq)
+		}
+		groups[seq] = append(groups[seq], i)
+	}
+
+	var removed = make(map[int]bool)
+	var aliases int
+
+	for _, k := range keys {
+
+		group := groups[k]
+		if len(group) < 2 {
+			continue
+		}
+
+		sort.Slice(group, func(a, b int) bool {
+			ra, rb := d.Records[group[a]], d.Records[group[b]]
+			if ra.IsContaminant != rb.IsContaminant {
+				return !ra.IsContaminant
+			}
+			return ra.PartHeader < rb.PartHeader
+		})
+
+		canonical := group[0]
+		canonicalDecoy, hasDecoy := decoys[tag+d.Records[canonical].PartHeader]
+
+		for _, i := range group[1:] {
+			d.Records[canonical].Aliases = append(d.Records[canonical].Aliases, d.Records[i].PartHeader)
+			d.Records[canonical].Aliases = append(d.Records[canonical].Aliases, d.Records[i].Aliases...)
+			removed[i] = true
+			aliases++
+
+			if decoy, ok := decoys[tag+d.Records[i].PartHeader]; ok && hasDecoy {
+				d.Records[canonicalDecoy].Aliases = append(d.Records[canonicalDecoy].Aliases, d.Records[decoy].PartHeader)
+				d.Records[canonicalDecoy].Aliases = append(d.Records[canonicalDecoy].Aliases, d.Records[decoy].Aliases...)
+				removed[decoy] = true
+			}
+		}
+	}
+
+	if len(removed) == 0 {
+		return 0
+	}
+
+	var records []Record
+	for i, j := range d.Records {
+		if !removed[i] {
+			records = append(records, j)
+		}
+	}
+
+	d.Records = records
+
+	return aliases
+}
+
+// AliasMap maps the headers of the collapsed records to the header of their canonical record
+func (d Base) AliasMap() map[string]string {
+
+	var aliases = make(map[string]string)
+
+	for _, i := range d.Records {
+		for _, j := range i.Aliases {
+			aliases[j] = i.PartHeader
+		}
+	}
+
+	return aliases
+}
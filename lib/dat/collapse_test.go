@@ -0,0 +1,79 @@
+package dat
+
+import (
+	"strings"
+	"testing"
+)
+
+func collapseTestBase() Base {
+
+	return Base{Records: []Record{
+		{PartHeader: "tr|Q1|C_HUMAN", Sequence: "MKLIR"},
+		{PartHeader: "sp|P2|B_HUMAN", Sequence: "MKLIR"},
+		{PartHeader: "con_sp|P1|A_BOVIN", Sequence: "MKLIR", IsContaminant: true},
+		{PartHeader: "sp|P3|D_HUMAN", Sequence: "MKLLR"},
+		{PartHeader: "rev_tr|Q1|C_HUMAN", Sequence: "RILKM", IsDecoy: true},
+		{PartHeader: "rev_sp|P2|B_HUMAN", Sequence: "RILKM", IsDecoy: true},
+		{PartHeader: "sp|P5|E_HUMAN", Sequence: "RILKM"},
+	}}
+}
+
+func TestCollapse(t *testing.T) {
+
+	d := collapseTestBase()
+
+	if n := d.Collapse("rev_", false); n != 2 {
+		t.Errorf("Collapse(false) = %d aliases, want 2", n)
+	}
+
+	if len(d.Records) != 4 {
+		t.Fatalf("got %d records, want 4", len(d.Records))
+	}
+
+	if d.Records[0].PartHeader != "sp|P2|B_HUMAN" || strings.Join(d.Records[0].Aliases, ",") != "tr|Q1|C_HUMAN,con_sp|P1|A_BOVIN" {
+		t.Errorf("canonical record = %s %v", d.Records[0].PartHeader, d.Records[0].Aliases)
+	}
+
+	aliases := d.AliasMap()
+
+	if aliases["tr|Q1|C_HUMAN"] != "sp|P2|B_HUMAN" || aliases["rev_tr|Q1|C_HUMAN"] != "rev_sp|P2|B_HUMAN" || len(aliases) != 3 {
+		t.Errorf("AliasMap() = %v", aliases)
+	}
+
+	if _, ok := aliases["sp|P5|E_HUMAN"]; ok {
+		t.Errorf("target collapsed with a decoy")
+	}
+}
+
+func TestCollapseIL(t *testing.T) {
+
+	d := collapseTestBase()
+
+	if n := d.Collapse("rev_", true); n != 3 {
+		t.Errorf("Collapse(true) = %d aliases, want 3", n)
+	}
+
+	if d.AliasMap()["sp|P3|D_HUMAN"] != "sp|P2|B_HUMAN" {
+		t.Errorf("I/L variant not collapsed: %v", d.AliasMap())
+	}
+}
+
+func TestCollapseShuffledDecoys(t *testing.T) {
+
+	d := Base{Records: []Record{
+		{PartHeader: "sp|P1|A_HUMAN", Sequence: "MKLIRPEK"},
+		{PartHeader: "sp|P2|B_HUMAN", Sequence: "MKLIRPEK"},
+		{PartHeader: "rev_sp|P1|A_HUMAN", Sequence: "MRPKLIEK", IsDecoy: true},
+		{PartHeader: "rev_sp|P2|B_HUMAN", Sequence: "MPKIRLEK", IsDecoy: true},
+	}}
+
+	if n := d.Collapse("rev_", false); n != 1 {
+		t.Errorf("Collapse() = %d aliases, want 1", n)
+	}
+
+	aliases := d.AliasMap()
+
+	if len(d.Records) != 2 || aliases["sp|P2|B_HUMAN"] != "sp|P1|A_HUMAN" || aliases["rev_sp|P2|B_HUMAN"] != "rev_sp|P1|A_HUMAN" {
+		t.Errorf("records = %+v, aliases = %v", d.Records, aliases)
+	}
+}
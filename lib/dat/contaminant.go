@@ -0,0 +1,72 @@
+package dat
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"philosopher/lib/cla"
+	"philosopher/lib/fas"
+
+	"github.com/sirupsen/logrus"
+)
+
+// readContaminants collects the built-in contaminants and the entries of the custom
+// contaminant files, given as a comma-separated list
+func (d *Base) readContaminants(temp, files string, crap bool) map[string]string {
+
+	var contaminants = make(map[string]string)
+
+	if crap == true {
+
+		d.Deploy(temp)
+
+		for k, v := range fas.ParseFile(d.CrapDB) {
+			contaminants[k] = v
+		}
+	}
+
+	if len(files) > 0 {
+		for _, i := range strings.Split(files, ",") {
+
+			f, _ := filepath.Abs(strings.TrimSpace(i))
+
+			for k, v := range fas.ParseFile(f) {
+				contaminants[k] = v
+			}
+		}
+	}
+
+	return contaminants
+}
+
+// addContaminants adds the contaminants to the database with the contaminant tag. Target
+// entries with the same accession as a contaminant are removed and substituted by it
+func addContaminants(db, contaminants map[string]string, decoyTag, contamTag string) {
+
+	var accessions = make(map[string]bool)
+	for k, v := range contaminants {
+		accessions[processRecord(k, v, decoyTag, contamTag, nil).ID] = true
+	}
+
+	var replaced int
+	for k, v := range db {
+		if accessions[processRecord(k, v, decoyTag, contamTag, nil).ID] {
+			delete(db, k)
+			replaced++
+		}
+	}
+
+	for k, v := range contaminants {
+		if !cla.IsContaminant(k, decoyTag, contamTag) {
+			k = contamTag + k
+		}
+		db[k] = v
+	}
+
+	if replaced > 0 {
+		logrus.Info(fmt.Sprintf("%d entries were replaced by contaminants with the same accession", replaced))
+	}
+
+	return
+}
@@ -0,0 +1,68 @@
+package dat
+
+import (
+	"testing"
+)
+
+func TestAddContaminants(t *testing.T) {
+
+	db := map[string]string{
+		"sp|P02769|ALBU_BOVIN Serum albumin OS=Bos taurus OX=9913 GN=ALB PE=1 SV=4":   "MKWVTFISLLLLFSSAYS",
+		"sp|P027690|TEST_HUMAN Test protein OS=Homo sapiens OX=9606 GN=TST PE=1 SV=1": "MKKLLPTAAAGLLLLA",
+		"sp|Q12345|BIND_HUMAN Binds P02769 OS=Homo sapiens OX=9606 GN=BND PE=1 SV=1":  "MSSHEGGKKKALKQPKKQ",
+	}
+
+	contaminants := map[string]string{
+		"sp|P02769|ALBU_BOVIN Serum albumin OS=Bos taurus GN=ALB PE=1 SV=4": "MKWVTFISLLLLFSSAYSRGVFRR",
+		"con_sp|P00761|TRYP_PIG Trypsin OS=Sus scrofa PE=1 SV=1":            "FPTDDDDKIVGGYTCAANSIPYQVSLNSGSHFCGGSLINSQWVVSAAHCYKS",
+	}
+
+	addContaminants(db, contaminants, "rev_", "con_")
+
+	if len(db) != 4 {
+		t.Errorf("got %d entries, want 4: %v", len(db), db)
+	}
+
+	if _, ok := db["sp|P02769|ALBU_BOVIN Serum albumin OS=Bos taurus OX=9913 GN=ALB PE=1 SV=4"]; ok {
+		t.Errorf("target entry with a contaminant accession was kept")
+	}
+
+	for _, i := range []string{
+		"sp|P027690|TEST_HUMAN Test protein OS=Homo sapiens OX=9606 GN=TST PE=1 SV=1",
+		"sp|Q12345|BIND_HUMAN Binds P02769 OS=Homo sapiens OX=9606 GN=BND PE=1 SV=1",
+		"con_sp|P02769|ALBU_BOVIN Serum albumin OS=Bos taurus GN=ALB PE=1 SV=4",
+		"con_sp|P00761|TRYP_PIG Trypsin OS=Sus scrofa PE=1 SV=1",
+	} {
+		if _, ok := db[i]; !ok {
+			t.Errorf("entry missing: %s", i)
+		}
+	}
+}
+
+func TestContaminantRecords(t *testing.T) {
+
+	tests := []struct {
+		header        string
+		class         string
+		isContaminant bool
+		isDecoy       bool
+	}{
+		{"con_sp|P00761|TRYP_PIG Trypsin OS=Sus scrofa PE=1 SV=1", "uniprot", true, false},
+		{"rev_con_sp|P00761|TRYP_PIG Trypsin OS=Sus scrofa PE=1 SV=1", "uniprot", true, true},
+		{"sp|P00761|TRYP_PIG Trypsin OS=Sus scrofa PE=1 SV=1", "uniprot", false, false},
+		{"con_NP_000468.1 albumin preproprotein [Homo sapiens]", "ncbi", true, false},
+	}
+
+	for _, i := range tests {
+
+		r := processRecord(i.header, "MKWVTFISLLLLFSSAYS", "rev_", "con_", nil)
+
+		if r.Class != i.class || r.IsContaminant != i.isContaminant || r.IsDecoy != i.isDecoy {
+			t.Errorf("%s: class %s, contaminant %v, decoy %v", i.header, r.Class, r.IsContaminant, r.IsDecoy)
+		}
+
+		if r.Class == "uniprot" && r.ID != "P00761" {
+			t.Errorf("%s: ID = %s", i.header, r.ID)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	"philosopher/lib/msg"
 
+	"philosopher/lib/cla"
 	"philosopher/lib/fas"
 	"philosopher/lib/met"
 	"philosopher/lib/sys"
@@ -51,17 +52,66 @@ func Run(m met.Data) met.Data {
 
 	var db = New()
 
+	// contaminant entries are always tagged, also when the meta data has no tag
+	if len(m.Database.ContamTag) == 0 {
+		m.Database.ContamTag = "con_"
+	}
+
+	if m.Database.Stats {
+
+		db.Restore()
+
+		if len(db.Records) == 0 {
+			msg.Custom(errors.New("No database found in the workspace, run the database command first"), "fatal")
+		}
+
+		logrus.Info("Collecting statistics for ", db.FileName)
+
+		db.Stats(m.Database.Tag, m.Database.ContamTag).Report()
+
+		return m
+	}
+
 	if len(m.Database.ID) == 0 && (len(m.Database.Annot) == 0 || m.Database.Annot == "--contam" || m.Database.Annot == "--prefix") && (len(m.Database.Custom) == 0 || m.Database.Custom == "--contam" || m.Database.Custom == "--prefix") {
 		msg.InputNotFound(errors.New("Provide a protein FASTA file or Proteome ID"), "fatal")
 	}
 
+	templates, e := NewHeaderTemplates(m.Database.HeaderRegex, m.Database.HeaderTemplates)
+	if e != nil {
+		msg.Custom(e, "fatal")
+	}
+
+	if len(m.Database.Annot) > 0 && len(m.Database.Variants) > 0 {
+		msg.Custom(errors.New("Variants can only be added when creating a database"), "fatal")
+	}
+
 	if len(m.Database.Annot) > 0 {
 
 		logrus.Info("Processing database")
 
 		m.DB = m.Database.Annot
 
-		db.ProcessDB(m.Database.Annot, m.Database.Tag)
+		db.ProcessDB(m.Database.Annot, m.Database.Tag, m.Database.ContamTag, templates)
+
+		m.Database.Hash, m.Database.Entries, e = Fingerprint(m.Database.Annot)
+		if e != nil {
+			msg.ReadFile(e, "fatal")
+		}
+
+		if m.Database.Collapse || m.Database.CollapseIL {
+			logrus.Info("Collapsing identical sequences")
+			logrus.Info(fmt.Sprintf("%d target entries were collapsed as aliases", db.Collapse(m.Database.Tag, m.Database.CollapseIL)))
+		}
+
+		if len(m.Database.Annotation) > 0 {
+			logrus.Info("Adding the UniProt annotation")
+			db.Annotate(m.Database.Annotation)
+		}
+
+		if m.Database.Digest.Report {
+			logrus.Info("Digesting protein sequences")
+			db.DigestReport(m.Database.Enz, digestOptions(m.Database.Digest))
+		}
 
 		db.Serialize()
 
@@ -72,7 +122,13 @@ func Run(m met.Data) met.Data {
 		msg.InputNotFound(errors.New("You need to provide a taxon ID or a custom FASTA file"), "fatal")
 	}
 
-	if m.Database.Crap == false {
+	if !ValidDecoyMethod(m.Database.DecoyMethod) {
+		msg.Custom(errors.New("Decoy method not supported, use reverse, pseudo-reverse or shuffle"), "fatal")
+	}
+
+	hasContaminants := m.Database.Crap || len(m.Database.ContamFiles) > 0
+
+	if hasContaminants == false {
 		msg.Custom(errors.New("Contaminants are not going to be added to database"), "warning")
 	}
 
@@ -97,67 +153,92 @@ func Run(m met.Data) met.Data {
 	}
 
 	logrus.Info("Processing decoys")
-	db.Create(m.Temp, m.Database.Add, m.Database.Enz, m.Database.Tag, m.Database.Crap, m.Database.NoD)
+	db.Create(m.Temp, m.Database.Add, m.Database.ContamFiles, m.Database.Variants, m.Database.Enz, m.Database.Tag, m.Database.ContamTag, m.Database.DecoyMethod, m.Database.DecoySeed, m.Database.Crap, m.Database.NoD)
 
 	logrus.Info("Creating file")
-	customDB := db.Save(m.Home, m.Temp, m.Database.ID, m.Database.Tag, m.Database.Rev, m.Database.Iso, m.Database.NoD, m.Database.Crap)
+	customDB := db.Save(m.Home, m.Temp, m.Database.ID, m.Database.Tag, m.Database.Rev, m.Database.Iso, m.Database.NoD, hasContaminants)
 
-	db.ProcessDB(customDB, m.Database.Tag)
+	db.ProcessDB(customDB, m.Database.Tag, m.Database.ContamTag, templates)
 
 	logrus.Info("Processing decoys")
-	db.Create(m.Temp, m.Database.Add, m.Database.Enz, m.Database.Tag, m.Database.Crap, m.Database.NoD)
+	db.Create(m.Temp, m.Database.Add, m.Database.ContamFiles, m.Database.Variants, m.Database.Enz, m.Database.Tag, m.Database.ContamTag, m.Database.DecoyMethod, m.Database.DecoySeed, m.Database.Crap, m.Database.NoD)
 
 	logrus.Info("Creating file")
-	db.Save(m.Home, m.Temp, m.Database.ID, m.Database.Tag, m.Database.Rev, m.Database.Iso, m.Database.NoD, m.Database.Crap)
+	customDB = db.Save(m.Home, m.Temp, m.Database.ID, m.Database.Tag, m.Database.Rev, m.Database.Iso, m.Database.NoD, hasContaminants)
 
 	db.Prefix = m.Database.Tag
 
+	m.Database.Hash, m.Database.Entries, e = Fingerprint(customDB)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	if m.Database.Collapse || m.Database.CollapseIL {
+		logrus.Info("Collapsing identical sequences")
+		logrus.Info(fmt.Sprintf("%d target entries were collapsed as aliases", db.Collapse(m.Database.Tag, m.Database.CollapseIL)))
+	}
+
+	if len(m.Database.Annotation) > 0 {
+		logrus.Info("Adding the UniProt annotation")
+		db.Annotate(m.Database.Annotation)
+	}
+
+	if m.Database.Digest.Report {
+		logrus.Info("Digesting protein sequences")
+		db.DigestReport(m.Database.Enz, digestOptions(m.Database.Digest))
+	}
+
 	db.Serialize()
 
 	return m
 }
 
-// ProcessDB determines the type of sequence and sends it to the appropriate parsing function
-func (d *Base) ProcessDB(file, decoyTag string) {
+// ProcessDB determines the type of sequence and sends it to the appropriate parsing function,
+// headers matching one of the templates are parsed by the template instead
+func (d *Base) ProcessDB(file, decoyTag, contamTag string, templates HeaderTemplates) {
 
 	fastaMap := fas.ParseFile(file)
 	d.FileName = path.Base(file)
 
 	for k, v := range fastaMap {
+		db := processRecord(k, v, decoyTag, contamTag, templates)
+		d.Records = append(d.Records, db)
+	}
 
-		class := Classify(k, decoyTag)
+	return
+}
 
-		if class == "uniprot" {
+// processRecord parses one FASTA entry with the first matching template or with the
+// parser of its header class
+func processRecord(k, v, decoyTag, contamTag string, templates HeaderTemplates) Record {
 
-			db := ProcessUniProtKB(k, v, decoyTag)
-			d.Records = append(d.Records, db)
+	db, ok := templates.Process(k, v, decoyTag)
 
-		} else if class == "ncbi" {
+	if !ok {
 
-			db := ProcessNCBI(k, v, decoyTag)
-			d.Records = append(d.Records, db)
+		class := Classify(k, decoyTag, contamTag)
 
+		if class == "uniprot" {
+			db = ProcessUniProtKB(k, v, decoyTag)
+		} else if class == "ncbi" {
+			db = ProcessNCBI(k, v, decoyTag)
 		} else if class == "ensembl" {
-
-			db := ProcessENSEMBL(k, v, decoyTag)
-			d.Records = append(d.Records, db)
-
+			db = ProcessENSEMBL(k, v, decoyTag)
 		} else if class == "generic" {
-
-			db := ProcessGeneric(k, v, decoyTag)
-			d.Records = append(d.Records, db)
-
+			db = ProcessGeneric(k, v, decoyTag)
 		} else if class == "uniref" {
-
-			db := ProcessUniRef(k, v, decoyTag)
-			d.Records = append(d.Records, db)
-
+			db = ProcessUniRef(k, v, decoyTag)
 		} else {
 			msg.ParsingFASTA(errors.New(""), "fatal")
 		}
+
+		db.Class = class
 	}
 
-	return
+	db.IsContaminant = cla.IsContaminant(k, decoyTag, contamTag)
+	db.Variant, db.VariantStart, db.VariantEnd = parseVariantTag(k)
+
+	return db
 }
 
 // Fetch downloads a database file from UniProt
@@ -209,10 +290,24 @@ func (d *Base) Fetch(id, temp string, iso, rev bool) {
 }
 
 // Create processes the given fasta file and add decoy sequences
-func (d *Base) Create(temp, add, enz, tag string, crap, noD bool) {
+func (d *Base) Create(temp, add, contam, variants, enz, tag, contamTag, method string, seed int64, crap, noD bool) {
 
 	d.TaDeDB = make(map[string]string)
 
+	var contaminants map[string]string
+	if crap == true || len(contam) > 0 {
+		contaminants = d.readContaminants(temp, contam, crap)
+	}
+
+	var variantList []Variant
+	if len(variants) > 0 {
+		var e error
+		variantList, e = ReadVariants(variants)
+		if e != nil {
+			msg.ReadFile(e, "fatal")
+		}
+	}
+
 	for _, i := range d.DownloadedFiles {
 
 		dbfile, _ := filepath.Abs(i)
@@ -228,34 +323,26 @@ func (d *Base) Create(temp, add, enz, tag string, crap, noD bool) {
 
 		// adding contaminants to database before reversion
 		// repeated entries are removed and substituted by contaminants
-		if crap == true {
-
-			d.Deploy(temp)
-
-			crapMap := fas.ParseFile(d.CrapDB)
-
-			for k, v := range crapMap {
-				split := strings.Split(k, "|")
-				for i := range db {
-					if strings.Contains(i, split[1]) {
-						delete(db, i)
-					}
-				}
-				db[k] = v
-			}
+		if len(contaminants) > 0 {
+			addContaminants(db, contaminants, tag, contamTag)
+		}
 
+		// variant entries are added before the decoys so they also get decoy sequences
+		if len(variantList) > 0 {
+			addVariants(db, variantList, tag, contamTag)
 		}
 
 		for h, s := range db {
-
 			th := ">" + h
 			d.TaDeDB[th] = s
+		}
 
-			if noD == false {
+		if noD == false {
+			decoys := newDecoyGenerator(method, enz, seed, db).decoys(db)
+			for h, s := range decoys {
 				dh := ">" + tag + h
-				d.TaDeDB[dh] = reverseSeq(s)
+				d.TaDeDB[dh] = s
 			}
-
 		}
 
 	}
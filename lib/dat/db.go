@@ -25,6 +25,12 @@ type Record struct {
 	Length           int
 	IsDecoy          bool
 	IsContaminant    bool
+	Class            string
+	Annotation       Annotation
+	Variant          string
+	VariantStart     int
+	VariantEnd       int
+	Aliases          []string
 }
 
 // ProcessENSEMBL parses ENSEMBL like FASTA records
@@ -410,11 +416,13 @@ func ProcessGeneric(k, v, decoyTag string) Record {
 }
 
 // Classify determines what kind of database originated the given sequence
-func Classify(s, decoyTag string) string {
+func Classify(s, decoyTag, contamTag string) string {
 
 	// remove the decoy and contamintant tags so we can see better the seq header
 	seq := strings.Replace(s, decoyTag, "", -1)
-	seq = strings.Replace(seq, "con_", "", -1)
+	if len(contamTag) > 0 {
+		seq = strings.Replace(seq, contamTag, "", -1)
+	}
 
 	if strings.HasPrefix(seq, "sp|") || strings.HasPrefix(seq, "tr|") || strings.HasPrefix(seq, "db|") {
 		return "uniprot"
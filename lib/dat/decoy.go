@@ -0,0 +1,176 @@
+package dat
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/msg"
+)
+
+// maxShuffles is the number of times a peptide is shuffled looking for a sequence
+// that is not found among the target peptides
+const maxShuffles = 10
+
+// decoyGenerator creates the decoy sequences with one of the supported methods:
+// reverse, pseudo-reverse and shuffle
+type decoyGenerator struct {
+	method  string
+	enzyme  bio.Enzyme
+	random  *rand.Rand
+	targets map[string]bool
+}
+
+// ValidDecoyMethod checks if the decoy method is supported, an empty method means reverse
+func ValidDecoyMethod(method string) bool {
+	switch method {
+	case "", "reverse", "pseudo-reverse", "shuffle":
+		return true
+	}
+	return false
+}
+
+// newDecoyGenerator prepares the generator for the given target sequences. The target
+// peptides are only collected for the shuffle method, where they are used to discard
+// shuffled peptides that are also found in the target database
+func newDecoyGenerator(method, enz string, seed int64, db map[string]string) *decoyGenerator {
+
+	if !ValidDecoyMethod(method) {
+		msg.Custom(errors.New("Decoy method not supported, use reverse, pseudo-reverse or shuffle"), "fatal")
+	}
+
+	g := &decoyGenerator{method: method}
+
+	if method == "pseudo-reverse" || method == "shuffle" {
+		g.enzyme.Synth(enz)
+	}
+
+	if method == "shuffle" {
+		g.random = rand.New(rand.NewSource(seed))
+		g.targets = make(map[string]bool)
+		for _, s := range db {
+			for _, f := range g.fragments(s) {
+				g.targets[f.seq] = true
+			}
+		}
+	}
+
+	return g
+}
+
+// decoys creates a decoy sequence for each target. The headers are sorted first so
+// the shuffled sequences only depend on the seed
+func (g *decoyGenerator) decoys(db map[string]string) map[string]string {
+
+	var headers []string
+	for k := range db {
+		headers = append(headers, k)
+	}
+
+	sort.Strings(headers)
+
+	decoys := make(map[string]string)
+	for _, h := range headers {
+		decoys[h] = g.decoy(db[h])
+	}
+
+	return decoys
+}
+
+// decoy creates the decoy version of a protein sequence
+func (g *decoyGenerator) decoy(s string) string {
+
+	switch g.method {
+	case "pseudo-reverse":
+		return g.pseudoReverseSeq(s)
+	case "shuffle":
+		return g.shuffleSeq(s)
+	}
+
+	return reverseSeq(s)
+}
+
+// fragment is an enzymatic peptide with the number of cleavage residues at each end,
+// these residues keep their positions in the decoy
+type fragment struct {
+	seq  string
+	head int
+	tail int
+}
+
+// fragments splits the sequence at every cleavage site of the enzyme
+func (g *decoyGenerator) fragments(s string) []fragment {
+
+	var fragments []fragment
+
+	start := 0
+	head := 0
+
+	for i := range s {
+		switch g.enzyme.CleavageSense(s, i) {
+		case "C":
+			fragments = append(fragments, fragment{seq: s[start : i+1], head: head, tail: 1})
+			start, head = i+1, 0
+		case "N":
+			fragments = append(fragments, fragment{seq: s[start : i+1], head: head})
+			start, head = i+1, 1
+		}
+	}
+
+	if start < len(s) {
+		fragments = append(fragments, fragment{seq: s[start:], head: head})
+	}
+
+	// a single residue between two sites cannot be rearranged
+	for i := range fragments {
+		if fragments[i].head+fragments[i].tail > len(fragments[i].seq) {
+			fragments[i].head, fragments[i].tail = 0, 0
+		}
+	}
+
+	return fragments
+}
+
+// pseudoReverseSeq reverses each enzymatic peptide keeping the cleavage residues in
+// place, so the decoy peptides have the same masses and termini as the targets
+func (g *decoyGenerator) pseudoReverseSeq(s string) string {
+
+	var decoy []byte
+
+	for _, f := range g.fragments(s) {
+		p := f.seq
+		decoy = append(decoy, p[:f.head]...)
+		decoy = append(decoy, reverseSeq(p[f.head:len(p)-f.tail])...)
+		decoy = append(decoy, p[len(p)-f.tail:]...)
+	}
+
+	return string(decoy)
+}
+
+// shuffleSeq shuffles each enzymatic peptide keeping the cleavage residues in place.
+// Peptides are shuffled again when the result is also a target peptide, the last
+// attempt is kept when no unique sequence is found, like for very short peptides
+func (g *decoyGenerator) shuffleSeq(s string) string {
+
+	var decoy []byte
+
+	for _, f := range g.fragments(s) {
+
+		shuffled := []byte(f.seq)
+		middle := shuffled[f.head : len(f.seq)-f.tail]
+
+		for i := 0; i < maxShuffles; i++ {
+			g.random.Shuffle(len(middle), func(i, j int) {
+				middle[i], middle[j] = middle[j], middle[i]
+			})
+			if !g.targets[string(shuffled)] {
+				break
+			}
+		}
+
+		decoy = append(decoy, shuffled...)
+	}
+
+	return string(decoy)
+}
@@ -0,0 +1,41 @@
+package dat
+
+import "testing"
+
+func TestPseudoReverseSeq(t *testing.T) {
+
+	g := newDecoyGenerator("pseudo-reverse", "trypsin", 0, nil)
+
+	// the K and R cleavage sites stay in place, KP is not a site and the last peptide is fully reversed
+	got := g.decoy("MPEPTIDEKAAKPLRGHIJ")
+	want := "EDITPEPMKLPKAARJIHG"
+	if got != want {
+		t.Errorf("pseudoReverseSeq() = %s, want %s", got, want)
+	}
+}
+
+func TestShuffleSeq(t *testing.T) {
+
+	db := map[string]string{
+		"sp|P1|A": "ACDEFGHIKLMNPQRSTVWY",
+		"sp|P2|B": "PEPTIDEKSAMPLERGA",
+	}
+
+	decoys := newDecoyGenerator("shuffle", "trypsin", 7, db).decoys(db)
+	again := newDecoyGenerator("shuffle", "trypsin", 7, db).decoys(db)
+
+	for h, s := range db {
+
+		if decoys[h] != again[h] {
+			t.Errorf("shuffled decoys should be reproducible with the same seed, got %s and %s", decoys[h], again[h])
+		}
+
+		if len(decoys[h]) != len(s) || decoys[h] == s {
+			t.Errorf("shuffled decoy %s is not a permutation of %s", decoys[h], s)
+		}
+	}
+
+	if decoys["sp|P2|B"][7] != 'K' || decoys["sp|P2|B"][14] != 'R' {
+		t.Errorf("cleavage residues should stay in place, got %s", decoys["sp|P2|B"])
+	}
+}
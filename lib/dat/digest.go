@@ -0,0 +1,87 @@
+package dat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+	"philosopher/lib/sys"
+)
+
+// digestOptions converts the command options into the digestion parameters
+func digestOptions(d met.Digest) bio.Digestion {
+	return bio.Digestion{
+		MissedCleavages: d.MissedCleavages,
+		MinLength:       d.MinLength,
+		MaxLength:       d.MaxLength,
+		MinMass:         d.MinMass,
+		MaxMass:         d.MaxMass,
+		ClipNTermM:      d.ClipNTermM,
+		Specificity:     d.Specificity,
+	}
+}
+
+// DigestReport writes the theoretical peptides of each protein sequence in the database
+func (d *Base) DigestReport(enz string, digestion bio.Digestion) {
+
+	var enzyme bio.Enzyme
+	enzyme.Synth(enz)
+
+	if len(enzyme.Name) == 0 {
+		msg.Custom(errors.New("Cannot digest the database without a supported enzyme"), "error")
+		return
+	}
+
+	output := fmt.Sprintf("%s%sdigest.tsv", sys.MetaDir(), string(filepath.Separator))
+
+	file, e := os.Create(output)
+	if e != nil {
+		msg.WriteFile(errors.New("Cannot create the digestion report"), "fatal")
+	}
+	defer file.Close()
+
+	_, e = file.WriteString("Protein\tProtein ID\tEntry Name\tPeptide\tStart\tEnd\tLength\tMissed Cleavages\tMass\tIs Decoy\n")
+	if e != nil {
+		msg.WriteToFile(errors.New("Cannot print to the digestion report"), "fatal")
+	}
+
+	records := make([]Record, len(d.Records))
+	copy(records, d.Records)
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].PartHeader < records[j].PartHeader
+	})
+
+	for _, i := range records {
+		for _, j := range enzyme.Digest(i.Sequence, digestion) {
+
+			line := fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%.4f\t%t\n",
+				i.PartHeader,
+				i.ID,
+				i.EntryName,
+				j.Sequence,
+				j.Start,
+				j.End,
+				len(j.Sequence),
+				j.MissedCleavages,
+				j.Mass,
+				i.IsDecoy,
+			)
+
+			_, e = file.WriteString(line)
+			if e != nil {
+				msg.WriteToFile(errors.New("Cannot print to the digestion report"), "fatal")
+			}
+		}
+	}
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
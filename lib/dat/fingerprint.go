@@ -0,0 +1,87 @@
+package dat
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"philosopher/lib/met"
+	"philosopher/lib/msg"
+)
+
+// Fingerprint returns the SHA-256 hash of a FASTA file and its number of entries
+func Fingerprint(f string) (string, int, error) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		return "", 0, e
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	reader := bufio.NewReader(io.TeeReader(file, h))
+
+	var entries int
+	var lineStart = true
+
+	for {
+		b, e := reader.ReadByte()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			return "", 0, e
+		}
+
+		if lineStart && b == '>' {
+			entries++
+		}
+
+		lineStart = b == '\n'
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), entries, nil
+}
+
+// CheckSearchDatabase compares the databases recorded in the pepXML files, given as the
+// local path and the number of entries, with the fingerprint of the workspace database.
+// Databases that cannot be read are compared by the number of entries only
+func CheckSearchDatabase(databases map[string]int, d met.Database) error {
+
+	if len(d.Hash) == 0 {
+		msg.Custom(errors.New("The workspace database has no fingerprint, run the database command again to verify the search database"), "warning")
+		return nil
+	}
+
+	var paths []string
+	for k := range databases {
+		paths = append(paths, k)
+	}
+
+	sort.Strings(paths)
+
+	for _, i := range paths {
+
+		entries := databases[i]
+
+		if entries > 0 && d.Entries > 0 && entries != d.Entries {
+			return fmt.Errorf("The search database %s has %d entries and the workspace database has %d", filepath.Base(i), entries, d.Entries)
+		}
+
+		hash, count, e := Fingerprint(i)
+		if e != nil {
+			msg.Custom(fmt.Errorf("Cannot read the search database %s, only the number of entries was compared", i), "warning")
+			continue
+		}
+
+		if hash != d.Hash {
+			return fmt.Errorf("The search database %s (%d entries) is not the database processed in the workspace (%d entries)", filepath.Base(i), count, d.Entries)
+		}
+	}
+
+	return nil
+}
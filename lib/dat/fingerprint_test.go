@@ -0,0 +1,60 @@
+package dat
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"philosopher/lib/met"
+)
+
+func TestFingerprint(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "fingerprint")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	searched := filepath.Join(dir, "searched.fas")
+	if e := ioutil.WriteFile(searched, []byte(">sp|P1|A_HUMAN\nMKR>X\n>rev_sp|P1|A_HUMAN\nRKM\n"), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	other := filepath.Join(dir, "other.fas")
+	if e := ioutil.WriteFile(other, []byte(">sp|P1|A_HUMAN\nMKR>X\n>rev_sp|P1|A_HUMAN\nRKA\n"), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	hash, entries, e := Fingerprint(searched)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if entries != 2 || len(hash) != 64 {
+		t.Fatalf("Fingerprint() = %s, %d entries", hash, entries)
+	}
+
+	d := met.Database{Hash: hash, Entries: entries}
+
+	if e := CheckSearchDatabase(map[string]int{searched: 2}, d); e != nil {
+		t.Errorf("same database rejected: %v", e)
+	}
+
+	if e := CheckSearchDatabase(map[string]int{other: 0}, d); e == nil {
+		t.Errorf("different database with the same number of entries accepted")
+	}
+
+	if e := CheckSearchDatabase(map[string]int{filepath.Join(dir, "missing.fas"): 3}, d); e == nil {
+		t.Errorf("missing database with a different number of entries accepted")
+	}
+
+	if e := CheckSearchDatabase(map[string]int{filepath.Join(dir, "missing.fas"): 2}, d); e != nil {
+		t.Errorf("missing database with the same number of entries rejected: %v", e)
+	}
+
+	if e := CheckSearchDatabase(map[string]int{other: 0}, met.Database{}); e != nil {
+		t.Errorf("workspace without a fingerprint rejected: %v", e)
+	}
+}
@@ -0,0 +1,136 @@
+package dat
+
+import (
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// HeaderTemplate parses FASTA headers with a regular expression. The named groups id,
+// entry_name, protein_name, gene_names, organism and description fill the Record fields
+// with the same names, the id group is required
+type HeaderTemplate struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+	regex   *regexp.Regexp
+}
+
+// HeaderTemplates is an ordered list of templates, the first one matching a header is used
+type HeaderTemplates []HeaderTemplate
+
+// headerTemplateFile is the layout of the YAML template file
+type headerTemplateFile struct {
+	Templates []HeaderTemplate `yaml:"templates"`
+}
+
+// NewHeaderTemplates compiles the header templates from a regular expression given on
+// the command line and from a YAML file, the command line expression goes first
+func NewHeaderTemplates(pattern, file string) (HeaderTemplates, error) {
+
+	var templates HeaderTemplates
+
+	if len(pattern) > 0 {
+		templates = append(templates, HeaderTemplate{Name: "custom", Pattern: pattern})
+	}
+
+	if len(file) > 0 {
+
+		b, e := ioutil.ReadFile(file)
+		if e != nil {
+			return nil, e
+		}
+
+		var t headerTemplateFile
+		e = yaml.Unmarshal(b, &t)
+		if e != nil {
+			return nil, e
+		}
+
+		templates = append(templates, t.Templates...)
+	}
+
+	for i := range templates {
+
+		regex, e := regexp.Compile(templates[i].Pattern)
+		if e != nil {
+			return nil, e
+		}
+
+		var hasID bool
+		for _, j := range regex.SubexpNames() {
+			if j == "id" {
+				hasID = true
+			}
+		}
+
+		if !hasID {
+			return nil, errors.New("the header template " + templates[i].Name + " needs an id group")
+		}
+
+		templates[i].regex = regex
+	}
+
+	return templates, nil
+}
+
+// Process parses the header with the first matching template, the decoy tag is removed
+// before matching so decoys get the same fields as their targets
+func (t HeaderTemplates) Process(k, v, decoyTag string) (Record, bool) {
+
+	var isDecoy bool
+
+	header := k
+	if len(decoyTag) > 0 && strings.HasPrefix(k, decoyTag) {
+		header = strings.TrimPrefix(k, decoyTag)
+		isDecoy = true
+	}
+
+	for _, i := range t {
+
+		match := i.regex.FindStringSubmatch(header)
+		if match == nil {
+			continue
+		}
+
+		e := Record{IsDecoy: isDecoy}
+
+		for j, name := range i.regex.SubexpNames() {
+
+			value := strings.TrimSpace(match[j])
+
+			switch name {
+			case "id":
+				e.ID = value
+			case "entry_name":
+				e.EntryName = value
+			case "protein_name":
+				e.ProteinName = value
+			case "gene_names":
+				e.GeneNames = value
+			case "organism":
+				e.Organism = value
+			case "description":
+				e.Description = value
+			}
+		}
+
+		if len(e.ID) == 0 {
+			continue
+		}
+
+		part := strings.Split(k, " ")
+		e.PartHeader = part[0]
+
+		e.Sequence = v
+		e.Length = len(v)
+		e.OriginalHeader = k
+		e.Class = "template:" + i.Name
+
+		return e, true
+	}
+
+	return Record{}, false
+}
@@ -0,0 +1,37 @@
+package dat
+
+import "testing"
+
+func TestHeaderTemplates(t *testing.T) {
+
+	// GENCODE translations carry the gene name in the sixth field
+	pattern := `^(?P<id>ENSP[^|]+)\|[^|]*\|[^|]*\|[^|]*\|[^|]*\|[^|]*\|(?P<gene_names>[^|]+)\|`
+
+	templates, e := NewHeaderTemplates(pattern, "")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	header := "ENSP00000493376.2|ENST00000641515.2|ENSG00000186092.7|OTTHUMG00000001094.4|OTTHUMT00000003223.4|OR4F5-201|OR4F5|326"
+
+	r, ok := templates.Process("rev_"+header, "MKK", "rev_")
+	if !ok {
+		t.Fatalf("header template did not match %s", header)
+	}
+
+	if r.ID != "ENSP00000493376.2" || r.GeneNames != "OR4F5" || !r.IsDecoy || r.Class != "template:custom" {
+		t.Errorf("Process() = %+v", r)
+	}
+
+	if r.PartHeader != "rev_"+header || r.Length != 3 {
+		t.Errorf("Process() PartHeader = %s, Length = %d", r.PartHeader, r.Length)
+	}
+
+	if _, ok := templates.Process("sp|P12345|A_HUMAN Protein", "MKK", "rev_"); ok {
+		t.Errorf("header template should not match a UniProt header")
+	}
+
+	if _, e := NewHeaderTemplates(`^(?P<gene_names>\w+)`, ""); e == nil {
+		t.Errorf("a header template without an id group should be rejected")
+	}
+}
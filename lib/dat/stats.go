@@ -0,0 +1,170 @@
+package dat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"philosopher/lib/cla"
+
+	"github.com/sirupsen/logrus"
+)
+
+// standardResidues are the 20 standard amino acids
+const standardResidues = "ACDEFGHIKLMNPQRSTVWY"
+
+// lengthBins are the upper limits of the sequence length distribution
+var lengthBins = []int{50, 100, 200, 500, 1000, 2000}
+
+// Stats summarizes the content of a processed database
+type Stats struct {
+	Targets             int
+	Decoys              int
+	Contaminants        int
+	DuplicatedSequences int
+	DuplicatedEntries   int
+	ILDuplicates        int
+	Classes             map[string]int
+	NonStandard         map[string]int
+	Lengths             []int
+	GenericExample      string
+}
+
+// Stats collects the database statistics, records parsed by a header template are counted
+// under the template name. Duplicates are counted among the target entries, a duplicated
+// sequence is shared by more than one entry, and an I/L duplicate is a group of different
+// sequences that become equal when I and L are not distinguished
+func (d Base) Stats(decoyTag, contamTag string) Stats {
+
+	var s Stats
+
+	s.Classes = make(map[string]int)
+	s.NonStandard = make(map[string]int)
+
+	var sequences = make(map[string]int)
+	var ilSequences = make(map[string]map[string]bool)
+
+	for _, i := range d.Records {
+
+		class := i.Class
+		if len(class) == 0 {
+			class = Classify(i.OriginalHeader, decoyTag, contamTag)
+		}
+		s.Classes[class]++
+
+		if class == "generic" && len(s.GenericExample) == 0 {
+			s.GenericExample = i.OriginalHeader
+		}
+
+		if i.IsDecoy {
+			s.Decoys++
+			continue
+		}
+
+		s.Targets++
+
+		if i.IsContaminant || cla.IsContaminant(i.OriginalHeader, decoyTag, contamTag) {
+			s.Contaminants++
+		}
+
+		s.Lengths = append(s.Lengths, len(i.Sequence))
+
+		sequences[i.Sequence]++
+
+		il := strings.Replace(i.Sequence, "I", "L", -1)
+		if _, ok := ilSequences[il]; !ok {
+			ilSequences[il] = make(map[string]bool)
+		}
+		ilSequences[il][i.Sequence] = true
+
+		var seen = make(map[rune]bool)
+		for _, r := range i.Sequence {
+			if !strings.ContainsRune(standardResidues, r) && !seen[r] {
+				seen[r] = true
+				s.NonStandard[string(r)]++
+			}
+		}
+	}
+
+	for _, v := range sequences {
+		if v > 1 {
+			s.DuplicatedSequences++
+			s.DuplicatedEntries += v
+		}
+	}
+
+	for _, v := range ilSequences {
+		if len(v) > 1 {
+			s.ILDuplicates++
+		}
+	}
+
+	sort.Ints(s.Lengths)
+
+	return s
+}
+
+// Report logs the database statistics
+func (s Stats) Report() {
+
+	logrus.WithFields(logrus.Fields{
+		"targets":      s.Targets,
+		"decoys":       s.Decoys,
+		"contaminants": s.Contaminants,
+	}).Info("Database entries")
+
+	var classes []string
+	for k := range s.Classes {
+		classes = append(classes, k)
+	}
+	sort.Strings(classes)
+
+	var classFields = make(logrus.Fields)
+	for _, i := range classes {
+		classFields[i] = s.Classes[i]
+	}
+	logrus.WithFields(classFields).Info("Header classes")
+
+	if s.Classes["generic"] > 0 {
+		logrus.Warn(fmt.Sprintf("%d headers are not recognized and gene names will be missing from the reports, e.g. %s", s.Classes["generic"], s.GenericExample))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"sequences": s.DuplicatedSequences,
+		"entries":   s.DuplicatedEntries,
+		"I/L":       s.ILDuplicates,
+	}).Info("Duplicated target sequences")
+
+	if len(s.NonStandard) > 0 {
+		var residueFields = make(logrus.Fields)
+		for k, v := range s.NonStandard {
+			residueFields[k] = v
+		}
+		logrus.WithFields(residueFields).Info("Entries with non-standard residues")
+	}
+
+	if len(s.Lengths) == 0 {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"min":    s.Lengths[0],
+		"median": s.Lengths[len(s.Lengths)/2],
+		"max":    s.Lengths[len(s.Lengths)-1],
+	}).Info("Sequence length")
+
+	var counts = make([]int, len(lengthBins)+1)
+	for _, i := range s.Lengths {
+		bin := sort.SearchInts(lengthBins, i+1)
+		counts[bin]++
+	}
+
+	var lower int
+	for i, j := range lengthBins {
+		logrus.Info(fmt.Sprintf("Length %d-%d: %d", lower, j-1, counts[i]))
+		lower = j
+	}
+	logrus.Info(fmt.Sprintf("Length %d or more: %d", lower, counts[len(lengthBins)]))
+
+	return
+}
@@ -0,0 +1,39 @@
+package dat
+
+import "testing"
+
+func TestStats(t *testing.T) {
+
+	var d = New()
+
+	d.Records = []Record{
+		{OriginalHeader: "sp|P1|A_HUMAN Protein A", Sequence: "PEPTIDEK"},
+		{OriginalHeader: "sp|P2|B_HUMAN Protein B", Sequence: "PEPTIDEK"},
+		{OriginalHeader: "sp|P3|C_HUMAN Protein C", Sequence: "PEPTLDEK"},
+		{OriginalHeader: "con_sp|P4|D_BOVIN Protein D", Sequence: "AXBU"},
+		{OriginalHeader: "my protein", Sequence: "ACDEFGHIKLMNPQRSTVWYACDEFGHIKLMNPQRSTVWYACDEFGHIKLMNPQRSTVWY"},
+		{OriginalHeader: "rev_sp|P1|A_HUMAN Protein A", Sequence: "KEDITPEP", IsDecoy: true},
+	}
+
+	s := d.Stats("rev_", "con_")
+
+	if s.Targets != 5 || s.Decoys != 1 || s.Contaminants != 1 {
+		t.Errorf("Stats() counts = %d targets, %d decoys, %d contaminants", s.Targets, s.Decoys, s.Contaminants)
+	}
+
+	if s.DuplicatedSequences != 1 || s.DuplicatedEntries != 2 || s.ILDuplicates != 1 {
+		t.Errorf("Stats() duplicates = %d sequences, %d entries, %d I/L", s.DuplicatedSequences, s.DuplicatedEntries, s.ILDuplicates)
+	}
+
+	if s.Classes["uniprot"] != 5 || s.Classes["generic"] != 1 {
+		t.Errorf("Stats() classes = %v", s.Classes)
+	}
+
+	if len(s.NonStandard) != 3 || s.NonStandard["X"] != 1 {
+		t.Errorf("Stats() non-standard residues = %v", s.NonStandard)
+	}
+
+	if len(s.Lengths) != 5 || s.Lengths[0] != 4 || s.Lengths[4] != 60 {
+		t.Errorf("Stats() lengths = %v", s.Lengths)
+	}
+}
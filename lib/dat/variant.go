@@ -0,0 +1,278 @@
+package dat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+)
+
+// VariantMark separates the accession of a variant entry from the variant description,
+// e.g. sp|P01308|INS_HUMAN_VAR_R54C is the R54C variant of P01308
+const VariantMark = "_VAR_"
+
+// Variant is a protein-level sequence change at a 1-based position. An empty alternative
+// is a deletion and an asterisk is a stop gain
+type Variant struct {
+	Protein  string
+	Position int
+	Ref      string
+	Alt      string
+}
+
+// variantTagReg reads the variant description from a FASTA header
+var variantTagReg = regexp.MustCompile(VariantMark + `([A-Z]+)(\d+)([A-Z*]+|-)`)
+
+// changeReg reads protein changes like R54C, p.R54C, p.Arg54Cys or p.Arg54Ter
+var changeReg = regexp.MustCompile(`^(?:p\.)?([A-Za-z]+?)(\d+)([A-Za-z*]+|del|-)$`)
+
+// threeLetterCodes converts the HGVS residue names to the one letter code
+var threeLetterCodes = map[string]string{
+	"Ala": "A", "Arg": "R", "Asn": "N", "Asp": "D", "Cys": "C", "Gln": "Q", "Glu": "E",
+	"Gly": "G", "His": "H", "Ile": "I", "Leu": "L", "Lys": "K", "Met": "M", "Phe": "F",
+	"Pro": "P", "Ser": "S", "Thr": "T", "Trp": "W", "Tyr": "Y", "Val": "V", "Sec": "U",
+	"Pyl": "O", "Ter": "*",
+}
+
+// Tag describes the variant as reference, position and alternative, e.g. R54C
+func (v Variant) Tag() string {
+
+	alt := v.Alt
+	if len(alt) == 0 {
+		alt = "-"
+	}
+
+	return fmt.Sprintf("%s%d%s", v.Ref, v.Position, alt)
+}
+
+// Apply returns the variant sequence, the reference residues must match the sequence
+func (v Variant) Apply(seq string) (string, error) {
+
+	start := v.Position - 1
+
+	if start < 0 || start+len(v.Ref) > len(seq) || seq[start:start+len(v.Ref)] != v.Ref {
+		return "", errors.New("the reference residues of " + v.Protein + " " + v.Tag() + " do not match the sequence")
+	}
+
+	if strings.HasPrefix(v.Alt, "*") {
+		return seq[:start], nil
+	}
+
+	return seq[:start] + v.Alt + seq[start+len(v.Ref):], nil
+}
+
+// ReadVariants reads a tab-separated variant table. Each line has either the protein ID,
+// position, reference and alternative residues, or the protein ID and a protein change
+// like R54C or p.Arg54Cys as found in VCF annotations. Comments and header lines are skipped
+func ReadVariants(f string) ([]Variant, error) {
+
+	var variants []Variant
+
+	file, e := os.Open(f)
+	if e != nil {
+		return nil, e
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		part := strings.Split(line, "\t")
+		for i := range part {
+			part[i] = strings.TrimSpace(part[i])
+		}
+
+		var v Variant
+		v.Protein = part[0]
+
+		if len(part) >= 4 {
+
+			pos, e := strconv.Atoi(part[1])
+			if e != nil {
+				continue
+			}
+
+			v.Position = pos
+			v.Ref = strings.ToUpper(part[2])
+			v.Alt = strings.ToUpper(part[3])
+
+		} else if len(part) >= 2 {
+
+			change := changeReg.FindStringSubmatch(part[1])
+			if change == nil {
+				continue
+			}
+
+			v.Position, _ = strconv.Atoi(change[2])
+			v.Ref = residueCode(change[1])
+			v.Alt = residueCode(change[3])
+
+			if len(v.Ref) == 0 || v.Position == 0 {
+				continue
+			}
+
+		} else {
+			continue
+		}
+
+		if v.Alt == "-" || v.Alt == "DEL" {
+			v.Alt = ""
+		}
+
+		variants = append(variants, v)
+	}
+
+	if e := scanner.Err(); e != nil {
+		return nil, e
+	}
+
+	return variants, nil
+}
+
+// residueCode converts HGVS three letter residues to one letter codes
+func residueCode(s string) string {
+
+	if s == "del" || s == "-" {
+		return ""
+	} else if s == "*" {
+		return s
+	}
+
+	if len(s)%3 == 0 {
+		var code string
+		for i := 0; i < len(s); i += 3 {
+			c, ok := threeLetterCodes[strings.Title(strings.ToLower(s[i:i+3]))]
+			if !ok {
+				code = ""
+				break
+			}
+			code += c
+		}
+		if len(code) > 0 {
+			return code
+		}
+	}
+
+	return strings.ToUpper(s)
+}
+
+// addVariants creates one variant entry for each variant matching a protein in the
+// database, the protein is found by its ID or by the first word of its header.
+// Contaminants are left unchanged
+func addVariants(db map[string]string, variants []Variant, decoyTag, contamTag string) {
+
+	var byProtein = make(map[string][]Variant)
+	for _, i := range variants {
+		byProtein[i.Protein] = append(byProtein[i.Protein], i)
+	}
+
+	var headers []string
+	for k := range db {
+		headers = append(headers, k)
+	}
+
+	sort.Strings(headers)
+
+	var applied int
+
+	for _, h := range headers {
+
+		r := processRecord(h, db[h], decoyTag, contamTag, nil)
+		if r.IsContaminant {
+			continue
+		}
+
+		list, ok := byProtein[r.ID]
+		if !ok {
+			list, ok = byProtein[r.PartHeader]
+		}
+
+		for _, i := range list {
+
+			seq, e := i.Apply(db[h])
+			if e != nil {
+				msg.Custom(e, "warning")
+				continue
+			}
+
+			part := strings.SplitN(h, " ", 2)
+			header := part[0] + VariantMark + i.Tag()
+			if len(part) > 1 {
+				header += " " + part[1]
+			}
+
+			db[header] = seq
+			applied++
+		}
+	}
+
+	if applied < len(variants) {
+		msg.Custom(fmt.Errorf("%d of %d variants were applied", applied, len(variants)), "warning")
+	}
+
+	return
+}
+
+// parseVariantTag reads the variant description of a header and returns the 1-based
+// range of the variant sequence changed by it. Deletions and stop gains cover the
+// residues next to the missing ones
+func parseVariantTag(header string) (string, int, int) {
+
+	part := strings.SplitN(header, " ", 2)
+
+	tag := variantTagReg.FindStringSubmatch(part[0])
+	if tag == nil {
+		return "", 0, 0
+	}
+
+	pos, _ := strconv.Atoi(tag[2])
+	alt := tag[3]
+
+	if alt == "-" || strings.HasPrefix(alt, "*") {
+		if pos > 1 {
+			return tag[0][len(VariantMark):], pos - 1, pos
+		}
+		return tag[0][len(VariantMark):], pos, pos
+	}
+
+	return tag[0][len(VariantMark):], pos, pos + len(alt) - 1
+}
+
+// CoversVariant tells if the peptide overlaps the changed residues of a variant record
+func (r Record) CoversVariant(peptide string) bool {
+
+	if len(r.Variant) == 0 || len(peptide) == 0 {
+		return false
+	}
+
+	for offset := 0; offset < len(r.Sequence); {
+
+		i := strings.Index(r.Sequence[offset:], peptide)
+		if i < 0 {
+			break
+		}
+
+		start := offset + i + 1
+		end := start + len(peptide) - 1
+
+		if start <= r.VariantEnd && end >= r.VariantStart {
+			return true
+		}
+
+		offset += i + 1
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package dat
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const variantTable = `# protein	position	ref	alt
+P01308	54	R	C
+P01308	p.Arg22Ter
+P01308	p.Gly20del
+P68871	7	E	V
+`
+
+func TestReadVariants(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "variants")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "variants.tsv")
+	if e := ioutil.WriteFile(f, []byte(variantTable), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	variants, e := ReadVariants(f)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := []Variant{
+		{Protein: "P01308", Position: 54, Ref: "R", Alt: "C"},
+		{Protein: "P01308", Position: 22, Ref: "R", Alt: "*"},
+		{Protein: "P01308", Position: 20, Ref: "G", Alt: ""},
+		{Protein: "P68871", Position: 7, Ref: "E", Alt: "V"},
+	}
+
+	if len(variants) != len(want) {
+		t.Fatalf("got %d variants, want %d", len(variants), len(want))
+	}
+
+	for i := range want {
+		if variants[i] != want[i] {
+			t.Errorf("variant %d = %+v, want %+v", i, variants[i], want[i])
+		}
+	}
+}
+
+func TestApplyVariant(t *testing.T) {
+
+	tests := []struct {
+		variant Variant
+		want    string
+		fails   bool
+	}{
+		{Variant{Position: 3, Ref: "C", Alt: "W"}, "ABWDE", false},
+		{Variant{Position: 3, Ref: "C", Alt: ""}, "ABDE", false},
+		{Variant{Position: 3, Ref: "C", Alt: "*"}, "AB", false},
+		{Variant{Position: 2, Ref: "BC", Alt: "XYZ"}, "AXYZDE", false},
+		{Variant{Position: 3, Ref: "D", Alt: "W"}, "", true},
+		{Variant{Position: 6, Ref: "E", Alt: "W"}, "", true},
+	}
+
+	for _, i := range tests {
+		got, e := i.variant.Apply("ABCDE")
+		if (e != nil) != i.fails {
+			t.Errorf("%s: error = %v", i.variant.Tag(), e)
+		}
+		if got != i.want {
+			t.Errorf("%s: got %s, want %s", i.variant.Tag(), got, i.want)
+		}
+	}
+}
+
+func TestAddVariants(t *testing.T) {
+
+	db := map[string]string{
+		"sp|P01308|INS_HUMAN Insulin OS=Homo sapiens OX=9606 GN=INS PE=1 SV=1": "MALWMRLLPLLALLALWGPDPAAAFVNQHLCGSHLVEALYLVCGERGFFYTPKTRREAEDLQVGQVELGGGPGAGSLQPLALEGSLQKRGIVEQCCTSICSLYQLENYCN",
+	}
+
+	addVariants(db, []Variant{{Protein: "P01308", Position: 10, Ref: "L", Alt: "P"}}, "rev_", "con_")
+
+	header := "sp|P01308|INS_HUMAN_VAR_L10P Insulin OS=Homo sapiens OX=9606 GN=INS PE=1 SV=1"
+	seq, ok := db[header]
+	if !ok {
+		t.Fatalf("variant entry missing: %v", db)
+	}
+
+	if seq[9] != 'P' || len(seq) != 110 {
+		t.Errorf("variant sequence = %s", seq)
+	}
+
+	r := processRecord(header, seq, "rev_", "con_", nil)
+
+	if r.ID != "P01308" || r.Variant != "L10P" || r.VariantStart != 10 || r.VariantEnd != 10 {
+		t.Errorf("record = %s %s %d-%d", r.ID, r.Variant, r.VariantStart, r.VariantEnd)
+	}
+
+	if !r.CoversVariant("MALWMRLLPPLALLALWGPDPAAAFVNQHLCGSHLVEALYLVCGER") {
+		t.Errorf("peptide covering the variant not flagged")
+	}
+
+	if r.CoversVariant("GFFYTPK") {
+		t.Errorf("peptide away from the variant flagged")
+	}
+}
+
+func TestParseVariantTag(t *testing.T) {
+
+	tests := []struct {
+		header     string
+		tag        string
+		start, end int
+	}{
+		{"sp|P01308|INS_HUMAN_VAR_R54C Insulin", "R54C", 54, 54},
+		{"sp|P01308|INS_HUMAN_VAR_G20- Insulin", "G20-", 19, 20},
+		{"sp|P01308|INS_HUMAN_VAR_R22*", "R22*", 21, 22},
+		{"sp|P01308|INS_HUMAN_VAR_BC2XYZ", "BC2XYZ", 2, 4},
+		{"sp|P01308|INS_HUMAN Insulin _VAR_R54C", "", 0, 0},
+	}
+
+	for _, i := range tests {
+		tag, start, end := parseVariantTag(i.header)
+		if tag != i.tag || start != i.start || end != i.end {
+			t.Errorf("%s: got %s %d-%d, want %s %d-%d", i.header, tag, start, end, i.tag, i.start, i.end)
+		}
+	}
+}
@@ -30,10 +30,8 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 	//var msg string
 	var targets float64
 	var decoys float64
-	var calcFDR float64
 	var list id.PepIDList
 	var peplist id.PepIDList
-	var minProb float64 = 10
 
 	if strings.EqualFold(level, "PSM") {
 
@@ -85,6 +83,48 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 
 	sort.Sort(list)
 
+	var scores []fdrScore
+	for i := range list {
+		scores = append(scores, fdrScore{list[i].Probability, cla.IsDecoyPSM(list[i], decoyTag)})
+	}
+
+	probList, minProb, calcFDR := fdrThresholds(scores, targets, decoys, targetFDR)
+
+	var cleanlist id.PepIDList
+	decoys = 0
+	targets = 0
+
+	for i := range list {
+		_, ok := probList[list[i].Probability]
+		if ok {
+			cleanlist = append(cleanlist, list[i])
+			if cla.IsDecoyPSM(list[i], decoyTag) {
+				decoys++
+			} else {
+				targets++
+			}
+		}
+	}
+
+	logFDR(level, calcFDR, minProb, targets, decoys)
+
+	return cleanlist, minProb
+}
+
+// fdrScore is the probability and the decoy status of an identification
+type fdrScore struct {
+	Probability float64
+	IsDecoy     bool
+}
+
+// fdrThresholds finds the probabilities at which the list, sorted from the highest to
+// the lowest probability, is below the target FDR. It returns the accepted probabilities,
+// the minimum accepted probability and the FDR at that probability
+func fdrThresholds(list []fdrScore, targets, decoys, targetFDR float64) (map[float64]uint8, float64, float64) {
+
+	var calcFDR float64
+	var minProb float64 = 10
+
 	var scoreMap = make(map[float64]float64)
 	limit := (len(list) - 1)
 
@@ -93,7 +133,7 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 		if !ok {
 			scoreMap[list[j].Probability] = (decoys / targets)
 		}
-		if cla.IsDecoyPSM(list[j], decoyTag) {
+		if list[j].IsDecoy {
 			decoys--
 		} else {
 			targets--
@@ -109,34 +149,18 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 
 	var probList = make(map[float64]uint8)
 	for i := range keys {
-
-		//f := fmt.Sprintf("%.2f", scoreMap[keys[i]]*100)
-		//f := uti.Round(scoreMap[keys[i]]*100, 5, 2)
-		//fmt.Println(keys[i], "\t", scoreMap[keys[i]], "\t", uti.ToFixed(scoreMap[keys[i]], 4), "\t", f, "\t", targetFDR)
-
 		if uti.ToFixed(scoreMap[keys[i]], 4) <= targetFDR {
 			probList[keys[i]] = 0
 			minProb = keys[i]
 			calcFDR = uti.ToFixed(scoreMap[keys[i]], 4)
 		}
-
 	}
 
-	var cleanlist id.PepIDList
-	decoys = 0
-	targets = 0
+	return probList, minProb, calcFDR
+}
 
-	for i := range list {
-		_, ok := probList[list[i].Probability]
-		if ok {
-			cleanlist = append(cleanlist, list[i])
-			if cla.IsDecoyPSM(list[i], decoyTag) {
-				decoys++
-			} else {
-				targets++
-			}
-		}
-	}
+// logFDR reports the FDR convergence for a level
+func logFDR(level string, calcFDR, minProb, targets, decoys float64) {
 
 	msg := fmt.Sprintf("Converged to %.2f %% FDR with %0.f %ss", (calcFDR * 100), targets, level)
 	logrus.WithFields(logrus.Fields{
@@ -145,7 +169,7 @@ func PepXMLFDRFilter(input map[string]id.PepIDList, targetFDR float64, level, de
 		"threshold": minProb,
 	}).Info(msg)
 
-	return cleanlist, minProb
+	return
 }
 
 // PickedFDR employs the picked FDR strategy
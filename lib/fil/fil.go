@@ -38,23 +38,86 @@ func Run(f met.Data) met.Data {
 
 	logrus.Info("Processing peptide identification files")
 
+	if len(f.Filter.Secondary) == 0 {
+		f.Filter.Secondary = "none"
+	}
+
+	if f.Filter.Secondary != "none" && f.Filter.Secondary != "best" && f.Filter.Secondary != "fdr" {
+		msg.Custom(fmt.Errorf("Unknown policy for lower-ranked hits: %s", f.Filter.Secondary), "fatal")
+	}
+
+	// the streaming mode does not keep the complete identification list required by the
+	// two-dimensional and the modification based filters
+	if f.Filter.Stream == true {
+		if f.Filter.TwoD == true {
+			msg.Custom(errors.New("The two-dimensional filter cannot be used with --stream, use --sequential instead"), "fatal")
+		}
+		if len(f.Filter.Mods) > 0 {
+			msg.Custom(errors.New("The modification based filter cannot be used with --stream"), "fatal")
+		}
+		if f.Filter.Secondary != "none" {
+			msg.Custom(errors.New("Lower-ranked hits cannot be kept with --stream"), "fatal")
+		}
+		if len(f.Filter.Percolator) > 0 {
+			msg.Custom(errors.New("Percolator results cannot be imported with --stream"), "fatal")
+		}
+		if len(f.Filter.Pox) > 0 && f.Filter.Seq == false {
+			f.Filter.Seq = true
+		}
+	}
+
 	// if no method is selected, force the 2D to be default
 	if len(f.Filter.Pox) > 0 && f.Filter.TwoD == false && f.Filter.Seq == false {
 		f.Filter.TwoD = true
 	}
 
-	pepid, searchEngine := id.ReadPepXMLInput(f.Filter.Pex, f.Filter.Tag, f.Temp, f.Filter.Model)
+	// proteins with identical sequences are reported by their canonical database entry
+	aliases := databaseAliases()
+
+	// lower-ranked hits are filtered after the top-ranked PSMs
+	var lower id.PepIDList
+
+	if f.Filter.Stream == true {
+
+		logrus.Info("Streaming peptide identifications")
+		f.SearchEngine, f.Prophet = streamPeptideIdentifications(f.Filter.Pex, f.Filter.Tag, f.Temp, f.Filter.Model, aliases, f.Filter.PsmFDR, f.Filter.PepFDR, f.Filter.IonFDR)
+
+	} else {
+
+		pepid, searchEngine, prophet := id.ReadPepXMLInput(f.Filter.Pex, f.Filter.Tag, f.Temp, f.Filter.Model, f.Filter.Secondary != "none")
+
+		if len(aliases) > 0 {
+			logrus.Info("Collapsing proteins with identical sequences")
 
-	f.SearchEngine = searchEngine
+			pepid.CollapseAliases(aliases)
+
+			pepxml.Restore()
+			pepxml.PeptideIdentification.CollapseAliases(aliases)
+			pepxml.Serialize()
+			pepxml = id.PepXML{}
+		}
 
-	psmT, pepT, ionT := processPeptideIdentifications(pepid, f.Filter.Tag, f.Filter.Mods, f.Filter.PsmFDR, f.Filter.PepFDR, f.Filter.IonFDR)
-	_ = psmT
-	_ = pepT
-	_ = ionT
+		f.SearchEngine = searchEngine
+		f.Prophet = prophet
+
+		// Percolator or mokapot results replace the PeptideProphet probabilities
+		if len(f.Filter.Percolator) > 0 {
+			logrus.Info("Importing Percolator results")
+			pepid = importPercolatorResults(pepid, f.Filter.Percolator, f.Filter.Tag)
+			f.Prophet = "percolator"
+		}
+
+		pepid, lower = pepid.SplitByRank()
+
+		psmT, pepT, ionT := processPeptideIdentifications(pepid, f.Filter.Tag, f.Filter.Mods, f.Filter.PsmFDR, f.Filter.PepFDR, f.Filter.IonFDR)
+		_ = psmT
+		_ = pepT
+		_ = ionT
+	}
 
 	if len(f.Filter.Pox) > 0 {
 
-		protXML := readProtXMLInput(f.Filter.Pox, f.Filter.Tag, f.Filter.Weight)
+		protXML := readProtXMLInput(f.Filter.Pox, f.Filter.Tag, f.Filter.Weight, aliases)
 		processProteinIdentifications(protXML, f.Filter.PtFDR, f.Filter.PepFDR, f.Filter.ProtProb, f.Filter.Picked, f.Filter.Razor, f.Filter.Fo, f.Filter.Tag)
 
 	} else {
@@ -98,6 +161,11 @@ func Run(f met.Data) met.Data {
 
 	}
 
+	if len(lower) > 0 {
+		processLowerRankedHits(lower, f.Filter.Secondary, f.Filter.PsmFDR, f.Filter.Tag, len(f.Filter.Pox) > 0 || f.Filter.Inference == true)
+		lower = nil
+	}
+
 	var dtb dat.Base
 	dtb.Restore()
 	if len(dtb.Records) < 1 {
@@ -110,6 +178,17 @@ func Run(f met.Data) met.Data {
 	var pxml id.PepXML
 	pxml.Restore()
 
+	// the search database must be the one processed by the database command
+	if f.Filter.DBCheck != "none" {
+		if err := dat.CheckSearchDatabase(pxml.SearchDatabases, f.Database); err != nil {
+			if f.Filter.DBCheck == "warning" {
+				msg.Custom(err, "warning")
+			} else {
+				msg.Custom(err, "fatal")
+			}
+		}
+	}
+
 	e.Mods = pxml.Modifications
 	e.AssembleSearchParameters(pxml.SearchParameters)
 	pxml = id.PepXML{}
@@ -174,7 +253,7 @@ func Run(f met.Data) met.Data {
 	}
 
 	if len(f.Filter.Pox) > 0 || f.Filter.Inference == true {
-		e.UpdateNumberOfEnzymaticTermini()
+		e.UpdateNumberOfEnzymaticTermini(f.Database.Enz)
 	}
 
 	logrus.Info("Calculating spectral counts")
@@ -186,6 +265,15 @@ func Run(f met.Data) met.Data {
 	return f
 }
 
+// databaseAliases maps the collapsed database entries to their canonical entries
+func databaseAliases() map[string]string {
+
+	var dtb dat.Base
+	dtb.Restore()
+
+	return dtb.AliasMap()
+}
+
 // processPeptideIdentifications reads and process pepXML
 func processPeptideIdentifications(p id.PepIDList, decoyTag, mods string, psm, peptide, ion float64) (float64, float64, float64) {
 
@@ -419,7 +507,7 @@ func GetUniquePeptides(p id.PepIDList) map[string]id.PepIDList {
 }
 
 // readProtXMLInput reads one or more fies and organize the data into PSM list
-func readProtXMLInput(xmlFile, decoyTag string, weight float64) id.ProtXML {
+func readProtXMLInput(xmlFile, decoyTag string, weight float64, aliases map[string]string) id.ProtXML {
 
 	var protXML id.ProtXML
 
@@ -427,6 +515,8 @@ func readProtXMLInput(xmlFile, decoyTag string, weight float64) id.ProtXML {
 
 	protXML.DecoyTag = decoyTag
 
+	protXML.CollapseAliases(aliases)
+
 	protXML.MarkUniquePeptides(weight)
 
 	protXML.PromoteProteinIDs()
@@ -39,7 +39,7 @@ func Test_readPepXMLInput(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 
-			got, got1 := id.ReadPepXMLInput(tt.args.xmlFile, tt.args.decoyTag, tt.args.temp, tt.args.models)
+			got, got1, _ := id.ReadPepXMLInput(tt.args.xmlFile, tt.args.decoyTag, tt.args.temp, tt.args.models, false)
 			pepIDList = got
 
 			if !reflect.DeepEqual(len(got), tt.want) {
@@ -293,7 +293,7 @@ func Test_readProtXMLInput(t *testing.T) {
 	for _, tt := range test1 {
 		t.Run(tt.name, func(t *testing.T) {
 
-			got := readProtXMLInput(tt.args.xmlFile, tt.args.decoyTag, tt.args.weight)
+			got := readProtXMLInput(tt.args.xmlFile, tt.args.decoyTag, tt.args.weight, nil)
 			proXML = got
 
 			if len(got.Groups) != tt.want {
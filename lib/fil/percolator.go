@@ -0,0 +1,52 @@
+package fil
+
+import (
+	"errors"
+
+	"philosopher/lib/cla"
+	"philosopher/lib/id"
+	"philosopher/lib/msg"
+
+	"github.com/sirupsen/logrus"
+)
+
+// importPercolatorResults replaces the pepXML probabilities with the ones derived from the
+// Percolator or mokapot q-values and PEPs, see id.PercolatorProbability. The identifications
+// that were not validated are removed from the list and from the serialized pepXML data, so
+// that the two-dimensional filter and the reports only see the rescored identifications
+func importPercolatorResults(pepid id.PepIDList, files, decoyTag string) id.PepIDList {
+
+	results := id.ReadPercolatorInput(files)
+
+	pepid, missing := pepid.Rescore(results)
+
+	var pepxml id.PepXML
+	pepxml.Restore()
+	pepxml.PeptideIdentification, _ = pepxml.PeptideIdentification.Rescore(results)
+	pepxml.Prophet = "percolator"
+	pepxml.Serialize()
+
+	var decoys int
+	for _, i := range pepid {
+		if cla.IsDecoyPSM(i, decoyTag) {
+			decoys++
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"validated": len(results),
+		"rescored":  len(pepid),
+		"missing":   missing,
+		"decoys":    decoys,
+	}).Info("Percolator results")
+
+	if len(pepid) == 0 {
+		msg.Custom(errors.New("No identifications matched the Percolator results, check that the PSM identifiers refer to the same runs and scans"), "fatal")
+	}
+
+	if decoys == 0 {
+		msg.Custom(errors.New("No decoy PSMs were imported, the FDR estimation requires the decoy results of Percolator or mokapot"), "warning")
+	}
+
+	return pepid
+}
@@ -0,0 +1,117 @@
+package fil
+
+import (
+	"sort"
+
+	"philosopher/lib/cla"
+	"philosopher/lib/id"
+
+	"github.com/sirupsen/logrus"
+)
+
+// processLowerRankedHits adds the lower-ranked hits of spectra with an accepted top-ranked PSM
+// to the filtered PSMs. When a protein list was filtered, only the hits mapping to an accepted
+// target protein are kept, so that the protein-level FDR also applies to the lower-ranked hits
+func processLowerRankedHits(lower id.PepIDList, policy string, psmFDR float64, decoyTag string, hasProteins bool) {
+
+	var psm id.PepIDList
+	psm.Restore("psm")
+
+	accepted := selectLowerRankedHits(psm, lower, policy, psmFDR, decoyTag)
+
+	if hasProteins == true {
+		var pro id.ProtIDList
+		pro.Restore()
+		accepted = proteinMappedHits(accepted, pro, decoyTag)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"accepted": len(accepted),
+	}).Info("Lower-ranked hits on accepted proteins")
+
+	psm = append(psm, accepted...)
+	sort.Sort(psm)
+	psm.Serialize("psm")
+
+	return
+}
+
+// selectLowerRankedHits returns the lower-ranked hits of the accepted spectra that pass the policy.
+// With the best policy the FDR is estimated on the top-ranked hits only and a lower-ranked hit is
+// accepted when its probability reaches the lowest accepted probability, with the fdr policy the
+// lower-ranked hits go through a second PSM FDR estimation of their own
+func selectLowerRankedHits(psm, lower id.PepIDList, policy string, psmFDR float64, decoyTag string) id.PepIDList {
+
+	var spectra = make(map[string]uint8)
+	var minProb float64 = 10
+
+	for _, i := range psm {
+		spectra[i.Spectrum] = 0
+		if i.Probability < minProb {
+			minProb = i.Probability
+		}
+	}
+
+	var candidates id.PepIDList
+	for _, i := range lower {
+		if _, ok := spectra[i.Spectrum]; ok {
+			candidates = append(candidates, i)
+		}
+	}
+
+	var accepted id.PepIDList
+
+	if len(candidates) > 0 {
+		if policy == "best" {
+			for _, i := range candidates {
+				if i.Probability >= minProb {
+					accepted = append(accepted, i)
+				}
+			}
+		} else if policy == "fdr" {
+			logrus.Info("Second-pass FDR estimation for lower-ranked hits")
+			accepted, _ = PepXMLFDRFilter(GetUniquePSMs(candidates), psmFDR, "PSM", decoyTag)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"candidates": len(candidates),
+		"accepted":   len(accepted),
+	}).Info("Lower-ranked hits")
+
+	return accepted
+}
+
+// proteinMappedHits keeps the hits whose protein or one of the alternative proteins is an
+// accepted target protein
+func proteinMappedHits(hits id.PepIDList, pro id.ProtIDList, decoyTag string) id.PepIDList {
+
+	var proteins = make(map[string]uint8)
+	for _, i := range pro {
+		if !cla.IsDecoy(i.ProteinName, decoyTag) {
+			proteins[i.ProteinName] = 0
+			for _, j := range i.IndistinguishableProtein {
+				proteins[j] = 0
+			}
+		}
+	}
+
+	var mapped id.PepIDList
+
+	for _, i := range hits {
+
+		_, ok := proteins[i.Protein]
+		for _, j := range i.AlternativeProteins {
+			if ok {
+				break
+			}
+			_, ok = proteins[j]
+		}
+
+		if ok {
+			mapped = append(mapped, i)
+		}
+	}
+
+	return mapped
+}
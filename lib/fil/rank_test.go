@@ -0,0 +1,52 @@
+package fil
+
+import (
+	"testing"
+
+	"philosopher/lib/id"
+)
+
+func TestSelectLowerRankedHits(t *testing.T) {
+
+	psm := id.PepIDList{
+		{Spectrum: "run.00001.00001.2", HitRank: 1, Peptide: "PEPTIDEK", Protein: "sp|P1|A_HUMAN", Probability: 0.99},
+		{Spectrum: "run.00002.00002.2", HitRank: 1, Peptide: "SAMPLER", Protein: "sp|P2|B_HUMAN", Probability: 0.9},
+	}
+
+	lower := id.PepIDList{
+		{Spectrum: "run.00001.00001.2", HitRank: 2, Peptide: "PEPTLDEK", Protein: "sp|P1|A_HUMAN", Probability: 0.95},
+		{Spectrum: "run.00002.00002.2", HitRank: 2, Peptide: "SAMPLEK", Protein: "sp|P3|C_HUMAN", Probability: 0.5},
+		{Spectrum: "run.00002.00002.2", HitRank: 3, Peptide: "KELPMAS", Protein: "rev_sp|P3|C_HUMAN", Probability: 0.2},
+		{Spectrum: "run.00003.00003.2", HitRank: 2, Peptide: "MAGICK", Protein: "sp|P1|A_HUMAN", Probability: 0.99},
+	}
+
+	best := selectLowerRankedHits(psm, lower, "best", 0.01, "rev_")
+	if len(best) != 1 || best[0].Peptide != "PEPTLDEK" {
+		t.Errorf("best policy accepted %+v", best)
+	}
+
+	// the decoy hit is rejected by the second-pass FDR
+	fdr := selectLowerRankedHits(psm, lower, "fdr", 0.01, "rev_")
+	if len(fdr) != 2 {
+		t.Errorf("fdr policy accepted %d hits, want 2", len(fdr))
+	}
+	for _, i := range fdr {
+		if i.Peptide != "PEPTLDEK" && i.Peptide != "SAMPLEK" {
+			t.Errorf("fdr policy accepted %s", i.Peptide)
+		}
+	}
+
+	if none := selectLowerRankedHits(psm, lower, "none", 0.01, "rev_"); len(none) != 0 {
+		t.Errorf("none policy accepted %d hits", len(none))
+	}
+
+	pro := id.ProtIDList{
+		{ProteinName: "sp|P1|A_HUMAN"},
+		{ProteinName: "rev_sp|P3|C_HUMAN"},
+	}
+
+	mapped := proteinMappedHits(lower, pro, "rev_")
+	if len(mapped) != 2 || mapped[0].Peptide != "PEPTLDEK" || mapped[1].Peptide != "MAGICK" {
+		t.Errorf("hits on accepted proteins = %+v", mapped)
+	}
+}
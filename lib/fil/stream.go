@@ -0,0 +1,213 @@
+package fil
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/cla"
+	"philosopher/lib/id"
+	"philosopher/lib/msg"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack"
+)
+
+// streamBest is the best scoring identification of a peptide or an ion
+type streamBest struct {
+	Spectrum string
+	fdrScore
+}
+
+// streamPeptideIdentifications reads the pepXML files one identification at a time and
+// applies the PSM, peptide and ion FDR filters. The identifications are written to a
+// temporary file, only their scores and the best identification of each peptide and ion
+// are kept in memory, and the passing identifications are selected in a second pass. The
+// search engine and the analysis of the files are returned
+func streamPeptideIdentifications(xmlFile, decoyTag, temp string, models bool, aliases map[string]string, psm, peptide, ion float64) (string, string) {
+
+	var psmScores []fdrScore
+	var charges = make(map[uint8][2]int)
+	var peptides = make(map[string]streamBest)
+	var ions = make(map[string]streamBest)
+
+	spill := filepath.Join(temp, "pepxml.stream.bin")
+
+	file, e := os.Create(spill)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+	defer os.Remove(spill)
+
+	w := bufio.NewWriter(file)
+	enc := msgpack.NewEncoder(w)
+
+	searchEngine, prophet := id.StreamPepXMLInput(xmlFile, decoyTag, temp, models, func(p id.PeptideIdentification) {
+
+		if p.HitRank > 1 {
+			return
+		}
+
+		// proteins with identical sequences are reported by their canonical database entry
+		p.CollapseAliases(aliases)
+
+		if e := enc.Encode(&p); e != nil {
+			msg.MarshalFile(e, "fatal")
+		}
+
+		s := fdrScore{p.Probability, cla.IsDecoyPSM(p, decoyTag)}
+
+		psmScores = append(psmScores, s)
+
+		c := charges[p.AssumedCharge]
+		if strings.HasPrefix(p.Protein, decoyTag) {
+			c[1]++
+		} else {
+			c[0]++
+		}
+		charges[p.AssumedCharge] = c
+
+		best, ok := peptides[p.Peptide]
+		if !ok || p.Probability > best.Probability {
+			peptides[p.Peptide] = streamBest{p.Spectrum, s}
+		}
+
+		key := fmt.Sprintf("%s#%d#%.4f", p.Peptide, p.AssumedCharge, p.CalcNeutralPepMass)
+		best, ok = ions[key]
+		if !ok || p.Probability > best.Probability {
+			ions[key] = streamBest{p.Spectrum, s}
+		}
+	})
+
+	if e := w.Flush(); e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+	file.Close()
+
+	// report charge profile
+	for i := uint8(1); i <= 6; i++ {
+		logrus.WithFields(logrus.Fields{
+			"target": charges[i][0],
+			"decoy":  charges[i][1],
+		}).Info(fmt.Sprintf("%d+ Charge profile", i))
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"psms":     len(psmScores),
+		"peptides": len(peptides),
+		"ions":     len(ions),
+	}).Info("Database search results")
+
+	psmProbs, psmThreshold, psmFDR := streamThresholds(psmScores, psm)
+	psmScores = nil
+
+	pepProbs, pepThreshold, pepFDR := streamThresholds(bestScores(peptides), peptide)
+	ionProbs, ionThreshold, ionFDR := streamThresholds(bestScores(ions), ion)
+
+	var filteredPSM, filteredPeptides, filteredIons id.PepIDList
+
+	file, e = os.Open(spill)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	dec := msgpack.NewDecoder(bufio.NewReader(file))
+
+	for {
+		var p id.PeptideIdentification
+		if e := dec.Decode(&p); e == io.EOF {
+			break
+		} else if e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		if _, ok := psmProbs[p.Probability]; ok {
+			filteredPSM = append(filteredPSM, p)
+		}
+
+		if best := peptides[p.Peptide]; best.Spectrum == p.Spectrum && best.Probability == p.Probability {
+			if _, ok := pepProbs[p.Probability]; ok {
+				filteredPeptides = append(filteredPeptides, p)
+			}
+			delete(peptides, p.Peptide)
+		}
+
+		key := fmt.Sprintf("%s#%d#%.4f", p.Peptide, p.AssumedCharge, p.CalcNeutralPepMass)
+		if best := ions[key]; best.Spectrum == p.Spectrum && best.Probability == p.Probability {
+			if _, ok := ionProbs[p.Probability]; ok {
+				filteredIons = append(filteredIons, p)
+			}
+			delete(ions, key)
+		}
+	}
+
+	file.Close()
+
+	for _, i := range []struct {
+		level     string
+		list      id.PepIDList
+		fdr       float64
+		threshold float64
+	}{
+		{"PSM", filteredPSM, psmFDR, psmThreshold},
+		{"Peptide", filteredPeptides, pepFDR, pepThreshold},
+		{"Ion", filteredIons, ionFDR, ionThreshold},
+	} {
+		var targets, decoys float64
+		for _, j := range i.list {
+			if cla.IsDecoyPSM(j, decoyTag) {
+				decoys++
+			} else {
+				targets++
+			}
+		}
+		logFDR(i.level, i.fdr, i.threshold, targets, decoys)
+	}
+
+	sort.Sort(filteredPSM)
+	filteredPSM.Serialize("psm")
+
+	sort.Sort(filteredPeptides)
+	filteredPeptides.Serialize("pep")
+
+	sort.Sort(filteredIons)
+	filteredIons.Serialize("ion")
+
+	return searchEngine, prophet
+}
+
+// streamThresholds sorts the scores and finds the accepted probabilities
+func streamThresholds(scores []fdrScore, targetFDR float64) (map[float64]uint8, float64, float64) {
+
+	var targets, decoys float64
+
+	for _, i := range scores {
+		if i.IsDecoy {
+			decoys++
+		} else {
+			targets++
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Probability > scores[j].Probability
+	})
+
+	return fdrThresholds(scores, targets, decoys, targetFDR)
+}
+
+// bestScores lists the scores of the best identifications
+func bestScores(best map[string]streamBest) []fdrScore {
+
+	var scores []fdrScore
+
+	for _, v := range best {
+		scores = append(scores, v.fdrScore)
+	}
+
+	return scores
+}
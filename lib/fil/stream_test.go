@@ -0,0 +1,150 @@
+package fil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"philosopher/lib/id"
+	"philosopher/lib/sys"
+)
+
+// streamTestPepXML creates target and decoy PSMs with distinct probabilities, some peptides
+// are identified in several spectra and with several charge states
+func streamTestPepXML() string {
+
+	var b strings.Builder
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<msms_pipeline_analysis date="2020-01-01T00:00:00" summary_xml="interact.pep.xml">
+<analysis_summary analysis="peptideprophet" time="2020-01-01T00:00:00"/>
+<msms_run_summary base_name="run" raw_data=".mzML">
+<search_summary base_name="run" search_engine="X! Tandem" search_engine_version="MSFragger-3.0">
+<search_database local_path="/data/db.fas" type="AA"/>
+</search_summary>
+`)
+
+	peptides := []string{"PEPTIDEK", "SAMPLER", "MAGICK", "PROTEINR", "ANALYSISK", "FILTERK", "STREAMR", "MEMORYK"}
+
+	for n := 1; n <= 60; n++ {
+
+		protein := fmt.Sprintf("sp|P%d|A_HUMAN", n%5)
+		peptide := peptides[n%len(peptides)]
+		probability := 1 - float64(n)/100
+
+		// decoys concentrate on the low probabilities
+		if n%11 == 0 || n > 40 && n%2 == 0 {
+			protein = "rev_" + protein
+			peptide = peptide + "R"
+		}
+
+		charge := 2 + n%2
+
+		// the alternative protein is an alias of the main protein on some identifications
+		alternative := strings.Replace(protein, "A_HUMAN", "B_HUMAN", 1)
+
+		fmt.Fprintf(&b, `<spectrum_query spectrum="run.%05d.%05d.%d" start_scan="%d" end_scan="%d" precursor_neutral_mass="1000.5" assumed_charge="%d" index="%d" retention_time_sec="600.0">
+<search_result>
+<search_hit hit_rank="1" peptide="%s" protein="%s" num_tot_proteins="2" calc_neutral_pep_mass="1000.5" massdiff="0.0">
+<alternative_protein protein="%s"/>
+<analysis_result analysis="peptideprophet"><peptideprophet_result probability="%.2f"/></analysis_result>
+</search_hit>
+</search_result>
+</spectrum_query>
+`, n, n, charge, n, n, charge, n, peptide, protein, alternative, probability)
+	}
+
+	b.WriteString("</msms_run_summary>\n</msms_pipeline_analysis>\n")
+
+	return b.String()
+}
+
+func TestStreamPeptideIdentifications(t *testing.T) {
+
+	wd, e := os.Getwd()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	dir, e := ioutil.TempDir("", "stream")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	if e := os.Chdir(dir); e != nil {
+		t.Fatal(e)
+	}
+	defer os.Chdir(wd)
+
+	if e := os.Mkdir(sys.MetaDir(), 0755); e != nil {
+		t.Fatal(e)
+	}
+
+	f := filepath.Join(dir, "interact.pep.xml")
+	if e := ioutil.WriteFile(f, []byte(streamTestPepXML()), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	// proteins with identical sequences are collapsed into their canonical entry
+	aliases := map[string]string{
+		"sp|P1|A_HUMAN":     "sp|P1|B_HUMAN",
+		"sp|P2|B_HUMAN":     "sp|P2|A_HUMAN",
+		"rev_sp|P1|A_HUMAN": "rev_sp|P1|B_HUMAN",
+	}
+
+	for _, c := range []map[string]string{nil, aliases} {
+
+		pepid, _, _ := id.ReadPepXMLInput(f, "rev_", dir, false, false)
+		pepid.CollapseAliases(c)
+		processPeptideIdentifications(pepid, "rev_", "", 0.05, 0.1, 0.1)
+
+		var want = make(map[string][]string)
+		for _, i := range []string{"psm", "pep", "ion"} {
+			want[i] = streamTestSpectra(i)
+		}
+
+		streamPeptideIdentifications(f, "rev_", dir, false, c, 0.05, 0.1, 0.1)
+
+		for _, i := range []string{"psm", "pep", "ion"} {
+
+			got := streamTestSpectra(i)
+
+			if len(got) == 0 {
+				t.Errorf("no %s identifications passed the filter", i)
+			}
+
+			if strings.Join(got, ",") != strings.Join(want[i], ",") {
+				t.Errorf("%s identifications differ with aliases %v:\n%v\n%v", i, c, got, want[i])
+			}
+
+			for _, j := range got {
+				for _, k := range strings.Fields(j) {
+					if _, ok := c[k]; ok {
+						t.Errorf("%s identification %s keeps the alias %s", i, j, k)
+					}
+				}
+			}
+		}
+	}
+}
+
+// streamTestSpectra lists the spectra of the serialized identifications with their proteins
+func streamTestSpectra(level string) []string {
+
+	var p id.PepIDList
+	p.Restore(level)
+
+	var spectra []string
+	for _, i := range p {
+		spectra = append(spectra, fmt.Sprintf("%s %s %s %d", i.Spectrum, i.Protein, strings.Join(i.AlternativeProteins, " "), i.NumberTotalProteins))
+	}
+
+	sort.Strings(spectra)
+
+	return spectra
+}
@@ -3,6 +3,7 @@ package id
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"path"
@@ -36,6 +37,8 @@ type PepXML struct {
 	DecoyTag              string
 	SearchParameters      []spc.Parameter
 	Database              string
+	DatabaseEntries       int
+	SearchDatabases       map[string]int
 	Prophet               string
 	Modifications         mod.Modifications
 	Models                []spc.DistributionPoint
@@ -72,7 +75,10 @@ type PeptideIdentification struct {
 	Massdiff                         float64
 	LocalizedPTMSites                map[string]int
 	LocalizedPTMMassDiff             map[string]string
+	LocalizedPTMProbabilities        map[string]map[int]float64
 	Probability                      float64
+	PeptideProphetProbability        float64
+	InterProphetProbability          float64
 	IsoMassD                         int
 	Expectation                      float64
 	Xcorr                            float64
@@ -115,177 +121,218 @@ func (p *PepXML) Read(f string) {
 
 	var mpa = xml.MsmsPipelineAnalysis
 
-	if len(mpa.AnalysisSummary) > 0 {
-		p.FileName = path.Base(f)
-		p.Database = string(mpa.MsmsRunSummary.SearchSummary.SearchDatabase.LocalPath)
-		p.SpectraFile = fmt.Sprintf("%s%s", mpa.MsmsRunSummary.BaseName, mpa.MsmsRunSummary.RawData)
-
-		var models []spc.DistributionPoint
-
-		// collect distribution points from meta
-		for _, i := range mpa.AnalysisSummary[0].PeptideprophetSummary.DistributionPoint {
-			var m spc.DistributionPoint
-			m.Fvalue = i.Fvalue
-			m.Obs1Distr = i.Obs1Distr
-			m.Model1PosDistr = i.Model1PosDistr
-			m.Model1NegDistr = i.Model1NegDistr
-			m.Obs2Distr = i.Obs2Distr
-			m.Model2PosDistr = i.Model2PosDistr
-			m.Model2NegDistr = i.Model2NegDistr
-			m.Obs3Distr = i.Obs3Distr
-			m.Model3PosDistr = i.Model3PosDistr
-			m.Model3NegDistr = i.Model3NegDistr
-			m.Obs4Distr = i.Obs4Distr
-			m.Model4PosDistr = i.Model4PosDistr
-			m.Model4NegDistr = i.Model4NegDistr
-			m.Obs5Distr = i.Obs5Distr
-			m.Model5PosDistr = i.Model5PosDistr
-			m.Model5NegDistr = i.Model5NegDistr
-			m.Obs6Distr = i.Obs6Distr
-			m.Model6PosDistr = i.Model6PosDistr
-			m.Model6NegDistr = i.Model6NegDistr
-			m.Obs7Distr = i.Obs7Distr
-			m.Model7PosDistr = i.Model7PosDistr
-			m.Model7NegDistr = i.Model7NegDistr
-			models = append(models, m)
-		}
-
-		p.Modifications.Index = make(map[string]mod.Modification)
-
-		// get the search engine
-		p.SearchEngine = string(mpa.MsmsRunSummary.SearchSummary.SearchEngine)
-		if strings.Contains(string(mpa.MsmsRunSummary.SearchSummary.SearchEngineVersion), "MSFragger") {
-			p.SearchEngine = "MSFragger"
-		}
-
-		// map internal modifications from file
-		for _, i := range mpa.MsmsRunSummary.SearchSummary.AminoAcidModifications {
-
-			key := fmt.Sprintf("%s#%.4f", i.AminoAcid, i.Mass)
-
-			_, ok := p.Modifications.Index[key]
-			if !ok {
+	p.readHeader(f, mpa)
 
-				m := mod.Modification{
-					Index:            key,
-					Type:             "Assigned",
-					MonoIsotopicMass: i.Mass,
-					MassDiff:         i.MassDiff,
-					Variable:         string(i.Variable),
-					AminoAcid:        string(i.AminoAcid),
-					IsobaricMods:     make(map[string]float64),
-				}
+	massDeviation := getMassDeviation(mpa.MsmsRunSummary.SpectrumQuery)
 
-				p.Modifications.Index[key] = m
-			}
-		}
+	// start processing spectra queries
+	var psmlist PepIDList
+	sq := mpa.MsmsRunSummary.SpectrumQuery
+	for _, i := range sq {
+		psms := processSpectrumQuery(i, massDeviation, p.Modifications, p.DecoyTag, p.FileName)
+		psmlist = append(psmlist, psms...)
+	}
 
-		// map terminal modifications from file
-		for _, i := range mpa.MsmsRunSummary.SearchSummary.TerminalModifications {
+	p.PeptideIdentification = psmlist
 
-			key := fmt.Sprintf("%s-term#%.4f", strings.ToUpper(string(i.Terminus)), i.Mass)
+	// p.adjustMassDeviation()
 
-			_, ok := p.Modifications.Index[key]
-			if !ok {
+	if len(psmlist) == 0 {
+		msg.NoPSMFound(errors.New(f), "warning")
+	}
 
-				m := mod.Modification{
-					Index:             key,
-					Type:              "Assigned",
-					MonoIsotopicMass:  i.Mass,
-					MassDiff:          i.MassDiff,
-					Variable:          string(i.Variable),
-					AminoAcid:         fmt.Sprintf("%s-term", i.Terminus),
-					IsProteinTerminus: string(i.ProteinTerminus),
-					Terminus:          strings.ToLower(string(i.Terminus)),
-					IsobaricMods:      make(map[string]float64),
-				}
+	return
+}
 
-				p.Modifications.Index[key] = m
-			}
-		}
+// readHeader collects the file information, models, modifications and search parameters
+func (p *PepXML) readHeader(f string, mpa spc.MsmsPipelineAnalysis) {
+
+	p.FileName = path.Base(f)
+	p.Database = string(mpa.MsmsRunSummary.SearchSummary.SearchDatabase.LocalPath)
+	p.DatabaseEntries = mpa.MsmsRunSummary.SearchSummary.SearchDatabase.SizeInDBEntries
+	p.SpectraFile = fmt.Sprintf("%s%s", mpa.MsmsRunSummary.BaseName, mpa.MsmsRunSummary.RawData)
+
+	var models []spc.DistributionPoint
+	var points []spc.DistributionPoint
 
-		for _, i := range xml.MsmsPipelineAnalysis.MsmsRunSummary.SearchSummary.Parameter {
-			par := &spc.Parameter{
-				Name:  i.Name,
-				Value: i.Value,
+	// search engine results that were not validated have no analysis summary
+	if len(mpa.AnalysisSummary) > 0 {
+		points = mpa.AnalysisSummary[0].PeptideprophetSummary.DistributionPoint
+	}
+
+	// collect distribution points from meta
+	for _, i := range points {
+		var m spc.DistributionPoint
+		m.Fvalue = i.Fvalue
+		m.Obs1Distr = i.Obs1Distr
+		m.Model1PosDistr = i.Model1PosDistr
+		m.Model1NegDistr = i.Model1NegDistr
+		m.Obs2Distr = i.Obs2Distr
+		m.Model2PosDistr = i.Model2PosDistr
+		m.Model2NegDistr = i.Model2NegDistr
+		m.Obs3Distr = i.Obs3Distr
+		m.Model3PosDistr = i.Model3PosDistr
+		m.Model3NegDistr = i.Model3NegDistr
+		m.Obs4Distr = i.Obs4Distr
+		m.Model4PosDistr = i.Model4PosDistr
+		m.Model4NegDistr = i.Model4NegDistr
+		m.Obs5Distr = i.Obs5Distr
+		m.Model5PosDistr = i.Model5PosDistr
+		m.Model5NegDistr = i.Model5NegDistr
+		m.Obs6Distr = i.Obs6Distr
+		m.Model6PosDistr = i.Model6PosDistr
+		m.Model6NegDistr = i.Model6NegDistr
+		m.Obs7Distr = i.Obs7Distr
+		m.Model7PosDistr = i.Model7PosDistr
+		m.Model7NegDistr = i.Model7NegDistr
+		models = append(models, m)
+	}
+
+	p.Modifications.Index = make(map[string]mod.Modification)
+
+	// get the search engine
+	p.SearchEngine = string(mpa.MsmsRunSummary.SearchSummary.SearchEngine)
+	if strings.Contains(string(mpa.MsmsRunSummary.SearchSummary.SearchEngineVersion), "MSFragger") {
+		p.SearchEngine = "MSFragger"
+	}
+
+	// map internal modifications from file
+	for _, i := range mpa.MsmsRunSummary.SearchSummary.AminoAcidModifications {
+
+		key := fmt.Sprintf("%s#%.4f", i.AminoAcid, i.Mass)
+
+		_, ok := p.Modifications.Index[key]
+		if !ok {
+
+			m := mod.Modification{
+				Index:            key,
+				Type:             "Assigned",
+				MonoIsotopicMass: i.Mass,
+				MassDiff:         i.MassDiff,
+				Variable:         string(i.Variable),
+				AminoAcid:        string(i.AminoAcid),
+				IsobaricMods:     make(map[string]float64),
 			}
-			p.SearchParameters = append(p.SearchParameters, *par)
 
+			p.Modifications.Index[key] = m
 		}
+	}
 
-		massDeviation := getMassDeviation(mpa.MsmsRunSummary.SpectrumQuery)
+	// map terminal modifications from file
+	for _, i := range mpa.MsmsRunSummary.SearchSummary.TerminalModifications {
 
-		// start processing spectra queries
-		var psmlist PepIDList
-		sq := mpa.MsmsRunSummary.SpectrumQuery
-		for _, i := range sq {
-			psm := processSpectrumQuery(i, massDeviation, p.Modifications, p.DecoyTag, p.FileName)
-			psmlist = append(psmlist, psm)
-		}
+		key := fmt.Sprintf("%s-term#%.4f", strings.ToUpper(string(i.Terminus)), i.Mass)
 
-		p.PeptideIdentification = psmlist
-		p.Prophet = string(mpa.AnalysisSummary[0].Analysis)
-		p.Models = models
+		_, ok := p.Modifications.Index[key]
+		if !ok {
 
-		// p.adjustMassDeviation()
+			m := mod.Modification{
+				Index:             key,
+				Type:              "Assigned",
+				MonoIsotopicMass:  i.Mass,
+				MassDiff:          i.MassDiff,
+				Variable:          string(i.Variable),
+				AminoAcid:         fmt.Sprintf("%s-term", i.Terminus),
+				IsProteinTerminus: string(i.ProteinTerminus),
+				Terminus:          strings.ToLower(string(i.Terminus)),
+				IsobaricMods:      make(map[string]float64),
+			}
 
-		if len(psmlist) == 0 {
-			msg.NoPSMFound(errors.New(f), "warning")
+			p.Modifications.Index[key] = m
+		}
+	}
+
+	for _, i := range mpa.MsmsRunSummary.SearchSummary.Parameter {
+		par := &spc.Parameter{
+			Name:  i.Name,
+			Value: i.Value,
 		}
+		p.SearchParameters = append(p.SearchParameters, *par)
 
 	}
 
+	// search engine results that were not validated have no analysis summary
+	if len(mpa.AnalysisSummary) > 0 {
+		p.Prophet = string(mpa.AnalysisSummary[0].Analysis)
+	}
+	p.Models = models
+
 	return
 }
 
-// ReadPepXMLInput reads one or more fies and organize the data into PSM list
-func ReadPepXMLInput(xmlFile, decoyTag, temp string, models bool) (PepIDList, string) {
+// ReadStream parses a pepXML file one spectrum query at a time and passes each
+// identification to fn without keeping them. The file is read twice, the first pass
+// calculates the mass deviation used to correct the mass differences
+func (p *PepXML) ReadStream(f string, fn func(PeptideIdentification)) {
 
-	var files = make(map[string]uint8)
-	var fileCheckList []string
-	var pepIdent PepIDList
-	var mods []mod.Modification
-	var params []spc.Parameter
-	var modsIndex = make(map[string]mod.Modification)
-	var searchEngine string
+	var md massDeviation
 
-	if strings.Contains(xmlFile, "pep.xml") || strings.Contains(xmlFile, "pepXML") {
-		fileCheckList = append(fileCheckList, xmlFile)
-		files[xmlFile] = 0
-	} else {
-		//glob := fmt.Sprintf("%s%s*pep.xml", xmlFile, string(filepath.Separator))
-		//list, _ := filepath.Glob(glob)
+	r, e := spc.OpenPepXML(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
 
-		list, err := uti.WalkMatch(xmlFile, "*.pep.xml")
-		if err != nil {
-			msg.NoParametersFound(errors.New("missing pepXML files"), "fatal")
+	for {
+		sq, e := r.Next()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			msg.ReadFile(e, "fatal")
 		}
+		md.add(sq)
+	}
 
-		if len(list) == 0 {
-			msg.NoParametersFound(errors.New("missing pepXML files"), "fatal")
+	r.Close()
+
+	r, e = spc.OpenPepXML(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer r.Close()
+
+	var psms int
+
+	for {
+		sq, e := r.Next()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			msg.ReadFile(e, "fatal")
 		}
 
-		// in case both PeptideProphet and PTMProphet files are rpesent, use
-		// PTMProphet results and ignore peptide prophet.
-		for _, i := range list {
-			base := filepath.Base(i)
-			if strings.Contains(base, ".mod.") {
-				files[i] = 0
-			}
+		// the summaries precede the spectrum queries
+		if psms == 0 {
+			p.readHeader(f, r.MsmsPipelineAnalysis)
 		}
 
-		// if no PptideProphet results are present, then use all PeptideProphet files.
-		if len(files) == 0 {
-			for _, i := range list {
-				base := filepath.Base(i)
-				if !strings.Contains(base, ".mod.") {
-					files[i] = 0
-				}
-			}
+		for _, i := range processSpectrumQuery(sq, md.value(), p.Modifications, p.DecoyTag, p.FileName) {
+			fn(i)
 		}
+		psms++
+	}
 
+	if psms == 0 {
+		msg.NoPSMFound(errors.New(f), "warning")
 	}
 
+	return
+}
+
+// ReadPepXMLInput reads one or more fies and organize the data into PSM list, the lower-ranked
+// hits are only returned with secondary and are never kept in the pepXML binary. The search
+// engine and the analysis that produced the probabilities are returned with the list
+func ReadPepXMLInput(xmlFile, decoyTag, temp string, models, secondary bool) (PepIDList, string, string) {
+
+	var pepIdent PepIDList
+	var mods []mod.Modification
+	var params []spc.Parameter
+	var modsIndex = make(map[string]mod.Modification)
+	var databases = make(map[string]int)
+	var searchEngine string
+	var prophet string
+
+	files := pepXMLFiles(xmlFile)
+
 	for i := range files {
 		var p PepXML
 		p.DecoyTag = decoyTag
@@ -314,7 +361,12 @@ func ReadPepXMLInput(xmlFile, decoyTag, temp string, models bool) (PepIDList, st
 			}
 		}
 
+		if len(p.Database) > 0 {
+			databases[p.Database] = p.DatabaseEntries
+		}
+
 		searchEngine = p.SearchEngine
+		prophet = p.Prophet
 	}
 
 	// create a "fake" global pepXML comprising all data
@@ -323,42 +375,169 @@ func ReadPepXMLInput(xmlFile, decoyTag, temp string, models bool) (PepIDList, st
 	pepXML.SearchParameters = params
 	pepXML.PeptideIdentification = pepIdent
 	pepXML.Modifications.Index = modsIndex
+	pepXML.SearchDatabases = databases
+	pepXML.Prophet = prophet
 
 	// promoting Spectra that matches to both decoys and targets to TRUE hits
 	pepXML.PromoteProteinIDs()
 
+	top, _ := pepIdent.SplitByRank()
+	pepXML.PeptideIdentification = top
+
 	// serialize all pep files
 	sort.Sort(pepXML.PeptideIdentification)
 	pepXML.Serialize()
 
-	return pepIdent, searchEngine
+	if secondary == false {
+		return top, searchEngine, prophet
+	}
+
+	sort.Sort(pepIdent)
+
+	return pepIdent, searchEngine, prophet
 }
 
-func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.Modifications, decoyTag, FileName string) PeptideIdentification {
+// StreamPepXMLInput reads one or more files one identification at a time and passes each
+// identification to fn. Only the file information is kept and serialized, the identifications
+// are not stored in the pepXML binary. The search engine and the analysis are returned
+func StreamPepXMLInput(xmlFile, decoyTag, temp string, models bool, fn func(PeptideIdentification)) (string, string) {
+
+	var params []spc.Parameter
+	var modsIndex = make(map[string]mod.Modification)
+	var databases = make(map[string]int)
+	var searchEngine string
+	var prophet string
+
+	files := pepXMLFiles(xmlFile)
+
+	for i := range files {
+		var p PepXML
+		p.DecoyTag = decoyTag
 
-	var psm PeptideIdentification
-	psm.Modifications.Index = make(map[string]mod.Modification)
-	psm.AlternativeProteinsIndexed = make(map[string]int)
+		// promoting Spectra that matches to both decoys and targets to TRUE hits
+		p.ReadStream(i, func(psm PeptideIdentification) {
+			promoteProteinID(&psm, decoyTag)
+			fn(psm)
+		})
 
-	psm.Index = sq.Index
-	psm.SpectrumFile = FileName
-	psm.Spectrum = string(sq.Spectrum)
-	psm.Scan = sq.StartScan
-	psm.AssumedCharge = sq.AssumedCharge
-	psm.RetentionTime = sq.RetentionTimeSec
-	psm.IonMobility = sq.IonMobility
+		params = p.SearchParameters
 
-	if sq.UncalibratedPrecursorNeutralMass > 0 {
-		psm.PrecursorNeutralMass = sq.PrecursorNeutralMass
-		psm.UncalibratedPrecursorNeutralMass = sq.UncalibratedPrecursorNeutralMass
+		// print models
+		if models == true {
+			if strings.EqualFold(p.Prophet, "interprophet") {
+				logrus.Error("Cannot print models for interprophet files")
+			} else {
+				logrus.Info("Printing models")
+				go p.ReportModels(temp, filepath.Base(i))
+				time.Sleep(time.Second * 3)
+			}
+		}
+
+		for _, k := range p.Modifications.Index {
+			_, ok := modsIndex[k.Index]
+			if !ok {
+				modsIndex[k.Index] = k
+			}
+		}
+
+		if len(p.Database) > 0 {
+			databases[p.Database] = p.DatabaseEntries
+		}
+
+		searchEngine = p.SearchEngine
+		prophet = p.Prophet
+	}
+
+	var pepXML PepXML
+	pepXML.DecoyTag = decoyTag
+	pepXML.SearchParameters = params
+	pepXML.Modifications.Index = modsIndex
+	pepXML.SearchDatabases = databases
+	pepXML.Prophet = prophet
+	pepXML.Serialize()
+
+	return searchEngine, prophet
+}
+
+// pepXMLFiles lists the pepXML files from a file or a directory
+func pepXMLFiles(xmlFile string) map[string]uint8 {
+
+	var files = make(map[string]uint8)
+	var fileCheckList []string
+
+	if strings.Contains(xmlFile, "pep.xml") || strings.Contains(xmlFile, "pepXML") {
+		fileCheckList = append(fileCheckList, xmlFile)
+		files[xmlFile] = 0
 	} else {
-		psm.PrecursorNeutralMass = sq.PrecursorNeutralMass
-		psm.UncalibratedPrecursorNeutralMass = sq.PrecursorNeutralMass
+		//glob := fmt.Sprintf("%s%s*pep.xml", xmlFile, string(filepath.Separator))
+		//list, _ := filepath.Glob(glob)
+
+		list, err := uti.WalkMatch(xmlFile, "*.pep.xml")
+		if err != nil {
+			msg.NoParametersFound(errors.New("missing pepXML files"), "fatal")
+		}
+
+		if len(list) == 0 {
+			msg.NoParametersFound(errors.New("missing pepXML files"), "fatal")
+		}
+
+		// in case both PeptideProphet and PTMProphet files are rpesent, use
+		// PTMProphet results and ignore peptide prophet.
+		for _, i := range list {
+			base := filepath.Base(i)
+			if strings.Contains(base, ".mod.") {
+				files[i] = 0
+			}
+		}
+
+		// if no PptideProphet results are present, then use all PeptideProphet files.
+		if len(files) == 0 {
+			for _, i := range list {
+				base := filepath.Base(i)
+				if !strings.Contains(base, ".mod.") {
+					files[i] = 0
+				}
+			}
+		}
+
 	}
 
-	for _, i := range sq.SearchResult.SearchHit {
+	return files
+}
+
+// processSpectrumQuery converts each search hit from a spectrum query into an identification,
+// the hits keep their rank from the search engine or their order in the search result
+func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.Modifications, decoyTag, FileName string) PepIDList {
+
+	var psms PepIDList
+
+	for n, i := range sq.SearchResult.SearchHit {
+
+		var psm PeptideIdentification
+		psm.Modifications.Index = make(map[string]mod.Modification)
+		psm.AlternativeProteinsIndexed = make(map[string]int)
+
+		psm.Index = sq.Index
+		psm.SpectrumFile = FileName
+		psm.Spectrum = string(sq.Spectrum)
+		psm.Scan = sq.StartScan
+		psm.AssumedCharge = sq.AssumedCharge
+		psm.RetentionTime = sq.RetentionTimeSec
+		psm.IonMobility = sq.IonMobility
+
+		if sq.UncalibratedPrecursorNeutralMass > 0 {
+			psm.PrecursorNeutralMass = sq.PrecursorNeutralMass
+			psm.UncalibratedPrecursorNeutralMass = sq.UncalibratedPrecursorNeutralMass
+		} else {
+			psm.PrecursorNeutralMass = sq.PrecursorNeutralMass
+			psm.UncalibratedPrecursorNeutralMass = sq.PrecursorNeutralMass
+		}
 
 		psm.HitRank = i.HitRank
+		if psm.HitRank == 0 {
+			psm.HitRank = uint8(n + 1)
+		}
+
 		psm.PrevAA = string(i.PrevAA)
 		psm.NextAA = string(i.NextAA)
 		psm.MissedCleavages = i.MissedCleavages
@@ -381,6 +560,7 @@ func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.
 			if string(j.Analysis) == "peptideprophet" {
 
 				psm.Probability = j.PeptideProphetResult.Probability
+				psm.PeptideProphetProbability = j.PeptideProphetResult.Probability
 
 				for _, k := range j.PeptideProphetResult.SearchScoreSummary.Parameter {
 
@@ -400,14 +580,20 @@ func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.
 
 			if string(j.Analysis) == "interprophet" {
 				psm.Probability = j.InterProphetResult.Probability
+				psm.InterProphetProbability = j.InterProphetResult.Probability
 			}
 
 			if string(j.Analysis) == "ptmprophet" {
 				psm.LocalizedPTMSites = make(map[string]int)
 				psm.LocalizedPTMMassDiff = make(map[string]string)
+				psm.LocalizedPTMProbabilities = make(map[string]map[int]float64)
 				for _, k := range j.PTMProphetResult {
 					psm.LocalizedPTMSites[string(k.PTM)] = len(k.ModAminoAcidProbability)
 					psm.LocalizedPTMMassDiff[string(k.PTM)] = string(k.PTMPeptide)
+					psm.LocalizedPTMProbabilities[string(k.PTM)] = make(map[int]float64)
+					for _, l := range k.ModAminoAcidProbability {
+						psm.LocalizedPTMProbabilities[string(k.PTM)][l.Position] = float64(l.Probability)
+					}
 				}
 			}
 		}
@@ -451,9 +637,11 @@ func processSpectrumQuery(sq spc.SpectrumQuery, massDeviation float64, mods mod.
 		psm.Spectrum = fmt.Sprintf("%s#%s", psm.Spectrum, FileName)
 
 		psm.mapModsFromPepXML(i.ModificationInfo, mods)
+
+		psms = append(psms, psm)
 	}
 
-	return psm
+	return psms
 }
 
 // mapModsFromPepXML receives a pepXML struct with modifications and adds them to the given struct
@@ -521,20 +709,38 @@ func (p *PeptideIdentification) mapModsFromPepXML(m spc.ModificationInfo, mods m
 // getMassDeviation calculates the mass deviation for a pepXML file based on the 0 mass difference
 func getMassDeviation(sq []spc.SpectrumQuery) float64 {
 
-	var countZero int
-	var massZero float64
-	var adjustedMass float64
+	var md massDeviation
 
 	for _, i := range sq {
-		for _, j := range i.SearchResult.SearchHit {
-			if math.Abs(j.Massdiff) >= -0.1 && math.Abs(j.Massdiff) <= 0.1 {
-				countZero++
-				massZero += j.Massdiff
-			}
+		md.add(i)
+	}
+
+	return md.value()
+}
+
+// massDeviation accumulates the mass differences close to 0 from the spectrum queries
+type massDeviation struct {
+	countZero int
+	massZero  float64
+}
+
+// add includes the search hits from a spectrum query
+func (m *massDeviation) add(sq spc.SpectrumQuery) {
+
+	for _, j := range sq.SearchResult.SearchHit {
+		if math.Abs(j.Massdiff) >= -0.1 && math.Abs(j.Massdiff) <= 0.1 {
+			m.countZero++
+			m.massZero += j.Massdiff
 		}
 	}
 
-	adjustedMass = massZero / float64(countZero)
+	return
+}
+
+// value returns the average mass difference
+func (m massDeviation) value() float64 {
+
+	adjustedMass := m.massZero / float64(m.countZero)
 
 	return adjustedMass
 }
@@ -544,70 +750,77 @@ func getMassDeviation(sq []spc.SpectrumQuery) float64 {
 func (p *PepXML) PromoteProteinIDs() {
 
 	for i := range p.PeptideIdentification {
+		promoteProteinID(&p.PeptideIdentification[i], p.DecoyTag)
+	}
 
-		var current string
-		var alt string
-		//var altNTT int
-		var list = make(map[string]int)
-		var isUniProt bool
+	return
+}
 
-		if strings.Contains(p.PeptideIdentification[i].Protein, p.DecoyTag) {
+// promoteProteinID replaces a decoy reference protein by one of the target alternative proteins
+func promoteProteinID(psm *PeptideIdentification, decoyTag string) {
 
-			current = p.PeptideIdentification[i].Protein
+	var current string
+	var alt string
+	//var altNTT int
+	var list = make(map[string]int)
+	var isUniProt bool
 
-			for j := range p.PeptideIdentification[i].AlternativeProteins {
+	if strings.Contains(psm.Protein, decoyTag) {
 
-				if strings.Contains(p.PeptideIdentification[i].AlternativeProteins[j], "sp|") {
-					isUniProt = true
-				}
+		current = psm.Protein
 
-				if !strings.HasPrefix(p.PeptideIdentification[i].AlternativeProteins[j], p.DecoyTag) {
-					list[p.PeptideIdentification[i].AlternativeProteins[j]] = j
-				}
+		for j := range psm.AlternativeProteins {
+
+			if strings.Contains(psm.AlternativeProteins[j], "sp|") {
+				isUniProt = true
 			}
 
+			if !strings.HasPrefix(psm.AlternativeProteins[j], decoyTag) {
+				list[psm.AlternativeProteins[j]] = j
+			}
 		}
 
-		if len(list) > 0 {
-
-			// if a Uniprot database is used we give preference to SwissProt proteins
-			if isUniProt == true {
-				for k := range list {
-					if strings.HasPrefix(k, "sp|") {
-						alt = k
-
-						break
-					} else {
-						alt = k
-					}
-				}
-				p.PeptideIdentification[i].Protein = alt
-
-				// remove the replaces protein from the alternative proteins list
-				p.PeptideIdentification[i].AlternativeProteins[list[alt]] = p.PeptideIdentification[i].AlternativeProteins[len(p.PeptideIdentification[i].AlternativeProteins)-1]
-				p.PeptideIdentification[i].AlternativeProteins[len(p.PeptideIdentification[i].AlternativeProteins)-1] = ""
-				p.PeptideIdentification[i].AlternativeProteins = p.PeptideIdentification[i].AlternativeProteins[:len(p.PeptideIdentification[i].AlternativeProteins)-1]
+	}
 
-				// add the replaces current to the list
-				p.PeptideIdentification[i].AlternativeProteins = append(p.PeptideIdentification[i].AlternativeProteins, current)
+	if len(list) > 0 {
 
-			} else {
-				for k := range list {
+		// if a Uniprot database is used we give preference to SwissProt proteins
+		if isUniProt == true {
+			for k := range list {
+				if strings.HasPrefix(k, "sp|") {
 					alt = k
+
 					break
+				} else {
+					alt = k
 				}
-				p.PeptideIdentification[i].Protein = alt
+			}
+			psm.Protein = alt
+
+			// remove the replaces protein from the alternative proteins list
+			psm.AlternativeProteins[list[alt]] = psm.AlternativeProteins[len(psm.AlternativeProteins)-1]
+			psm.AlternativeProteins[len(psm.AlternativeProteins)-1] = ""
+			psm.AlternativeProteins = psm.AlternativeProteins[:len(psm.AlternativeProteins)-1]
 
-				// remove the replaces protein from the alternative proteins list
-				p.PeptideIdentification[i].AlternativeProteins[list[alt]] = p.PeptideIdentification[i].AlternativeProteins[len(p.PeptideIdentification[i].AlternativeProteins)-1]
-				p.PeptideIdentification[i].AlternativeProteins[len(p.PeptideIdentification[i].AlternativeProteins)-1] = ""
-				p.PeptideIdentification[i].AlternativeProteins = p.PeptideIdentification[i].AlternativeProteins[:len(p.PeptideIdentification[i].AlternativeProteins)-1]
+			// add the replaces current to the list
+			psm.AlternativeProteins = append(psm.AlternativeProteins, current)
 
-				// add the replaces current to the list
-				p.PeptideIdentification[i].AlternativeProteins = append(p.PeptideIdentification[i].AlternativeProteins, current)
+		} else {
+			for k := range list {
+				alt = k
+				break
 			}
+			psm.Protein = alt
 
+			// remove the replaces protein from the alternative proteins list
+			psm.AlternativeProteins[list[alt]] = psm.AlternativeProteins[len(psm.AlternativeProteins)-1]
+			psm.AlternativeProteins[len(psm.AlternativeProteins)-1] = ""
+			psm.AlternativeProteins = psm.AlternativeProteins[:len(psm.AlternativeProteins)-1]
+
+			// add the replaces current to the list
+			psm.AlternativeProteins = append(psm.AlternativeProteins, current)
 		}
+
 	}
 
 	return
@@ -852,3 +1065,59 @@ func (p *PepIDList) Restore(level string) {
 
 	return
 }
+
+// SplitByRank separates the top-ranked hits from the lower-ranked hits of the same spectra
+func (p PepIDList) SplitByRank() (PepIDList, PepIDList) {
+
+	var top PepIDList
+	var lower PepIDList
+
+	for _, i := range p {
+		if i.HitRank > 1 {
+			lower = append(lower, i)
+		} else {
+			top = append(top, i)
+		}
+	}
+
+	return top, lower
+}
+
+// CollapseAliases replaces the proteins by the canonical protein of their sequence as
+// defined by the database, alternative proteins that become the same protein are merged
+func (p PepIDList) CollapseAliases(aliases map[string]string) {
+
+	if len(aliases) == 0 {
+		return
+	}
+
+	for i := range p {
+		p[i].CollapseAliases(aliases)
+	}
+
+	return
+}
+
+// CollapseAliases replaces the proteins of a single identification by the canonical
+// protein of their sequence, see PepIDList.CollapseAliases
+func (p *PeptideIdentification) CollapseAliases(aliases map[string]string) {
+
+	if len(aliases) == 0 {
+		return
+	}
+
+	if c, ok := aliases[p.Protein]; ok {
+		p.Protein = c
+	}
+
+	p.AlternativeProteins = collapseNames(p.AlternativeProteins, p.Protein, aliases)
+
+	p.AlternativeProteinsIndexed = make(map[string]int)
+	for _, j := range p.AlternativeProteins {
+		p.AlternativeProteinsIndexed[j]++
+	}
+
+	p.NumberTotalProteins = uint16(len(p.AlternativeProteins) + 1)
+
+	return
+}
@@ -0,0 +1,179 @@
+package id
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"philosopher/lib/mod"
+	"philosopher/lib/spc"
+)
+
+const streamPepXML = `<?xml version="1.0" encoding="UTF-8"?>
+<msms_pipeline_analysis date="2020-01-01T00:00:00" summary_xml="interact.pep.xml">
+<analysis_summary analysis="peptideprophet" time="2020-01-01T00:00:00">
+<peptideprophet_summary version="PeptideProphet">
+<distribution_point fvalue="-1.0" obs_1_distr="1" model_1_pos_distr="0.1" model_1_neg_distr="0.9"/>
+</peptideprophet_summary>
+</analysis_summary>
+<msms_run_summary base_name="run" raw_data=".mzML">
+<sample_enzyme name="trypsin"><specificity cut="KR" no_cut="P" sense="C"/></sample_enzyme>
+<search_summary base_name="run" search_engine="X! Tandem" search_engine_version="MSFragger-3.0">
+<search_database local_path="/data/db.fas" type="AA" size_in_db_entries="4"/>
+<aminoacid_modification aminoacid="M" massdiff="15.9949" mass="147.0354" variable="Y"/>
+<parameter name="precursor_true_tolerance" value="20"/>
+</search_summary>
+<spectrum_query spectrum="run.00010.00010.2" start_scan="10" end_scan="10" precursor_neutral_mass="1000.5" assumed_charge="2" index="1" retention_time_sec="600.0">
+<search_result>
+<search_hit hit_rank="1" peptide="PEPTIDEK" protein="rev_sp|P2|B_HUMAN" num_tot_proteins="2" calc_neutral_pep_mass="1000.5" massdiff="0.02">
+<alternative_protein protein="sp|P1|A_HUMAN"/>
+<analysis_result analysis="peptideprophet"><peptideprophet_result probability="0.95"/></analysis_result>
+</search_hit>
+</search_result>
+</spectrum_query>
+<spectrum_query spectrum="run.00011.00011.3" start_scan="11" end_scan="11" precursor_neutral_mass="1500.7" assumed_charge="3" index="2" retention_time_sec="610.0">
+<search_result>
+<search_hit hit_rank="1" peptide="MAGICK" protein="sp|P1|A_HUMAN" num_tot_proteins="1" calc_neutral_pep_mass="1500.7" massdiff="0.04">
+<modification_info modified_peptide="M[147]AGICK"><mod_aminoacid_mass position="1" mass="147.0354"/></modification_info>
+<analysis_result analysis="peptideprophet"><peptideprophet_result probability="0.5"/></analysis_result>
+</search_hit>
+</search_result>
+</spectrum_query>
+</msms_run_summary>
+</msms_pipeline_analysis>
+`
+
+func TestPepXML_ReadStream(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "pepxml")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "interact.pep.xml")
+	if e := ioutil.WriteFile(f, []byte(streamPepXML), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	var whole PepXML
+	whole.DecoyTag = "rev_"
+	whole.Read(f)
+
+	var stream PepXML
+	var psms PepIDList
+	stream.DecoyTag = "rev_"
+	stream.ReadStream(f, func(p PeptideIdentification) {
+		psms = append(psms, p)
+	})
+
+	if len(psms) != 2 || len(stream.PeptideIdentification) != 0 {
+		t.Fatalf("got %d streamed and %d stored identifications", len(psms), len(stream.PeptideIdentification))
+	}
+
+	if !reflect.DeepEqual(psms, whole.PeptideIdentification) {
+		t.Errorf("streamed identifications differ:\n%+v\n%+v", psms, whole.PeptideIdentification)
+	}
+
+	if stream.SearchEngine != "MSFragger" || stream.Database != "/data/db.fas" || stream.DatabaseEntries != 4 || stream.SpectraFile != "run.mzML" {
+		t.Errorf("header = %s %s %d %s", stream.SearchEngine, stream.Database, stream.DatabaseEntries, stream.SpectraFile)
+	}
+
+	if !reflect.DeepEqual(stream.Modifications, whole.Modifications) || len(stream.Modifications.Index) != 1 {
+		t.Errorf("modifications = %+v", stream.Modifications.Index)
+	}
+
+	if len(stream.SearchParameters) != 1 || len(stream.Models) != 1 || stream.Prophet != "peptideprophet" {
+		t.Errorf("parameters = %v, models = %v, prophet = %s", stream.SearchParameters, stream.Models, stream.Prophet)
+	}
+
+	if psms[1].Massdiff != 0.01 {
+		t.Errorf("mass difference = %f, want 0.01", psms[1].Massdiff)
+	}
+
+	promoteProteinID(&psms[0], "rev_")
+	if psms[0].Protein != "sp|P1|A_HUMAN" || psms[0].AlternativeProteins[0] != "rev_sp|P2|B_HUMAN" {
+		t.Errorf("decoy protein not promoted: %s %v", psms[0].Protein, psms[0].AlternativeProteins)
+	}
+}
+
+func TestProcessSpectrumQuery_LowerRankedHits(t *testing.T) {
+
+	sq := spc.SpectrumQuery{
+		Spectrum:      []byte("run.00010.00010.2"),
+		AssumedCharge: 2,
+		SearchResult: spc.SearchResult{
+			SearchHit: []spc.SearchHit{
+				{HitRank: 1, Peptide: []byte("PEPTIDEK"), Protein: []byte("sp|P1|A_HUMAN")},
+				{HitRank: 2, Peptide: []byte("PEPTLDEK"), Protein: []byte("sp|P2|B_HUMAN")},
+				{Peptide: []byte("MAGICK"), Protein: []byte("rev_sp|P3|C_HUMAN")},
+			},
+		},
+	}
+
+	mods := mod.Modifications{Index: make(map[string]mod.Modification)}
+
+	psms := processSpectrumQuery(sq, 0, mods, "rev_", "interact.pep.xml")
+
+	if len(psms) != 3 {
+		t.Fatalf("got %d identifications, want 3", len(psms))
+	}
+
+	for n, i := range psms {
+		if i.HitRank != uint8(n+1) || i.Spectrum != "run.00010.00010.2#interact.pep.xml" {
+			t.Errorf("hit %d: rank %d, spectrum %s", n, i.HitRank, i.Spectrum)
+		}
+	}
+
+	if psms[1].Peptide != "PEPTLDEK" || psms[1].Protein != "sp|P2|B_HUMAN" {
+		t.Errorf("second hit = %s %s", psms[1].Peptide, psms[1].Protein)
+	}
+
+	top, lower := psms.SplitByRank()
+	if len(top) != 1 || len(lower) != 2 || top[0].Peptide != "PEPTIDEK" {
+		t.Errorf("split = %d top and %d lower-ranked hits", len(top), len(lower))
+	}
+}
+
+func TestProcessSpectrumQuery_AnalysisResults(t *testing.T) {
+
+	sq := spc.SpectrumQuery{
+		Spectrum:      []byte("run.00010.00010.2"),
+		AssumedCharge: 2,
+		SearchResult: spc.SearchResult{
+			SearchHit: []spc.SearchHit{
+				{HitRank: 1, Peptide: []byte("MSTYK"), Protein: []byte("sp|P1|A_HUMAN"),
+					AnalysisResult: []spc.AnalysisResult{
+						{Analysis: []byte("peptideprophet"), PeptideProphetResult: spc.PeptideProphetResult{Probability: 0.95}},
+						{Analysis: []byte("interprophet"), InterProphetResult: spc.InterProphetResult{Probability: 0.99}},
+						{Analysis: []byte("ptmprophet"), PTMProphetResult: []spc.PTMProphetResult{
+							{PTM: []byte("STY:79.9663"), PTMPeptide: []byte("MS(0.75)T(0.25)YK"),
+								ModAminoAcidProbability: []spc.ModAminoAcidProbability{{Position: 2, Probability: 0.75}, {Position: 3, Probability: 0.25}}},
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	mods := mod.Modifications{Index: make(map[string]mod.Modification)}
+
+	psms := processSpectrumQuery(sq, 0, mods, "rev_", "interact.pep.xml")
+
+	if len(psms) != 1 {
+		t.Fatalf("got %d identifications, want 1", len(psms))
+	}
+
+	p := psms[0]
+
+	if p.Probability != 0.99 || p.PeptideProphetProbability != 0.95 || p.InterProphetProbability != 0.99 {
+		t.Errorf("probabilities = %v %v %v", p.Probability, p.PeptideProphetProbability, p.InterProphetProbability)
+	}
+
+	sites := p.LocalizedPTMProbabilities["STY:79.9663"]
+	if p.LocalizedPTMSites["STY:79.9663"] != 2 || len(sites) != 2 || sites[2] != 0.75 || sites[3] != 0.25 {
+		t.Errorf("localization = %v %v", p.LocalizedPTMSites, p.LocalizedPTMProbabilities)
+	}
+}
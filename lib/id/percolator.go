@@ -0,0 +1,262 @@
+package id
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+)
+
+// PercolatorPSM is a PSM validated by Percolator or mokapot
+type PercolatorPSM struct {
+	ID          string
+	Peptide     string
+	QValue      float64
+	PEP         float64
+	Probability float64
+}
+
+// PercolatorResults indexes the validated PSMs by run, scan, charge and peptide sequence
+type PercolatorResults map[string]PercolatorPSM
+
+// column names used by Percolator (pout), crux and mokapot, compared in lower case
+var (
+	percolatorIDColumns      = []string{"psmid", "specid"}
+	percolatorPeptideColumns = []string{"peptide"}
+	percolatorQValueColumns  = []string{"q-value", "percolator q-value", "mokapot q-value"}
+	percolatorPEPColumns     = []string{"posterior_error_prob", "percolator pep", "mokapot pep", "pep"}
+)
+
+// PercolatorProbability converts the Percolator scores into a probability that can be used
+// in place of the PeptideProphet probability. The posterior error probability (PEP) is the
+// probability that the PSM is incorrect, so the probability is 1 - PEP. When the file has no
+// PEP column, 1 - q-value is used instead; it keeps the ranking of the PSMs, which is all
+// the target-decoy FDR filter needs, but it is not a per-PSM probability. The result is
+// limited to the [0, 1] interval
+func PercolatorProbability(qValue, pep float64, hasPEP bool) float64 {
+
+	p := 1 - qValue
+	if hasPEP == true {
+		p = 1 - pep
+	}
+
+	if p < 0 {
+		return 0
+	} else if p > 1 {
+		return 1
+	}
+
+	return p
+}
+
+// ReadPercolatorInput reads one or more comma separated Percolator pout or mokapot PSM files
+func ReadPercolatorInput(files string) PercolatorResults {
+
+	var r = make(PercolatorResults)
+
+	for _, i := range strings.Split(files, ",") {
+
+		f := strings.TrimSpace(i)
+		if len(f) == 0 {
+			continue
+		}
+
+		r.read(f)
+	}
+
+	if len(r) == 0 {
+		msg.Custom(errors.New("No PSMs were found in the Percolator files"), "fatal")
+	}
+
+	return r
+}
+
+// read parses a tab separated file, the columns are found by their names on the header and
+// the extra protein columns of the pout format are ignored
+func (r PercolatorResults) read(f string) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+
+	if !scanner.Scan() {
+		msg.Custom(fmt.Errorf("The Percolator file %s is empty", f), "fatal")
+	}
+
+	header := strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t")
+
+	idCol := percolatorColumn(header, percolatorIDColumns)
+	pepCol := percolatorColumn(header, percolatorPeptideColumns)
+	qCol := percolatorColumn(header, percolatorQValueColumns)
+	pepProbCol := percolatorColumn(header, percolatorPEPColumns)
+
+	if idCol < 0 || pepCol < 0 {
+		msg.Custom(fmt.Errorf("The Percolator file %s has no PSM identifier or peptide column", f), "fatal")
+	}
+
+	if qCol < 0 && pepProbCol < 0 {
+		msg.Custom(fmt.Errorf("The Percolator file %s has no q-value or PEP column, pin files must be validated first", f), "fatal")
+	}
+
+	for scanner.Scan() {
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < len(header) {
+			msg.Custom(fmt.Errorf("Malformed line in %s: %s", f, line), "fatal")
+		}
+
+		var psm PercolatorPSM
+
+		psm.ID = fields[idCol]
+		psm.Peptide = percolatorSequence(fields[pepCol])
+
+		if qCol >= 0 {
+			psm.QValue, e = strconv.ParseFloat(fields[qCol], 64)
+			if e != nil {
+				msg.Custom(fmt.Errorf("Invalid q-value in %s: %s", f, fields[qCol]), "fatal")
+			}
+		}
+
+		if pepProbCol >= 0 {
+			psm.PEP, e = strconv.ParseFloat(fields[pepProbCol], 64)
+			if e != nil {
+				msg.Custom(fmt.Errorf("Invalid PEP in %s: %s", f, fields[pepProbCol]), "fatal")
+			}
+		}
+
+		psm.Probability = PercolatorProbability(psm.QValue, psm.PEP, pepProbCol >= 0)
+
+		run, scan, charge, ok := percolatorSpectrum(psm.ID)
+		if !ok {
+			msg.Custom(fmt.Errorf("Cannot find the run, scan and charge in the PSM identifier %s", psm.ID), "fatal")
+		}
+
+		r[percolatorKey(run, scan, charge, psm.Peptide)] = psm
+	}
+
+	if e := scanner.Err(); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	return
+}
+
+// Rescore replaces the probabilities of the identifications with the imported Percolator
+// results, the identifications without a result are removed and counted
+func (p PepIDList) Rescore(r PercolatorResults) (PepIDList, int) {
+
+	var rescored PepIDList
+	var missing int
+
+	for _, i := range p {
+
+		name := strings.Split(i.Spectrum, "#")[0]
+		parts := strings.Split(name, ".")
+		run := name
+		if len(parts) >= 4 {
+			run = strings.Join(parts[:len(parts)-3], ".")
+		}
+
+		psm, ok := r[percolatorKey(run, i.Scan, i.AssumedCharge, i.Peptide)]
+		if !ok {
+			missing++
+			continue
+		}
+
+		i.Probability = psm.Probability
+		rescored = append(rescored, i)
+	}
+
+	return rescored, missing
+}
+
+// percolatorColumn returns the index of the first header column matching one of the names
+func percolatorColumn(header, names []string) int {
+	for _, n := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), n) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// percolatorSpectrum finds the run, scan and charge of a PSM identifier, either in the
+// TPP style used by MSFragger (run.scan.scan.charge_rank) or in the Comet style
+// (run_scan_charge_rank)
+func percolatorSpectrum(psmID string) (string, int, uint8, bool) {
+
+	name := psmID
+	if i := strings.LastIndex(name, "_"); i > 0 {
+		if _, e := strconv.Atoi(name[i+1:]); e == nil {
+			name = name[:i]
+		}
+	}
+
+	parts := strings.Split(name, ".")
+	if n := len(parts); n >= 4 {
+		scan, e1 := strconv.Atoi(parts[n-3])
+		_, e2 := strconv.Atoi(parts[n-2])
+		charge, e3 := strconv.Atoi(parts[n-1])
+		if e1 == nil && e2 == nil && e3 == nil {
+			return strings.Join(parts[:n-3], "."), scan, uint8(charge), true
+		}
+	}
+
+	parts = strings.Split(psmID, "_")
+	if n := len(parts); n >= 4 {
+		scan, e1 := strconv.Atoi(parts[n-3])
+		charge, e2 := strconv.Atoi(parts[n-2])
+		_, e3 := strconv.Atoi(parts[n-1])
+		if e1 == nil && e2 == nil && e3 == nil {
+			return strings.Join(parts[:n-3], "_"), scan, uint8(charge), true
+		}
+	}
+
+	return "", 0, 0, false
+}
+
+// percolatorSequence removes the flanking residues and the modifications from a peptide,
+// K.PEPT[79.9663]IDEK.R becomes PEPTIDEK
+func percolatorSequence(peptide string) string {
+
+	if len(peptide) > 4 && peptide[1] == '.' && peptide[len(peptide)-2] == '.' {
+		peptide = peptide[2 : len(peptide)-2]
+	}
+
+	var b strings.Builder
+	var depth int
+
+	for _, c := range peptide {
+		switch {
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case depth == 0 && c >= 'A' && c <= 'Z':
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
+
+// percolatorKey is the key shared by the Percolator results and the pepXML identifications
+func percolatorKey(run string, scan int, charge uint8, peptide string) string {
+	return fmt.Sprintf("%s#%d#%d#%s", run, scan, charge, peptide)
+}
@@ -0,0 +1,180 @@
+package id
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const percolatorPout = "PSMId\tscore\tq-value\tposterior_error_prob\tpeptide\tproteinIds\n" +
+	"run.00010.00010.2_1\t3.2\t0.001\t0.02\tK.PEPTIDEK.R\tsp|P1|A_HUMAN\tsp|P2|B_HUMAN\n" +
+	"run.00011.00011.3_1\t-1.5\t0.2\t0.75\t-.M[15.9949]AGICK.-\trev_sp|P1|A_HUMAN\n"
+
+const mokapotPSMs = "SpecId\tLabel\tScanNr\tmokapot score\tmokapot q-value\tPeptide\tProteins\n" +
+	"run_12_2_1\tTrue\t12\t2.1\t0.01\tR.SAMPLER.K\tsp|P3|C_HUMAN\n"
+
+func TestPercolatorProbability(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		q      float64
+		pep    float64
+		hasPEP bool
+		want   float64
+	}{
+		{"pep", 0.001, 0.25, true, 0.75},
+		{"q-value", 0.01, 0, false, 0.99},
+		{"clamped", 0, -0.1, true, 1},
+		{"clamped q-value", 1.2, 0, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PercolatorProbability(tt.q, tt.pep, tt.hasPEP); got != tt.want {
+				t.Errorf("PercolatorProbability() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPercolatorSpectrum(t *testing.T) {
+
+	tests := []struct {
+		id     string
+		run    string
+		scan   int
+		charge uint8
+		ok     bool
+	}{
+		{"run.00010.00010.2_1", "run", 10, 2, true},
+		{"my_run.b1.00010.00010.3", "my_run.b1", 10, 3, true},
+		{"my.run_12_2_1", "my.run", 12, 2, true},
+		{"spectrum", "", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		run, scan, charge, ok := percolatorSpectrum(tt.id)
+		if run != tt.run || scan != tt.scan || charge != tt.charge || ok != tt.ok {
+			t.Errorf("percolatorSpectrum(%s) = %s %d %d %v", tt.id, run, scan, charge, ok)
+		}
+	}
+}
+
+func TestPepIDList_Rescore(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "percolator")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	pout := filepath.Join(dir, "target.pout")
+	if e := ioutil.WriteFile(pout, []byte(percolatorPout), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	mokapot := filepath.Join(dir, "mokapot.psms.txt")
+	if e := ioutil.WriteFile(mokapot, []byte(mokapotPSMs), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	r := ReadPercolatorInput(pout + ", " + mokapot)
+
+	if len(r) != 3 {
+		t.Fatalf("got %d Percolator PSMs, want 3", len(r))
+	}
+
+	psms := PepIDList{
+		{Spectrum: "run.00010.00010.2#interact.pep.xml", Scan: 10, AssumedCharge: 2, Peptide: "PEPTIDEK", Probability: 0.5},
+		{Spectrum: "run.00011.00011.3#interact.pep.xml", Scan: 11, AssumedCharge: 3, Peptide: "MAGICK", Probability: 0.5},
+		{Spectrum: "run.00012.00012.2#interact.pep.xml", Scan: 12, AssumedCharge: 2, Peptide: "SAMPLER", Probability: 0.5},
+		{Spectrum: "run.00013.00013.2#interact.pep.xml", Scan: 13, AssumedCharge: 2, Peptide: "MISSINGK", Probability: 0.5},
+	}
+
+	rescored, missing := psms.Rescore(r)
+
+	if len(rescored) != 3 || missing != 1 {
+		t.Fatalf("got %d rescored and %d missing identifications", len(rescored), missing)
+	}
+
+	for n, want := range []float64{0.98, 0.25, 0.99} {
+		if rescored[n].Probability != want {
+			t.Errorf("%s probability = %v, want %v", rescored[n].Peptide, rescored[n].Probability, want)
+		}
+	}
+}
+
+const unvalidatedPepXML = `<?xml version="1.0" encoding="UTF-8"?>
+<msms_pipeline_analysis date="2020-01-01T00:00:00" summary_xml="run.pepXML">
+<msms_run_summary base_name="run" raw_data=".mzML">
+<search_summary base_name="run" search_engine="X! Tandem" search_engine_version="MSFragger-3.0">
+<search_database local_path="/data/db.fas" type="AA"/>
+</search_summary>
+<spectrum_query spectrum="run.00010.00010.2" start_scan="10" end_scan="10" precursor_neutral_mass="927.45" assumed_charge="2" index="1" retention_time_sec="600.0">
+<search_result>
+<search_hit hit_rank="1" peptide="PEPTIDEK" protein="sp|P1|A_HUMAN" num_tot_proteins="1" calc_neutral_pep_mass="927.45" massdiff="0.0">
+<search_score name="hyperscore" value="30.1"/>
+</search_hit>
+</search_result>
+</spectrum_query>
+<spectrum_query spectrum="run.00011.00011.3" start_scan="11" end_scan="11" precursor_neutral_mass="650.3" assumed_charge="3" index="2" retention_time_sec="610.0">
+<search_result>
+<search_hit hit_rank="1" peptide="MAGICK" protein="rev_sp|P1|A_HUMAN" num_tot_proteins="1" calc_neutral_pep_mass="650.3" massdiff="0.0">
+<search_score name="hyperscore" value="12.4"/>
+</search_hit>
+</search_result>
+</spectrum_query>
+</msms_run_summary>
+</msms_pipeline_analysis>
+`
+
+func TestPepXML_ReadUnvalidated(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "percolator")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "run.pepXML")
+	if e := ioutil.WriteFile(f, []byte(unvalidatedPepXML), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	pout := filepath.Join(dir, "target.pout")
+	if e := ioutil.WriteFile(pout, []byte(percolatorPout), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	var whole PepXML
+	whole.DecoyTag = "rev_"
+	whole.Read(f)
+
+	var stream PepXML
+	var psms PepIDList
+	stream.DecoyTag = "rev_"
+	stream.ReadStream(f, func(p PeptideIdentification) {
+		psms = append(psms, p)
+	})
+
+	if len(whole.PeptideIdentification) != 2 || len(psms) != 2 {
+		t.Fatalf("got %d and %d streamed identifications, want 2", len(whole.PeptideIdentification), len(psms))
+	}
+
+	if whole.Prophet != "" || stream.Database != "/data/db.fas" {
+		t.Errorf("header = %s %s", whole.Prophet, stream.Database)
+	}
+
+	rescored, missing := whole.PeptideIdentification.Rescore(ReadPercolatorInput(pout))
+
+	if len(rescored) != 2 || missing != 0 {
+		t.Fatalf("got %d rescored and %d missing identifications", len(rescored), missing)
+	}
+
+	for n, want := range []float64{0.98, 0.25} {
+		if rescored[n].Probability != want {
+			t.Errorf("%s probability = %v, want %v", rescored[n].Peptide, rescored[n].Probability, want)
+		}
+	}
+}
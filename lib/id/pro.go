@@ -316,3 +316,55 @@ func (p *ProtIDList) Restore() {
 
 	return
 }
+
+// CollapseAliases replaces the proteins by the canonical protein of their sequence as
+// defined by the database, indistinguishable and parent proteins are merged the same way
+func (p *ProtXML) CollapseAliases(aliases map[string]string) {
+
+	if len(aliases) == 0 {
+		return
+	}
+
+	for i := range p.Groups {
+		for j := range p.Groups[i].Proteins {
+
+			pt := &p.Groups[i].Proteins[j]
+
+			if c, ok := aliases[pt.ProteinName]; ok {
+				pt.ProteinName = c
+			}
+
+			pt.IndistinguishableProtein = collapseNames(pt.IndistinguishableProtein, pt.ProteinName, aliases)
+
+			for k := range pt.PeptideIons {
+				pt.PeptideIons[k].PeptideParentProtein = collapseNames(pt.PeptideIons[k].PeptideParentProtein, pt.ProteinName, aliases)
+			}
+		}
+	}
+
+	return
+}
+
+// collapseNames replaces the aliases in a protein list by their canonical proteins,
+// removing the repeated ones and the given main protein
+func collapseNames(names []string, main string, aliases map[string]string) []string {
+
+	var list []string
+	var seen = make(map[string]bool)
+
+	for _, i := range names {
+
+		if c, ok := aliases[i]; ok {
+			i = c
+		}
+
+		if i == main || seen[i] {
+			continue
+		}
+
+		seen[i] = true
+		list = append(list, i)
+	}
+
+	return list
+}
@@ -78,3 +78,46 @@ func TestProtXML_MarkUniquePeptides(t *testing.T) {
 	}
 
 }
+
+func TestProtXML_CollapseAliases(t *testing.T) {
+
+	aliases := map[string]string{
+		"sp|P2|B_HUMAN": "sp|P1|A_HUMAN",
+		"tr|Q1|C_HUMAN": "sp|P1|A_HUMAN",
+	}
+
+	p := ProtXML{Groups: GroupList{{Proteins: ProtIDList{{
+		ProteinName:              "sp|P2|B_HUMAN",
+		IndistinguishableProtein: []string{"sp|P1|A_HUMAN", "tr|Q1|C_HUMAN", "sp|P3|D_HUMAN"},
+		PeptideIons: []PeptideIonIdentification{{
+			PeptideParentProtein: []string{"tr|Q1|C_HUMAN", "sp|P4|E_HUMAN"},
+		}},
+	}}}}}
+
+	p.CollapseAliases(aliases)
+
+	pt := p.Groups[0].Proteins[0]
+
+	if pt.ProteinName != "sp|P1|A_HUMAN" {
+		t.Errorf("ProteinName = %s", pt.ProteinName)
+	}
+
+	if strings.Join(pt.IndistinguishableProtein, ",") != "sp|P3|D_HUMAN" {
+		t.Errorf("IndistinguishableProtein = %v", pt.IndistinguishableProtein)
+	}
+
+	if strings.Join(pt.PeptideIons[0].PeptideParentProtein, ",") != "sp|P4|E_HUMAN" {
+		t.Errorf("PeptideParentProtein = %v", pt.PeptideIons[0].PeptideParentProtein)
+	}
+
+	psm := PepIDList{{
+		Protein:             "rev_sp|P2|B_HUMAN",
+		AlternativeProteins: []string{"sp|P2|B_HUMAN", "tr|Q1|C_HUMAN", "sp|P3|D_HUMAN"},
+	}}
+
+	psm.CollapseAliases(aliases)
+
+	if psm[0].Protein != "rev_sp|P2|B_HUMAN" || strings.Join(psm[0].AlternativeProteins, ",") != "sp|P1|A_HUMAN,sp|P3|D_HUMAN" || psm[0].NumberTotalProteins != 3 || len(psm[0].AlternativeProteinsIndexed) != 2 {
+		t.Errorf("CollapseAliases() = %s %v %d", psm[0].Protein, psm[0].AlternativeProteins, psm[0].NumberTotalProteins)
+	}
+}
@@ -39,6 +39,9 @@ func ProteinInference(psm id.PepIDList) (id.PepIDList, map[string]string, map[st
 	var db dat.Base
 	db.Restore()
 
+	// proteins with identical sequences compete as their canonical entry
+	psm.CollapseAliases(db.AliasMap())
+
 	// build the peptide index
 	for _, i := range psm {
 
@@ -33,6 +33,7 @@ type Data struct {
 	Build          string
 	ProjectName    string
 	SearchEngine   string
+	Prophet        string
 	Msconvert      Msconvert
 	Idconvert      Idconvert
 	Database       Database
@@ -46,6 +47,7 @@ type Data struct {
 	Quantify       Quantify
 	BioQuant       BioQuant
 	Abacus         Abacus
+	Chromatogram   Chromatogram
 	Report         Report
 	TMTIntegrator  TMTIntegrator
 	Index          Index
@@ -69,17 +71,43 @@ type Idconvert struct {
 
 // Database options and parameters
 type Database struct {
-	ID        string `yaml:"id"`
-	Annot     string `yaml:"protein_database"`
-	Enz       string `yaml:"enzyme"`
-	Tag       string `yaml:"decoy_tag"`
-	Add       string `yaml:"add"`
-	Custom    string `yaml:"custom"`
-	TimeStamp string `yaml:"timestamp"`
-	Crap      bool   `yaml:"contam"`
-	Rev       bool   `yaml:"reviewed"`
-	Iso       bool   `yaml:"isoform"`
-	NoD       bool   `yaml:"nodecoys"`
+	ID              string `yaml:"id"`
+	Annot           string `yaml:"protein_database"`
+	Enz             string `yaml:"enzyme"`
+	Tag             string `yaml:"decoy_tag"`
+	Add             string `yaml:"add"`
+	Custom          string `yaml:"custom"`
+	TimeStamp       string `yaml:"timestamp"`
+	DecoyMethod     string `yaml:"decoy_method"`
+	DecoySeed       int64  `yaml:"decoy_seed"`
+	Crap            bool   `yaml:"contam"`
+	ContamFiles     string `yaml:"contam_files"`
+	ContamTag       string `yaml:"contam_tag"`
+	Collapse        bool   `yaml:"collapse"`
+	CollapseIL      bool   `yaml:"collapse_il"`
+	Hash            string `yaml:"hash"`
+	Entries         int    `yaml:"entries"`
+	Rev             bool   `yaml:"reviewed"`
+	Iso             bool   `yaml:"isoform"`
+	NoD             bool   `yaml:"nodecoys"`
+	Stats           bool   `yaml:"stats"`
+	HeaderRegex     string `yaml:"header_regex"`
+	HeaderTemplates string `yaml:"header_templates"`
+	Annotation      string `yaml:"annotation"`
+	Variants        string `yaml:"variants"`
+	Digest          Digest `yaml:"digest"`
+}
+
+// Digest options for the in-silico digestion report
+type Digest struct {
+	Report          bool    `yaml:"report"`
+	MissedCleavages int     `yaml:"missed_cleavages"`
+	MinLength       int     `yaml:"min_length"`
+	MaxLength       int     `yaml:"max_length"`
+	MinMass         float64 `yaml:"min_mass"`
+	MaxMass         float64 `yaml:"max_mass"`
+	ClipNTermM      bool    `yaml:"clip_nterm_m"`
+	Specificity     string  `yaml:"specificity"`
 }
 
 // Comet options and parameters
@@ -321,25 +349,29 @@ type PTMProphet struct {
 
 // Filter options and parameters
 type Filter struct {
-	Pex       string  `yaml:"pepxml"`
-	Pox       string  `yaml:"protxml"`
-	Tag       string  `yaml:"tag"`
-	Mods      string  `yaml:"mods"`
-	PsmFDR    float64 `yaml:"psmFDR"`
-	PepFDR    float64 `yaml:"peptideFDR"`
-	IonFDR    float64 `yaml:"ionFDR"`
-	PtFDR     float64 `yaml:"proteinFDR"`
-	ProtProb  float64 `yaml:"proteinProbability"`
-	PepProb   float64 `yaml:"peptideProbability"`
-	Weight    float64 `yaml:"peptideWeight"`
-	Model     bool    `yaml:"models"`
-	Razor     bool    `yaml:"razor"`
-	Picked    bool    `yaml:"picked"`
-	Seq       bool    `yaml:"sequential"`
-	TwoD      bool    `yaml:"two-dimensional"`
-	Mapmods   bool    `yaml:"mapMods"`
-	Fo        bool
-	Inference bool
+	Pex        string  `yaml:"pepxml"`
+	Pox        string  `yaml:"protxml"`
+	Tag        string  `yaml:"tag"`
+	Mods       string  `yaml:"mods"`
+	PsmFDR     float64 `yaml:"psmFDR"`
+	PepFDR     float64 `yaml:"peptideFDR"`
+	IonFDR     float64 `yaml:"ionFDR"`
+	PtFDR      float64 `yaml:"proteinFDR"`
+	ProtProb   float64 `yaml:"proteinProbability"`
+	PepProb    float64 `yaml:"peptideProbability"`
+	Weight     float64 `yaml:"peptideWeight"`
+	Model      bool    `yaml:"models"`
+	Razor      bool    `yaml:"razor"`
+	Picked     bool    `yaml:"picked"`
+	Seq        bool    `yaml:"sequential"`
+	TwoD       bool    `yaml:"two-dimensional"`
+	Mapmods    bool    `yaml:"mapMods"`
+	DBCheck    string  `yaml:"databaseCheck"`
+	Stream     bool    `yaml:"stream"`
+	Secondary  string  `yaml:"secondaryHits"`
+	Percolator string  `yaml:"percolator"`
+	Fo         bool
+	Inference  bool
 }
 
 // Quantify options and parameters
@@ -353,9 +385,11 @@ type Quantify struct {
 	ChanNorm   string  `yaml:"chanNorm"`
 	Annot      string  `yaml:"annotation"`
 	Level      int     `yaml:"level"`
+	Threads    int     `yaml:"threads"`
 	RTWin      float64 `yaml:"retentionTimeWindow"`
 	PTWin      float64 `yaml:"peakTimeWindow"`
 	Tol        float64 `yaml:"tolerance"`
+	MobTol     float64 `yaml:"mobilityTolerance"`
 	Purity     float64 `yaml:"purity"`
 	MinProb    float64 `yaml:"minprob"`
 	RemoveLow  float64 `yaml:"removeLow"`
@@ -380,6 +414,14 @@ type Abacus struct {
 	Reprint  bool    `yaml:"reprint"`
 }
 
+// Chromatogram options and parameters
+type Chromatogram struct {
+	XIC        []float64
+	Tol        float64
+	Controller int
+	Stored     bool
+}
+
 // BioQuant options and parameters
 type BioQuant struct {
 	UID   string  `yaml:"organismUniProtID"`
@@ -388,9 +430,14 @@ type BioQuant struct {
 
 // Report options and parameters
 type Report struct {
-	Decoys  bool `yaml:"withDecoys"`
-	MSstats bool `yaml:"msstats"`
-	MZID    bool `yaml:"mzID"`
+	Dir     string `yaml:"dir"`
+	Decoys  bool   `yaml:"withDecoys"`
+	MSstats bool   `yaml:"msstats"`
+	MZID    bool   `yaml:"mzID"`
+	MGF     bool   `yaml:"mgf"`
+	MzML    bool   `yaml:"mzml"`
+	PepXML  bool   `yaml:"pepXML"`
+	ProtXML bool   `yaml:"protXML"`
 }
 
 // TMTIntegrator options and parameters
@@ -96,6 +96,26 @@ func ReadingMzMLZlib(e error, t string) {
 	return
 }
 
+// ReadingMzMLBinary call when the mzML binary data arrays cannot be decoded
+func ReadingMzMLBinary(e error, t string) {
+
+	m := fmt.Sprintf("Error trying to decode mzML binary data. %s", e)
+
+	callLogrus(m, t)
+
+	return
+}
+
+// ReadingMzMLIndex call when trying to read the indexedmzML offset index
+func ReadingMzMLIndex(e error, t string) {
+
+	m := fmt.Sprintf("Error trying to read the mzML offset index. %s", e)
+
+	callLogrus(m, t)
+
+	return
+}
+
 // WriteFile call for failed file writing event
 func WriteFile(e error, t string) {
 
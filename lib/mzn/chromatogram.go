@@ -0,0 +1,131 @@
+package mzn
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"os"
+	"strconv"
+
+	"philosopher/lib/fin"
+	"philosopher/lib/msg"
+	"philosopher/lib/psi"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// Chromatogram is an intensity trace over the retention time, in minutes
+type Chromatogram struct {
+	ID        string
+	Time      []float64
+	Intensity []float64
+}
+
+// ForEachChromatogram streams the chromatograms stored in an mzML file and calls fn for each one
+func ForEachChromatogram(f string, fn func(Chromatogram)) {
+
+	xmlFile, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer xmlFile.Close()
+
+	decoder := xml.NewDecoder(bufio.NewReader(xmlFile))
+	decoder.CharsetReader = charset.NewReader
+
+	for {
+
+		t, e := decoder.Token()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		// spectra are much larger than the chromatograms, skip them without decoding
+		if se.Name.Local == "spectrum" {
+			decoder.Skip()
+			continue
+		}
+
+		if se.Name.Local != "chromatogram" {
+			continue
+		}
+
+		var mzChrom psi.Chromatogram
+		if e := decoder.DecodeElement(&mzChrom, &se); e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		fn(processChromatogram(mzChrom))
+	}
+
+	return
+}
+
+// processChromatogram decodes the time and intensity arrays of a chromatogram
+func processChromatogram(mzChrom psi.Chromatogram) Chromatogram {
+
+	var chrom Chromatogram
+
+	chrom.ID = mzChrom.ID
+
+	for _, i := range mzChrom.BinaryDataArrayList.BinaryDataArray {
+
+		var isTime, isIntensity, inSeconds bool
+
+		for _, j := range i.CVParam {
+			switch j.Accession {
+			case "MS:1000595":
+				isTime = true
+				// time arrays are annotated with their unit, UO:0000010 is second
+				inSeconds = j.UnitAccession == "UO:0000010"
+			case "MS:1000515":
+				isIntensity = true
+			}
+		}
+
+		precision, compression := binaryEncoding(i.CVParam)
+
+		values, e := readEncoded(i.Binary.Value, precision, compression)
+		if e != nil {
+			msg.ReadingMzMLBinary(e, "fatal")
+		}
+
+		if isTime {
+			if inSeconds {
+				for k := range values {
+					values[k] = values[k] / 60
+				}
+			}
+			chrom.Time = values
+		} else if isIntensity {
+			chrom.Intensity = values
+		}
+	}
+
+	return chrom
+}
+
+// RawChromatogram reads the chromatography data recorded by one of the additional
+// instrument controllers of a Thermo RAW file, like UV detectors or pump pressure
+func RawChromatogram(f string, controller int) Chromatogram {
+
+	var rd fin.RawData
+	rd.ProcessRaw(f)
+	defer rd.Close()
+
+	chrom := Chromatogram{ID: "controller=" + strconv.Itoa(controller)}
+
+	for _, i := range rd.Chromatography(controller) {
+		chrom.Time = append(chrom.Time, i.Time)
+		chrom.Intensity = append(chrom.Intensity, i.Value)
+	}
+
+	return chrom
+}
@@ -0,0 +1,194 @@
+package mzn
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/psi"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// indexTailSize is the number of bytes read from the end of the file when looking for the index offset
+const indexTailSize int64 = 4096
+
+// IndexedMsData gives random access to the spectra of an indexedmzML file
+// using the offset index stored at the end of the document
+type IndexedMsData struct {
+	FileName string
+	IDs      []string
+	Offsets  map[string]int64
+	file     *os.File
+}
+
+// Open reads the offset index from an indexedmzML file and keeps the file
+// open for the spectrum lookups, Close must be called when done
+func (p *IndexedMsData) Open(f string) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	p.FileName = f
+	p.file = file
+	p.Offsets = make(map[string]int64)
+
+	indexOffset, e := readIndexListOffset(file)
+	if e != nil {
+		msg.ReadingMzMLIndex(e, "fatal")
+	}
+
+	if _, e := file.Seek(indexOffset, io.SeekStart); e != nil {
+		msg.ReadingMzMLIndex(e, "fatal")
+	}
+
+	var indexList psi.IndexList
+
+	decoder := xml.NewDecoder(bufio.NewReader(file))
+	decoder.CharsetReader = charset.NewReader
+
+	if e := decoder.Decode(&indexList); e != nil {
+		msg.ReadingMzMLIndex(e, "fatal")
+	}
+
+	for _, i := range indexList.Index {
+		if i.Name == "spectrum" {
+			for _, j := range i.Offset {
+				p.IDs = append(p.IDs, j.IDRef)
+				p.Offsets[j.IDRef] = j.Value
+			}
+		}
+	}
+
+	if len(p.IDs) == 0 {
+		msg.ReadingMzMLIndex(errors.New("the spectrum index is empty"), "fatal")
+	}
+
+	return
+}
+
+// Close releases the mzML file
+func (p *IndexedMsData) Close() {
+
+	if p.file != nil {
+		p.file.Close()
+		p.file = nil
+	}
+
+	return
+}
+
+// SpectrumByID returns the spectrum referenced by its native ID
+func (p *IndexedMsData) SpectrumByID(id string) (Spectrum, bool) {
+
+	offset, ok := p.Offsets[id]
+	if !ok {
+		return Spectrum{}, false
+	}
+
+	return p.readSpectrumAt(offset)
+}
+
+// SpectrumByScan returns the spectrum with the given scan number. Scan numbers
+// follow the same convention used by MsData, the scan of the native ID or the spectrum
+// index plus one. The subsets written by WriteMzML keep the scan numbers of the source
+// file, so when the spectrum is not at its position the sorted index is searched
+func (p *IndexedMsData) SpectrumByScan(scan int) (Spectrum, bool) {
+
+	if scan < 1 {
+		return Spectrum{}, false
+	}
+
+	if scan <= len(p.IDs) {
+		if spec, ok := p.SpectrumByID(p.IDs[scan-1]); ok && spec.Scan == strconv.Itoa(scan) {
+			return spec, true
+		}
+	}
+
+	i := sort.Search(len(p.IDs), func(i int) bool {
+		spec, ok := p.SpectrumByID(p.IDs[i])
+		n, _ := strconv.Atoi(spec.Scan)
+		return !ok || n >= scan
+	})
+
+	if i < len(p.IDs) {
+		if spec, ok := p.SpectrumByID(p.IDs[i]); ok && spec.Scan == strconv.Itoa(scan) {
+			return spec, true
+		}
+	}
+
+	return Spectrum{}, false
+}
+
+// readSpectrumAt decodes the spectrum element starting at the given byte offset
+func (p *IndexedMsData) readSpectrumAt(offset int64) (Spectrum, bool) {
+
+	if _, e := p.file.Seek(offset, io.SeekStart); e != nil {
+		msg.ReadingMzMLIndex(e, "error")
+		return Spectrum{}, false
+	}
+
+	decoder := xml.NewDecoder(bufio.NewReader(p.file))
+	decoder.CharsetReader = charset.NewReader
+
+	for {
+
+		t, e := decoder.Token()
+		if e != nil {
+			msg.ReadingMzMLIndex(e, "error")
+			return Spectrum{}, false
+		}
+
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if se.Name.Local != "spectrum" {
+			msg.ReadingMzMLIndex(errors.New("the offset does not point to a spectrum"), "error")
+			return Spectrum{}, false
+		}
+
+		var mzSpec psi.Spectrum
+		if e := decoder.DecodeElement(&mzSpec, &se); e != nil {
+			msg.ReadingMzMLIndex(e, "error")
+			return Spectrum{}, false
+		}
+
+		return processSpectrum(mzSpec), true
+	}
+}
+
+// readIndexListOffset retrieves the indexListOffset value from the end of the file
+func readIndexListOffset(file *os.File) (int64, error) {
+
+	info, e := file.Stat()
+	if e != nil {
+		return 0, e
+	}
+
+	size := indexTailSize
+	if info.Size() < size {
+		size = info.Size()
+	}
+
+	tail := make([]byte, size)
+	if _, e := file.ReadAt(tail, info.Size()-size); e != nil && e != io.EOF {
+		return 0, e
+	}
+
+	match := regexp.MustCompile(`<indexListOffset>\s*(\d+)\s*</indexListOffset>`).FindSubmatch(tail)
+	if match == nil {
+		return 0, errors.New("the file is not an indexed mzML")
+	}
+
+	return strconv.ParseInt(string(match[1]), 10, 64)
+}
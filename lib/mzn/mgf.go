@@ -0,0 +1,221 @@
+package mzn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+)
+
+// MGFEntry is a spectrum written to an MGF file together with its identification
+type MGFEntry struct {
+	Title           string
+	Peptide         string
+	ModifiedPeptide string
+	Charge          int
+	PrecursorMz     float64
+	Probability     float64
+	Spectrum        Spectrum
+}
+
+// forEachMGFSpectrum streams an MGF file and calls fn for every spectrum that passes the filter.
+// MGF files only carry fragment spectra, so every entry is reported as an MS2 scan
+func forEachMGFSpectrum(f string, filter SpectrumFilter, fn func(Spectrum)) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer file.Close()
+
+	var spec Spectrum
+	var counter int
+	var inside bool
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if line == "BEGIN IONS" {
+			spec = Spectrum{Level: "2"}
+			inside = true
+			continue
+		}
+
+		if line == "END IONS" {
+
+			counter++
+			if len(spec.Scan) == 0 {
+				spec.Scan = strconv.Itoa(counter)
+			}
+			scan, _ := strconv.Atoi(spec.Scan)
+			spec.Index = strconv.Itoa(scan - 1)
+
+			if filter.Accept(spec) {
+				fn(spec)
+			}
+
+			inside = false
+			continue
+		}
+
+		if inside == false {
+			continue
+		}
+
+		if eq := strings.Index(line, "="); eq > 0 && !isPeakLine(line) {
+			parseMGFField(&spec, strings.ToUpper(line[:eq]), line[eq+1:])
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			mz, e1 := strconv.ParseFloat(fields[0], 64)
+			intensity, e2 := strconv.ParseFloat(fields[1], 64)
+			if e1 != nil || e2 != nil {
+				msg.CastFloatToString(errors.New(line), "error")
+				continue
+			}
+			spec.Mz.DecodedStream = append(spec.Mz.DecodedStream, mz)
+			spec.Intensity.DecodedStream = append(spec.Intensity.DecodedStream, intensity)
+		}
+	}
+
+	if e := scanner.Err(); e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	return
+}
+
+// parseMGFField maps an MGF header field into the spectrum structure
+func parseMGFField(spec *Spectrum, key, value string) {
+
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "TITLE":
+		spec.SpectrumName = value
+		// TPP-style titles carry the scan number: name.scan.scan.charge
+		if len(spec.Scan) == 0 {
+			part := strings.Split(value, ".")
+			if len(part) >= 4 {
+				if _, e := strconv.Atoi(part[len(part)-3]); e == nil {
+					spec.Scan = strings.TrimLeft(part[len(part)-3], "0")
+				}
+			}
+		}
+	case "SCANS":
+		scan := strings.Split(value, "-")[0]
+		if _, e := strconv.Atoi(scan); e == nil {
+			spec.Scan = scan
+		}
+	case "PEPMASS":
+		fields := strings.Fields(value)
+		if len(fields) > 0 {
+			spec.Precursor.SelectedIon, _ = strconv.ParseFloat(fields[0], 64)
+			spec.Precursor.TargetIon = spec.Precursor.SelectedIon
+		}
+		if len(fields) > 1 {
+			spec.Precursor.SelectedIonIntensity, _ = strconv.ParseFloat(fields[1], 64)
+		}
+	case "CHARGE":
+		charge := strings.Fields(value)
+		if len(charge) > 0 {
+			spec.Precursor.ChargeState, _ = strconv.Atoi(strings.Trim(charge[0], "+-"))
+		}
+	case "RTINSECONDS":
+		rt, e := strconv.ParseFloat(strings.Split(value, "-")[0], 64)
+		if e == nil {
+			spec.ScanStartTime = rt / 60
+		}
+	}
+
+	return
+}
+
+// isPeakLine checks if a line starts with a number, MGF peak lines may contain annotations with '='
+func isPeakLine(line string) bool {
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+
+	_, e := strconv.ParseFloat(fields[0], 64)
+
+	return e == nil
+}
+
+// WriteMGF creates an MGF file with the given spectra and their identifications.
+// The spectra are decoded if needed
+func WriteMGF(f string, entries []MGFEntry) {
+
+	file, e := os.Create(f)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+
+	for _, i := range entries {
+
+		spec := i.Spectrum
+		spec.Decode()
+
+		precursorMz := i.PrecursorMz
+		if precursorMz == 0 {
+			precursorMz = spec.Precursor.SelectedIon
+		}
+
+		charge := i.Charge
+		if charge == 0 {
+			charge = spec.Precursor.ChargeState
+		}
+
+		fmt.Fprintln(w, "BEGIN IONS")
+		fmt.Fprintf(w, "TITLE=%s\n", i.Title)
+		fmt.Fprintf(w, "PEPMASS=%.6f\n", precursorMz)
+		if charge > 0 {
+			fmt.Fprintf(w, "CHARGE=%d+\n", charge)
+		}
+		fmt.Fprintf(w, "RTINSECONDS=%.4f\n", spec.ScanStartTime*60)
+		if len(spec.Scan) > 0 {
+			fmt.Fprintf(w, "SCANS=%s\n", spec.Scan)
+		}
+		if len(i.Peptide) > 0 {
+			fmt.Fprintf(w, "SEQ=%s\n", i.Peptide)
+			if len(i.ModifiedPeptide) > 0 {
+				fmt.Fprintf(w, "MODSEQ=%s\n", i.ModifiedPeptide)
+			}
+			fmt.Fprintf(w, "PROBABILITY=%.4f\n", i.Probability)
+		}
+
+		for j := range spec.Mz.DecodedStream {
+			if j < len(spec.Intensity.DecodedStream) {
+				fmt.Fprintf(w, "%.6f %.4f\n", spec.Mz.DecodedStream[j], spec.Intensity.DecodedStream[j])
+			}
+		}
+
+		fmt.Fprintln(w, "END IONS")
+		fmt.Fprintln(w)
+	}
+
+	if e := w.Flush(); e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	return
+}
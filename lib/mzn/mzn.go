@@ -6,16 +6,17 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"math"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 
 	"philosopher/lib/msg"
 
 	"philosopher/lib/psi"
-
-	"github.com/sirupsen/logrus"
 )
 
 // MsData top struct
@@ -82,23 +83,16 @@ func (a Spectra) Len() int           { return len(a) }
 func (a Spectra) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a Spectra) Less(i, j int) bool { return a[i].Index < a[j].Index }
 
-// Read is the main function for parsing mzML data
+// Read is the main function for parsing mzML, mzXML, MGF and Thermo RAW data
 func (p *MsData) Read(f string) {
 
-	var xml psi.IndexedMzML
-	xml.Parse(f)
-
 	p.FileName = f
 
 	var spectra Spectra
-	sl := xml.MzML.Run.SpectrumList
-
-	for _, i := range sl.Spectrum {
-
-		spectrum := processSpectrum(i)
 
+	ForEachSpectrum(f, SpectrumFilter{}, func(spectrum Spectrum) {
 		spectra = append(spectra, spectrum)
-	}
+	})
 
 	if len(spectra) == 0 {
 		msg.NoSpectraFound(errors.New(""), "fatal")
@@ -109,16 +103,29 @@ func (p *MsData) Read(f string) {
 	return
 }
 
+// nativeScanRegexp finds the scan number of the Thermo native IDs
+var nativeScanRegexp = regexp.MustCompile(`(?:^|\s)scan=(\d+)`)
+
+// nativeScan returns the scan number written in the native ID of a spectrum, so that the
+// subsets exported by WriteMzML keep the scan numbers of the source file. The native IDs
+// without a scan number follow the spectrum index plus one
+func nativeScan(id, index string) string {
+
+	if match := nativeScanRegexp.FindStringSubmatch(id); len(match) > 1 {
+		return strings.TrimLeft(match[1], "0")
+	}
+
+	indexInt, _ := strconv.Atoi(index)
+
+	return strconv.Itoa(indexInt + 1)
+}
+
 func processSpectrum(mzSpec psi.Spectrum) Spectrum {
 
 	var spec Spectrum
 
 	spec.Index = string(mzSpec.Index)
-
-	indexStr := string(mzSpec.Index)
-	indexInt, _ := strconv.Atoi(indexStr)
-	indexInt++
-	spec.Scan = string(strconv.Itoa(indexInt))
+	spec.Scan = nativeScan(mzSpec.ID, mzSpec.Index)
 
 	for _, j := range mzSpec.CVParam {
 		if string(j.Accession) == "MS:1000511" {
@@ -245,76 +252,126 @@ func processSpectrum(mzSpec psi.Spectrum) Spectrum {
 	}
 
 	spec.Mz.Stream = mzSpec.BinaryDataArrayList.BinaryDataArray[0].Binary.Value
-	for _, j := range mzSpec.BinaryDataArrayList.BinaryDataArray[0].CVParam {
-		if string(j.Accession) == "MS:1000523" {
-			spec.Mz.Precision = "64"
-		} else if string(j.Accession) == "MS:1000521" {
-			spec.Mz.Precision = "32"
-		}
-
-		if string(j.Accession) == "MS:1000574" {
-			spec.Mz.Compression = "1"
-		} else if string(j.Accession) == "MS:1000576" {
-			spec.Mz.Compression = "0"
-		}
-	}
+	spec.Mz.Precision, spec.Mz.Compression = binaryEncoding(mzSpec.BinaryDataArrayList.BinaryDataArray[0].CVParam)
 
 	spec.Intensity.Stream = mzSpec.BinaryDataArrayList.BinaryDataArray[1].Binary.Value
-	for _, j := range mzSpec.BinaryDataArrayList.BinaryDataArray[1].CVParam {
-		if string(j.Accession) == "MS:1000523" {
-			spec.Intensity.Precision = "64"
-		} else if string(j.Accession) == "MS:1000521" {
-			spec.Intensity.Precision = "32"
-		}
-
-		if string(j.Accession) == "MS:1000574" {
-			spec.Intensity.Compression = "1"
-		} else if string(j.Accession) == "MS:1000576" {
-			spec.Intensity.Compression = "0"
-		}
-	}
+	spec.Intensity.Precision, spec.Intensity.Compression = binaryEncoding(mzSpec.BinaryDataArrayList.BinaryDataArray[1].CVParam)
 
 	if mzSpec.BinaryDataArrayList.Count == 3 {
 		spec.IonMobility.Stream = mzSpec.BinaryDataArrayList.BinaryDataArray[2].Binary.Value
-		for _, j := range mzSpec.BinaryDataArrayList.BinaryDataArray[2].CVParam {
-			if string(j.Accession) == "MS:1000523" {
-				spec.IonMobility.Precision = "64"
-			} else if string(j.Accession) == "MS:1000521" {
-				spec.IonMobility.Precision = "32"
-			}
+		spec.IonMobility.Precision, spec.IonMobility.Compression = binaryEncoding(mzSpec.BinaryDataArrayList.BinaryDataArray[2].CVParam)
+	}
+
+	return spec
+}
 
-			if string(j.Accession) == "MS:1000574" {
-				spec.IonMobility.Compression = "1"
-			} else if string(j.Accession) == "MS:1000576" {
-				spec.IonMobility.Compression = "0"
+// binaryEncoding reads the precision and the compression type from the binary data array cvParams.
+// Compression is 0 for none, 1 for zlib, or the MS-Numpress codec name followed by -zlib when both are used
+func binaryEncoding(cv []psi.CVParam) (string, string) {
+
+	var precision string
+	var compression string
+
+	for _, j := range cv {
+		switch string(j.Accession) {
+		case "MS:1000523":
+			precision = "64"
+		case "MS:1000521":
+			precision = "32"
+		case "MS:1000574":
+			compression = "1"
+		case "MS:1000576":
+			compression = "0"
+		case "MS:1002312":
+			compression = "linear"
+		case "MS:1002313":
+			compression = "pic"
+		case "MS:1002314":
+			compression = "slof"
+		case "MS:1002746":
+			compression = "linear-zlib"
+		case "MS:1002747":
+			compression = "pic-zlib"
+		case "MS:1002748":
+			compression = "slof-zlib"
+		default:
+			// keep track of unknown compression types so they can be reported
+			if strings.Contains(strings.ToLower(j.Name), "compression") {
+				compression = fmt.Sprintf("%s %s", j.Accession, j.Name)
 			}
 		}
 	}
 
-	return spec
+	return precision, compression
 }
 
 // Decode processes the binary data
 func (s *Spectrum) Decode() {
 
+	var e error
+
 	if len(s.Mz.Stream) > 0 && len(s.Intensity.Stream) > 0 {
-		s.Mz.DecodedStream = readEncoded(s.Mz.Stream, s.Mz.Precision, s.Mz.Compression)
+		s.Mz.DecodedStream, e = readEncoded(s.Mz.Stream, s.Mz.Precision, s.Mz.Compression)
+		if e != nil {
+			msg.ReadingMzMLBinary(e, "fatal")
+		}
 		s.Mz.Stream = nil
 
-		s.Intensity.DecodedStream = readEncoded(s.Intensity.Stream, s.Intensity.Precision, s.Intensity.Compression)
+		s.Intensity.DecodedStream, e = readEncoded(s.Intensity.Stream, s.Intensity.Precision, s.Intensity.Compression)
+		if e != nil {
+			msg.ReadingMzMLBinary(e, "fatal")
+		}
 		s.Intensity.Stream = nil
 	}
 
 	if len(s.IonMobility.Stream) > 0 {
-		s.IonMobility.DecodedStream = readEncoded(s.IonMobility.Stream, s.IonMobility.Precision, s.IonMobility.Compression)
+		s.IonMobility.DecodedStream, e = readEncoded(s.IonMobility.Stream, s.IonMobility.Precision, s.IonMobility.Compression)
+		if e != nil {
+			msg.ReadingMzMLBinary(e, "fatal")
+		}
 		s.IonMobility.Stream = nil
 	}
 
 	return
 }
 
+// DecodeAll decodes the binary data of all spectra using a bounded pool of workers.
+// Spectra are decoded independently, so the result is the same as calling Decode on
+// each one of them in order
+func (a Spectra) DecodeAll(threads int) {
+
+	if threads <= 1 {
+		for i := range a {
+			a[i].Decode()
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	var jobs = make(chan int, threads)
+
+	wg.Add(threads)
+	for w := 0; w < threads; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				a[i].Decode()
+			}
+		}()
+	}
+
+	for i := range a {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	return
+}
+
 // readEncoded transforms the binary data into float64 values
-func readEncoded(bin []byte, precision, isCompressed string) []float64 {
+func readEncoded(bin []byte, precision, compression string) ([]float64, error) {
 
 	var stream []uint8
 	var floatArray []float64
@@ -323,21 +380,34 @@ func readEncoded(bin []byte, precision, isCompressed string) []float64 {
 	b64 := base64.NewDecoder(base64.StdEncoding, b)
 
 	var bytestream bytes.Buffer
-	if isCompressed == "1" {
+	if compression == "1" || strings.HasSuffix(compression, "-zlib") {
 		r, e := zlib.NewReader(b64)
 		if e != nil {
-			msg.ReadingMzMLZlib(e, "error")
-			var emptyArray []float64
-			emptyArray = append(emptyArray, 0.0)
-			return emptyArray
+			return nil, e
+		}
+		if _, e := io.Copy(&bytestream, r); e != nil {
+			return nil, e
 		}
-		io.Copy(&bytestream, r)
 	} else {
-		io.Copy(&bytestream, b64)
+		if _, e := io.Copy(&bytestream, b64); e != nil {
+			return nil, e
+		}
 	}
 
 	dataArray := bytestream.Bytes()
 
+	switch strings.TrimSuffix(compression, "-zlib") {
+	case "linear":
+		return decodeNumpressLinear(dataArray)
+	case "pic":
+		return decodeNumpressPic(dataArray)
+	case "slof":
+		return decodeNumpressSlof(dataArray)
+	case "", "0", "1":
+	default:
+		return nil, fmt.Errorf("unsupported binary data compression: %s", compression)
+	}
+
 	var counter int
 
 	if precision == "32" {
@@ -365,8 +435,8 @@ func readEncoded(bin []byte, precision, isCompressed string) []float64 {
 			}
 		}
 	} else {
-		logrus.Trace("Error trying to define mzML binary precision")
+		return nil, fmt.Errorf("unsupported binary data precision: %s", precision)
 	}
 
-	return floatArray
+	return floatArray, nil
 }
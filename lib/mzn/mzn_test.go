@@ -1,6 +1,18 @@
 package mzn_test
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 
 	"philosopher/lib/mzn"
@@ -108,3 +120,377 @@ func TestMS2Spectra(t *testing.T) {
 		t.Errorf("Spectrum number is incorrect, got %f, want %f", spec.Precursor.IsolationWindowLowerOffset, 0.34999999404)
 	}
 }
+
+// writeTestMzML creates a small uncompressed mzML file with alternating MS1 and MS2 spectra
+func writeTestMzML(t *testing.T, n int) string {
+
+	peaks := func(values ...float64) string {
+		var b []byte
+		for _, v := range values {
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+			b = append(b, buf...)
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	var offsets []string
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	sb.WriteString(`<indexedmzML><mzML><run id="test"><spectrumList count="` + strconv.Itoa(n) + `">` + "\n")
+
+	for i := 0; i < n; i++ {
+
+		level := "1"
+		if i%2 == 1 {
+			level = "2"
+		}
+
+		id := fmt.Sprintf("controllerType=0 controllerNumber=1 scan=%d", i+1)
+		offsets = append(offsets, fmt.Sprintf(`<offset idRef="%s">%d</offset>`, id, sb.Len()))
+
+		sb.WriteString(fmt.Sprintf(`<spectrum index="%d" id="%s" defaultArrayLength="2">`, i, id))
+		sb.WriteString(`<cvParam accession="MS:1000511" name="ms level" value="` + level + `"/>`)
+		sb.WriteString(fmt.Sprintf(`<scanList count="1"><scan><cvParam accession="MS:1000016" name="scan start time" value="%f"/></scan></scanList>`, float64(i)*0.5))
+
+		if level == "2" {
+			sb.WriteString(fmt.Sprintf(`<precursorList count="1"><precursor spectrumRef="controllerType=0 controllerNumber=1 scan=%d">`, i))
+			sb.WriteString(`<selectedIonList count="1"><selectedIon><cvParam accession="MS:1000744" name="selected ion m/z" value="500.25"/><cvParam accession="MS:1000041" name="charge state" value="2"/></selectedIon></selectedIonList>`)
+			sb.WriteString(`</precursor></precursorList>`)
+		}
+
+		sb.WriteString(`<binaryDataArrayList count="2">`)
+		sb.WriteString(`<binaryDataArray><cvParam accession="MS:1000523" name="64-bit float"/><cvParam accession="MS:1000576" name="no compression"/><cvParam accession="MS:1000514" name="m/z array"/><binary>` + peaks(100.5, 200.5) + `</binary></binaryDataArray>`)
+		sb.WriteString(`<binaryDataArray><cvParam accession="MS:1000523" name="64-bit float"/><cvParam accession="MS:1000576" name="no compression"/><cvParam accession="MS:1000515" name="intensity array"/><binary>` + peaks(float64(i+1), 10) + `</binary></binaryDataArray>`)
+		sb.WriteString(`</binaryDataArrayList></spectrum>` + "\n")
+	}
+
+	sb.WriteString(`</spectrumList>` + "\n")
+
+	// total ion current chromatogram with the time array in seconds
+	var times, tic []float64
+	for i := 0; i < n; i += 2 {
+		times = append(times, float64(i)*30)
+		tic = append(tic, float64(i+11))
+	}
+	sb.WriteString(`<chromatogramList count="1"><chromatogram index="0" id="TIC" defaultArrayLength="` + strconv.Itoa(len(times)) + `"><binaryDataArrayList count="2">`)
+	sb.WriteString(`<binaryDataArray><cvParam accession="MS:1000523" name="64-bit float"/><cvParam accession="MS:1000576" name="no compression"/><cvParam accession="MS:1000595" name="time array" unitAccession="UO:0000010" unitName="second"/><binary>` + peaks(times...) + `</binary></binaryDataArray>`)
+	sb.WriteString(`<binaryDataArray><cvParam accession="MS:1000523" name="64-bit float"/><cvParam accession="MS:1000576" name="no compression"/><cvParam accession="MS:1000515" name="intensity array"/><binary>` + peaks(tic...) + `</binary></binaryDataArray>`)
+	sb.WriteString(`</binaryDataArrayList></chromatogram></chromatogramList></run></mzML>` + "\n")
+
+	indexOffset := sb.Len()
+	sb.WriteString(`<indexList count="1"><index name="spectrum">` + strings.Join(offsets, "\n") + `</index></indexList>` + "\n")
+	sb.WriteString(fmt.Sprintf("<indexListOffset>%d</indexListOffset>\n</indexedmzML>\n", indexOffset))
+
+	dir, e := ioutil.TempDir("", "mzn")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	f := filepath.Join(dir, "test.mzML")
+	if e := ioutil.WriteFile(f, []byte(sb.String()), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	return f
+}
+
+func TestForEachSpectrum(t *testing.T) {
+
+	f := writeTestMzML(t, 10)
+	defer os.RemoveAll(filepath.Dir(f))
+
+	tests := []struct {
+		name   string
+		filter mzn.SpectrumFilter
+		want   []string
+	}{
+		{"No filter", mzn.SpectrumFilter{}, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}},
+		{"MS1 only", mzn.SpectrumFilter{Levels: []string{"1"}}, []string{"1", "3", "5", "7", "9"}},
+		{"Scan range", mzn.SpectrumFilter{MinScan: 3, MaxScan: 5}, []string{"3", "4", "5"}},
+		{"Retention time", mzn.SpectrumFilter{Levels: []string{"2"}, MinRT: 1.0, MaxRT: 3.0}, []string{"4", "6"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var got []string
+			mzn.ForEachSpectrum(f, tt.filter, func(s mzn.Spectrum) {
+				got = append(got, s.Scan)
+			})
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ForEachSpectrum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForEachSpectrumDecode(t *testing.T) {
+
+	f := writeTestMzML(t, 4)
+	defer os.RemoveAll(filepath.Dir(f))
+
+	var spectra mzn.Spectra
+	mzn.ForEachSpectrum(f, mzn.SpectrumFilter{Levels: []string{"2"}}, func(s mzn.Spectrum) {
+		s.Decode()
+		spectra = append(spectra, s)
+	})
+
+	if len(spectra) != 2 {
+		t.Fatalf("Spectra number is incorrect, got %d, want %d", len(spectra), 2)
+	}
+
+	if spectra[0].Precursor.ParentScan != "1" || spectra[0].Precursor.ChargeState != 2 {
+		t.Errorf("Spectrum precursor is incorrect, got %s, %d", spectra[0].Precursor.ParentScan, spectra[0].Precursor.ChargeState)
+	}
+
+	if spectra[1].Mz.DecodedStream[1] != 200.5 || spectra[1].Intensity.DecodedStream[0] != 4 {
+		t.Errorf("Spectrum peaks are incorrect, got %v, %v", spectra[1].Mz.DecodedStream, spectra[1].Intensity.DecodedStream)
+	}
+}
+
+func TestForEachChromatogram(t *testing.T) {
+
+	f := writeTestMzML(t, 4)
+	defer os.RemoveAll(filepath.Dir(f))
+
+	var chroms []mzn.Chromatogram
+	mzn.ForEachChromatogram(f, func(c mzn.Chromatogram) {
+		chroms = append(chroms, c)
+	})
+
+	if len(chroms) != 1 || chroms[0].ID != "TIC" {
+		t.Fatalf("Chromatograms are incorrect, got %v", chroms)
+	}
+
+	// times are converted from seconds to minutes, matching the scan start times
+	if !reflect.DeepEqual(chroms[0].Time, []float64{0, 1}) || !reflect.DeepEqual(chroms[0].Intensity, []float64{11, 13}) {
+		t.Errorf("Chromatogram arrays are incorrect, got %v, %v", chroms[0].Time, chroms[0].Intensity)
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+
+	f := writeTestMzML(t, 20)
+	defer os.RemoveAll(filepath.Dir(f))
+
+	var serial, parallel mzn.Spectra
+	mzn.ForEachSpectrum(f, mzn.SpectrumFilter{}, func(s mzn.Spectrum) {
+		serial = append(serial, s)
+		parallel = append(parallel, s)
+	})
+
+	serial.DecodeAll(1)
+	parallel.DecodeAll(4)
+
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Errorf("Parallel decoding differs from the serial decoding")
+	}
+
+	if len(parallel[19].Mz.Stream) > 0 || parallel[19].Intensity.DecodedStream[0] != 20 {
+		t.Errorf("Spectrum was not decoded, got %v", parallel[19].Intensity.DecodedStream)
+	}
+}
+
+func TestIndexedMsData(t *testing.T) {
+
+	f := writeTestMzML(t, 6)
+	defer os.RemoveAll(filepath.Dir(f))
+
+	var idx mzn.IndexedMsData
+	idx.Open(f)
+	defer idx.Close()
+
+	if len(idx.IDs) != 6 {
+		t.Fatalf("Index size is incorrect, got %d, want %d", len(idx.IDs), 6)
+	}
+
+	spec, ok := idx.SpectrumByScan(4)
+	if !ok {
+		t.Fatalf("Spectrum not found by scan")
+	}
+
+	spec.Decode()
+	if spec.Scan != "4" || spec.Level != "2" || spec.Intensity.DecodedStream[0] != 4 {
+		t.Errorf("Spectrum is incorrect, got scan %s, level %s, intensities %v", spec.Scan, spec.Level, spec.Intensity.DecodedStream)
+	}
+
+	spec, ok = idx.SpectrumByID("controllerType=0 controllerNumber=1 scan=1")
+	if !ok || spec.Scan != "1" || spec.Level != "1" {
+		t.Errorf("Spectrum not found by native ID, got %s", spec.Scan)
+	}
+
+	if _, ok := idx.SpectrumByScan(7); ok {
+		t.Errorf("Spectrum out of range should not be found")
+	}
+}
+
+func TestWriteMzML(t *testing.T) {
+
+	f := writeTestMzML(t, 10)
+	defer os.RemoveAll(filepath.Dir(f))
+
+	output := filepath.Join(filepath.Dir(f), "subset.mzML")
+
+	n := mzn.WriteMzML(f, output, mzn.SpectrumFilter{Levels: []string{"2"}}, func(s mzn.Spectrum) bool {
+		return s.Scan == "4" || s.Scan == "8"
+	})
+
+	if n != 2 {
+		t.Fatalf("Written spectra number is incorrect, got %d, want %d", n, 2)
+	}
+
+	var idx mzn.IndexedMsData
+	idx.Open(output)
+	defer idx.Close()
+
+	if len(idx.IDs) != 2 {
+		t.Fatalf("Index size is incorrect, got %d, want %d", len(idx.IDs), 2)
+	}
+
+	// the index is renumbered, the native ID keeps the original scan number
+	spec, ok := idx.SpectrumByID("controllerType=0 controllerNumber=1 scan=8")
+	if !ok {
+		t.Fatalf("Spectrum not found by native ID")
+	}
+
+	spec.Decode()
+	if spec.Index != "1" || spec.Scan != "8" || spec.Level != "2" || spec.Precursor.ChargeState != 2 || spec.Intensity.DecodedStream[0] != 8 {
+		t.Errorf("Spectrum is incorrect, got index %s, scan %s, level %s, charge %d, intensities %v", spec.Index, spec.Scan, spec.Level, spec.Precursor.ChargeState, spec.Intensity.DecodedStream)
+	}
+
+	if spec, ok := idx.SpectrumByScan(4); !ok || spec.Index != "0" {
+		t.Errorf("Spectrum not found by its original scan number")
+	}
+
+	content, e := ioutil.ReadFile(output)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !bytes.Contains(content, []byte(`<run id="test">`)) {
+		t.Errorf("Source header is missing from the new file")
+	}
+
+	end := bytes.Index(content, []byte("<fileChecksum>")) + len("<fileChecksum>")
+	checksum := fmt.Sprintf("%x", sha1.Sum(content[:end]))
+	if !bytes.HasPrefix(content[end:], []byte(checksum)) {
+		t.Errorf("File checksum is incorrect, want %s", checksum)
+	}
+}
+
+func TestForEachSpectrumMzXML(t *testing.T) {
+
+	peaks := func(values ...float32) string {
+		var b []byte
+		for _, v := range values {
+			buf := make([]byte, 4)
+			binary.BigEndian.PutUint32(buf, math.Float32bits(v))
+			b = append(b, buf...)
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	content := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<mzXML><msRun scanCount="3">
+<scan num="1" msLevel="1" peaksCount="2" retentionTime="PT60.0S">
+<peaks precision="32" byteOrder="network" pairOrder="m/z-int">` + peaks(400.5, 1000, 500.25, 2000) + `</peaks>
+<scan num="2" msLevel="2" peaksCount="1" retentionTime="PT61.2S">
+<precursorMz precursorIntensity="2000" precursorCharge="2" windowWideness="1.4">500.25</precursorMz>
+<peaks precision="32" byteOrder="network" pairOrder="m/z-int">` + peaks(126.1277, 300) + `</peaks>
+</scan>
+</scan>
+<scan num="3" msLevel="1" peaksCount="0" retentionTime="PT1M2.4S">
+<peaks precision="32" byteOrder="network" pairOrder="m/z-int"></peaks>
+</scan>
+</msRun></mzXML>
+`
+
+	dir, e := ioutil.TempDir("", "mzn")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "test.mzXML")
+	if e := ioutil.WriteFile(f, []byte(content), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	var msd mzn.MsData
+	msd.Read(f)
+
+	if len(msd.Spectra) != 3 {
+		t.Fatalf("Spectra number is incorrect, got %d, want %d", len(msd.Spectra), 3)
+	}
+
+	ms1 := msd.Spectra[0]
+	if ms1.Scan != "1" || ms1.Level != "1" || ms1.ScanStartTime != 1 {
+		t.Errorf("MS1 spectrum is incorrect, got scan %s, level %s, time %f", ms1.Scan, ms1.Level, ms1.ScanStartTime)
+	}
+
+	if !reflect.DeepEqual(ms1.Mz.DecodedStream, []float64{400.5, 500.25}) || !reflect.DeepEqual(ms1.Intensity.DecodedStream, []float64{1000, 2000}) {
+		t.Errorf("MS1 peaks are incorrect, got %v, %v", ms1.Mz.DecodedStream, ms1.Intensity.DecodedStream)
+	}
+
+	ms2 := msd.Spectra[1]
+	if ms2.Precursor.ParentScan != "1" || ms2.Precursor.ChargeState != 2 || ms2.Precursor.SelectedIon != 500.25 || ms2.Precursor.IsolationWindowLowerOffset != 0.7 {
+		t.Errorf("MS2 precursor is incorrect, got %+v", ms2.Precursor)
+	}
+
+	if msd.Spectra[2].ScanStartTime != 1.04 {
+		t.Errorf("Retention time is incorrect, got %f, want %f", msd.Spectra[2].ScanStartTime, 1.04)
+	}
+}
+
+func TestMGFReadWrite(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "mzn")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	var spec mzn.Spectrum
+	spec.Scan = "3130"
+	spec.Level = "2"
+	spec.ScanStartTime = 2.5
+	spec.Mz.DecodedStream = []float64{110.071472, 175.118952}
+	spec.Intensity.DecodedStream = []float64{3716.5, 1200}
+
+	entries := []mzn.MGFEntry{
+		{
+			Title:       "sample.03130.03130.2",
+			Peptide:     "PEPTIDEK",
+			Charge:      2,
+			PrecursorMz: 464.728,
+			Probability: 0.9985,
+			Spectrum:    spec,
+		},
+	}
+
+	f := filepath.Join(dir, "test.mgf")
+	mzn.WriteMGF(f, entries)
+
+	var msd mzn.MsData
+	msd.Read(f)
+
+	if len(msd.Spectra) != 1 {
+		t.Fatalf("Spectra number is incorrect, got %d, want %d", len(msd.Spectra), 1)
+	}
+
+	got := msd.Spectra[0]
+
+	if got.Scan != "3130" || got.Level != "2" || got.SpectrumName != "sample.03130.03130.2" {
+		t.Errorf("Spectrum is incorrect, got scan %s, level %s, name %s", got.Scan, got.Level, got.SpectrumName)
+	}
+
+	if got.Precursor.ChargeState != 2 || got.Precursor.SelectedIon != 464.728 || got.ScanStartTime != 2.5 {
+		t.Errorf("Spectrum precursor is incorrect, got %+v, time %f", got.Precursor, got.ScanStartTime)
+	}
+
+	if !reflect.DeepEqual(got.Mz.DecodedStream, spec.Mz.DecodedStream) || !reflect.DeepEqual(got.Intensity.DecodedStream, spec.Intensity.DecodedStream) {
+		t.Errorf("Spectrum peaks are incorrect, got %v, %v", got.Mz.DecodedStream, got.Intensity.DecodedStream)
+	}
+}
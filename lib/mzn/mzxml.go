@@ -0,0 +1,183 @@
+package mzn
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/psi"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// forEachMzXMLScan streams an mzXML file and calls fn for every scan that passes the filter.
+// Older mzXML versions nest the fragment scans inside their precursor scan, those are
+// flattened and reported right after their parent
+func forEachMzXMLScan(f string, filter SpectrumFilter, fn func(Spectrum)) {
+
+	xmlFile, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer xmlFile.Close()
+
+	decoder := xml.NewDecoder(bufio.NewReader(xmlFile))
+	decoder.CharsetReader = charset.NewReader
+
+	for {
+
+		t, e := decoder.Token()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		se, ok := t.(xml.StartElement)
+		if !ok || se.Name.Local != "scan" {
+			continue
+		}
+
+		var scan psi.MzXMLScan
+		if e := decoder.DecodeElement(&scan, &se); e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		// nested scans always have a higher number than their parent
+		if filter.MaxScan > 0 && scan.Num > filter.MaxScan {
+			break
+		}
+
+		walkMzXMLScan(scan, 0, filter, fn)
+	}
+
+	return
+}
+
+// walkMzXMLScan processes a scan and its nested scans
+func walkMzXMLScan(scan psi.MzXMLScan, parent int, filter SpectrumFilter, fn func(Spectrum)) {
+
+	spectrum := processMzXMLScan(scan, parent)
+
+	if filter.Accept(spectrum) {
+		spectrum.Mz.DecodedStream, spectrum.Intensity.DecodedStream = readMzXMLPeaks(scan.Peaks)
+		fn(spectrum)
+	}
+
+	for _, i := range scan.Scan {
+		walkMzXMLScan(i, scan.Num, filter, fn)
+	}
+
+	return
+}
+
+// processMzXMLScan maps an mzXML scan to a Spectrum, the peaks are not decoded
+func processMzXMLScan(scan psi.MzXMLScan, parent int) Spectrum {
+
+	var spec Spectrum
+
+	spec.Index = strconv.Itoa(scan.Num - 1)
+	spec.Scan = strconv.Itoa(scan.Num)
+	spec.Level = scan.MsLevel
+	spec.ScanStartTime = parseRetentionTime(scan.RetentionTime)
+
+	spec.Precursor = Precursor{}
+	if len(scan.PrecursorMz) > 0 {
+
+		prec := scan.PrecursorMz[0]
+
+		parentScan := prec.PrecursorScanNum
+		if parentScan == 0 {
+			parentScan = parent
+		}
+
+		if parentScan > 0 {
+			spec.Precursor.ParentScan = strconv.Itoa(parentScan)
+			spec.Precursor.ParentIndex = strconv.Itoa(parentScan - 1)
+		}
+
+		spec.Precursor.ChargeState = prec.PrecursorCharge
+		spec.Precursor.SelectedIon = prec.Value
+		spec.Precursor.SelectedIonIntensity = prec.PrecursorIntensity
+		spec.Precursor.TargetIon = prec.Value
+
+		if prec.WindowWideness > 0 {
+			spec.Precursor.IsolationWindowLowerOffset = prec.WindowWideness / 2
+			spec.Precursor.IsolationWindowUpperOffset = prec.WindowWideness / 2
+		}
+	}
+
+	return spec
+}
+
+// readMzXMLPeaks decodes the interleaved m/z and intensity pairs
+func readMzXMLPeaks(peaks psi.Peaks) ([]float64, []float64) {
+
+	var mz []float64
+	var intensity []float64
+
+	b64 := base64.NewDecoder(base64.StdEncoding, bytes.NewReader(bytes.TrimSpace(peaks.Value)))
+
+	var bytestream bytes.Buffer
+	if peaks.CompressionType == "zlib" {
+		r, e := zlib.NewReader(b64)
+		if e != nil {
+			msg.ReadingMzMLZlib(e, "error")
+			return mz, intensity
+		}
+		io.Copy(&bytestream, r)
+	} else {
+		io.Copy(&bytestream, b64)
+	}
+
+	dataArray := bytestream.Bytes()
+
+	// mzXML stores the peaks in network byte order unless stated otherwise
+	var order binary.ByteOrder = binary.BigEndian
+	if peaks.ByteOrder == "little" {
+		order = binary.LittleEndian
+	}
+
+	size := 4
+	if peaks.Precision == "64" {
+		size = 8
+	}
+
+	for i := 0; i+(2*size) <= len(dataArray); i += 2 * size {
+		if size == 8 {
+			mz = append(mz, math.Float64frombits(order.Uint64(dataArray[i:])))
+			intensity = append(intensity, math.Float64frombits(order.Uint64(dataArray[i+size:])))
+		} else {
+			mz = append(mz, float64(math.Float32frombits(order.Uint32(dataArray[i:]))))
+			intensity = append(intensity, float64(math.Float32frombits(order.Uint32(dataArray[i+size:]))))
+		}
+	}
+
+	return mz, intensity
+}
+
+// parseRetentionTime converts an xs:duration value like PT123.45S to minutes
+func parseRetentionTime(rt string) float64 {
+
+	if len(rt) == 0 {
+		return 0
+	}
+
+	d, e := time.ParseDuration(strings.ToLower(strings.TrimPrefix(rt, "PT")))
+	if e != nil {
+		msg.CastFloatToString(e, "error")
+		return 0
+	}
+
+	return d.Minutes()
+}
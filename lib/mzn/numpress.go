@@ -0,0 +1,183 @@
+package mzn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// The MS-Numpress codecs, as described by Teleman et al. (2014) and implemented by the
+// reference library used by ProteoWizard. All integers are encoded using a variable
+// number of half-bytes, the first half-byte specifies how many leading half-bytes
+// of the 32-bit value are zeros (0-8) or ones (9-15)
+
+var errCorruptNumpress = errors.New("corrupt MS-Numpress data")
+
+// decodeNumpressFixedPoint reads the scaling factor stored in the first 8 bytes
+func decodeNumpressFixedPoint(data []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(data[:8]))
+}
+
+// numpressReader walks the half-bytes of an encoded stream
+type numpressReader struct {
+	data []byte
+	pos  int
+	half int
+}
+
+// done reports if the stream is exhausted, a trailing zero half-byte is padding
+func (r *numpressReader) done() bool {
+
+	if r.pos >= len(r.data) {
+		return true
+	}
+
+	if r.pos == len(r.data)-1 && r.half == 1 && (r.data[r.pos]&0xf) == 0 {
+		return true
+	}
+
+	return false
+}
+
+// nextHalfByte returns the next 4 bits of the stream
+func (r *numpressReader) nextHalfByte() (uint32, error) {
+
+	if r.pos >= len(r.data) {
+		return 0, errCorruptNumpress
+	}
+
+	var hb uint32
+	if r.half == 0 {
+		hb = uint32(r.data[r.pos] >> 4)
+	} else {
+		hb = uint32(r.data[r.pos] & 0xf)
+		r.pos++
+	}
+
+	r.half = 1 - r.half
+
+	return hb, nil
+}
+
+// decodeInt reads one half-byte encoded integer
+func (r *numpressReader) decodeInt() (uint32, error) {
+
+	head, e := r.nextHalfByte()
+	if e != nil {
+		return 0, e
+	}
+
+	var res uint32
+	var n uint32
+
+	if head <= 8 {
+		n = head
+	} else {
+		// n leading half-bytes filled with ones
+		n = head - 8
+		for i := uint32(0); i < n; i++ {
+			res |= 0xf0000000 >> (4 * i)
+		}
+	}
+
+	for i := n; i < 8; i++ {
+		hb, e := r.nextHalfByte()
+		if e != nil {
+			return 0, e
+		}
+		res |= hb << ((i - n) * 4)
+	}
+
+	return res, nil
+}
+
+// decodeNumpressLinear decodes values compressed with the linear prediction codec
+func decodeNumpressLinear(data []byte) ([]float64, error) {
+
+	var result []float64
+
+	if len(data) == 8 {
+		return result, nil
+	}
+
+	if len(data) < 12 {
+		return nil, errCorruptNumpress
+	}
+
+	fixedPoint := decodeNumpressFixedPoint(data)
+
+	var ints [3]int64
+	ints[1] = int64(binary.LittleEndian.Uint32(data[8:12]))
+	result = append(result, float64(ints[1])/fixedPoint)
+
+	if len(data) == 12 {
+		return result, nil
+	}
+
+	if len(data) < 16 {
+		return nil, errCorruptNumpress
+	}
+
+	ints[2] = int64(binary.LittleEndian.Uint32(data[12:16]))
+	result = append(result, float64(ints[2])/fixedPoint)
+
+	r := numpressReader{data: data, pos: 16}
+
+	for !r.done() {
+
+		ints[0] = ints[1]
+		ints[1] = ints[2]
+
+		buff, e := r.decodeInt()
+		if e != nil {
+			return nil, e
+		}
+
+		extrapol := ints[1] + (ints[1] - ints[0])
+		y := extrapol + int64(int32(buff))
+
+		result = append(result, float64(y)/fixedPoint)
+		ints[2] = y
+	}
+
+	return result, nil
+}
+
+// decodeNumpressPic decodes values compressed with the positive integer codec
+func decodeNumpressPic(data []byte) ([]float64, error) {
+
+	var result []float64
+
+	r := numpressReader{data: data}
+
+	for !r.done() {
+
+		x, e := r.decodeInt()
+		if e != nil {
+			return nil, e
+		}
+
+		result = append(result, float64(x))
+	}
+
+	return result, nil
+}
+
+// decodeNumpressSlof decodes values compressed with the short logged float codec
+func decodeNumpressSlof(data []byte) ([]float64, error) {
+
+	var result []float64
+
+	if len(data) < 8 || (len(data)-8)%2 != 0 {
+		return nil, errCorruptNumpress
+	}
+
+	fixedPoint := decodeNumpressFixedPoint(data)
+
+	for i := 8; i < len(data); i += 2 {
+		x := binary.LittleEndian.Uint16(data[i:])
+		result = append(result, math.Exp(float64(x)/fixedPoint)-1)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package mzn
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func numpressFixedPoint(f float64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(f))
+	return b
+}
+
+func TestDecodeNumpressLinear(t *testing.T) {
+
+	// fixed point 100, first values 100 and 200, then a difference of 50 from the extrapolated 300
+	data := numpressFixedPoint(100)
+	data = append(data, 100, 0, 0, 0, 200, 0, 0, 0, 0x62, 0x30)
+
+	got, e := decodeNumpressLinear(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := []float64{1, 2, 3.5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeNumpressLinear() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeNumpressPic(t *testing.T) {
+
+	// half-bytes 8 | 7 5 | 5 C 2 1 | padding
+	data := []byte{0x87, 0x55, 0xC2, 0x10}
+
+	got, e := decodeNumpressPic(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := []float64{0, 5, 300}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeNumpressPic() = %v, want %v", got, want)
+	}
+
+	if _, e := decodeNumpressPic([]byte{0x60}); e == nil {
+		t.Errorf("decodeNumpressPic() should fail on truncated data")
+	}
+}
+
+func TestDecodeNumpressSlof(t *testing.T) {
+
+	data := numpressFixedPoint(1000)
+	data = append(data, 0, 0, 0xE8, 0x03)
+
+	got, e := decodeNumpressSlof(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if got[0] != 0 || math.Abs(got[1]-(math.E-1)) > 1e-9 {
+		t.Errorf("decodeNumpressSlof() = %v, want [0 %f]", got, math.E-1)
+	}
+}
+
+func TestReadEncodedUnsupportedCompression(t *testing.T) {
+
+	bin := []byte(base64.StdEncoding.EncodeToString([]byte{0, 0, 0, 0}))
+
+	if _, e := readEncoded(bin, "32", "MS:1003088 truncation, delta prediction and zlib compression"); e == nil {
+		t.Errorf("readEncoded() should fail on unsupported compression")
+	}
+
+	if _, e := readEncoded(bin, "", "0"); e == nil {
+		t.Errorf("readEncoded() should fail on unknown precision")
+	}
+}
+
+func TestReadEncodedZlibError(t *testing.T) {
+
+	// the data is declared as zlib compressed but has no zlib header
+	bin := []byte(base64.StdEncoding.EncodeToString([]byte{0, 0, 128, 63}))
+
+	if got, e := readEncoded(bin, "32", "1"); e == nil || got != nil {
+		t.Errorf("readEncoded() = %v, %v, want a zlib error", got, e)
+	}
+
+	// a zlib header followed by a truncated stream
+	bin = []byte(base64.StdEncoding.EncodeToString([]byte{0x78, 0x9c, 0x63, 0x60}))
+
+	if got, e := readEncoded(bin, "32", "1"); e == nil || got != nil {
+		t.Errorf("readEncoded() = %v, %v, want a zlib error", got, e)
+	}
+}
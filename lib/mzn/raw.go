@@ -0,0 +1,95 @@
+package mzn
+
+import (
+	"strconv"
+
+	"philosopher/lib/fin"
+)
+
+// forEachRawScan reads a Thermo RAW file with the native reader and calls fn for every
+// scan that passes the filter
+func forEachRawScan(f string, filter SpectrumFilter, fn func(Spectrum)) {
+
+	var rd fin.RawData
+	rd.ProcessRaw(f)
+	defer rd.Close()
+
+	forEachRawDataScan(&rd, filter, fn)
+
+	return
+}
+
+// forEachRawDataScan visits the scans of an open RAW file. The scan headers are cheap to
+// read, so all of them are visited to keep track of the parent scans, the peaks are only
+// read for accepted scans
+func forEachRawDataScan(rd *fin.RawData, filter SpectrumFilter, fn func(Spectrum)) {
+
+	// last seen scan number for each MS level, used to reference the precursor scans
+	var parents = make(map[uint8]int)
+
+	for sn := 1; sn <= rd.NScans(); sn++ {
+
+		if filter.MaxScan > 0 && sn > filter.MaxScan {
+			break
+		}
+
+		scan := rd.Scan(sn)
+
+		parent := parents[scan.MSLevel-1]
+		parents[scan.MSLevel] = sn
+
+		if !filter.acceptScan(sn) {
+			continue
+		}
+
+		spectrum := processRawScan(sn, parent, scan)
+
+		if !filter.Accept(spectrum) {
+			continue
+		}
+
+		peaks := scan.Spectrum(true)
+		spectrum.Mz.DecodedStream = make([]float64, len(peaks))
+		spectrum.Intensity.DecodedStream = make([]float64, len(peaks))
+		for i := range peaks {
+			spectrum.Mz.DecodedStream[i] = peaks[i].Mz
+			spectrum.Intensity.DecodedStream[i] = float64(peaks[i].I)
+		}
+
+		fn(spectrum)
+	}
+
+	return
+}
+
+// processRawScan maps the scan header into a Spectrum, the peaks are left empty.
+// The RAW reader does not parse the scan trailers, so the precursor charge state
+// and the isolation window are not available
+func processRawScan(sn, parent int, scan fin.Scan) Spectrum {
+
+	var spec Spectrum
+
+	spec.Index = strconv.Itoa(sn - 1)
+	spec.Scan = strconv.Itoa(sn)
+	spec.Level = strconv.Itoa(int(scan.MSLevel))
+	spec.ScanStartTime = scan.Time
+
+	if scan.MSLevel > 1 {
+
+		if parent > 0 {
+			spec.Precursor.ParentScan = strconv.Itoa(parent)
+			spec.Precursor.ParentIndex = strconv.Itoa(parent - 1)
+		}
+
+		// the last reaction is the one that produced this scan
+		if len(scan.Fragment) > 0 {
+			last := scan.Fragment[len(scan.Fragment)-1]
+			if len(last.PrecursorMzs) > 0 {
+				spec.Precursor.TargetIon = last.PrecursorMzs[len(scan.Fragment)-1]
+				spec.Precursor.SelectedIon = spec.Precursor.TargetIon
+			}
+		}
+	}
+
+	return spec
+}
@@ -0,0 +1,166 @@
+package mzn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"philosopher/lib/fin"
+)
+
+func TestProcessRawScan(t *testing.T) {
+
+	ms1 := fin.Scan{MSLevel: 1, Time: 12.5}
+
+	got := processRawScan(10, 0, ms1)
+	if got.Index != "9" || got.Scan != "10" || got.Level != "1" || got.ScanStartTime != 12.5 {
+		t.Errorf("processRawScan() = %+v", got)
+	}
+
+	if len(got.Precursor.ParentScan) > 0 || got.Precursor.TargetIon != 0 {
+		t.Errorf("MS1 scan should not have a precursor, got %+v", got.Precursor)
+	}
+
+	// the reader stores one fragment per reaction, each one with its own position filled
+	ms3 := fin.Scan{
+		MSLevel: 3,
+		Time:    12.6,
+		Fragment: []fin.Fragment{
+			{PrecursorMzs: []float64{650.3, 0}, ColisionEnergy: []float64{35, 0}},
+			{PrecursorMzs: []float64{0, 126.1}, ColisionEnergy: []float64{0, 65}},
+		},
+	}
+
+	got = processRawScan(12, 11, ms3)
+	if got.Level != "3" || got.Precursor.ParentScan != "11" || got.Precursor.ParentIndex != "10" {
+		t.Errorf("processRawScan() = %+v", got)
+	}
+
+	if got.Precursor.TargetIon != 126.1 || got.Precursor.SelectedIon != 126.1 {
+		t.Errorf("TargetIon = %v, want 126.1", got.Precursor.TargetIon)
+	}
+}
+
+// rawTestScan describes a centroided scan of the RAW fixture
+type rawTestScan struct {
+	level     uint8
+	time      float64
+	precursor float64
+	peaks     [][2]float32
+}
+
+// writeRawTestData writes the scan data packets of a small RAW file and returns the reader
+// structures pointing at them, the file headers are not needed to read the scans
+func writeRawTestData(t *testing.T, scans []rawTestScan) *fin.RawData {
+
+	f, e := ioutil.TempFile("", "small*.raw")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	rd := &fin.RawData{File: f, FileName: f.Name()}
+
+	var offset uint64
+
+	for n, i := range scans {
+
+		var packet bytes.Buffer
+
+		header := fin.PacketHeader{PeaklistSize: uint32(len(i.peaks)), Lowmz: 100, Highmz: 2000}
+		binary.Write(&packet, binary.LittleEndian, header)
+		binary.Write(&packet, binary.LittleEndian, uint32(len(i.peaks)))
+		for _, j := range i.peaks {
+			binary.Write(&packet, binary.LittleEndian, fin.CentroidedPeak{Mz: j[0], Abundance: j[1]})
+		}
+
+		if _, e := f.Write(packet.Bytes()); e != nil {
+			t.Fatal(e)
+		}
+
+		var event fin.ScanEvent
+		event.Preamble[6] = i.level
+		if i.precursor > 0 {
+			event.Nprecursors = 1
+			event.Reaction = []fin.Reaction{{Precursormz: i.precursor, Energy: 35}}
+		}
+
+		rd.Scanevents = append(rd.Scanevents, event)
+		rd.Scanindex = append(rd.Scanindex, fin.ScanIndexEntry{
+			Index:          uint32(n),
+			Time:           i.time,
+			Offset:         offset,
+			DataPacketSize: uint32(packet.Len()),
+		})
+
+		offset += uint64(packet.Len())
+	}
+
+	rd.ScanCount = uint64(len(scans))
+
+	return rd
+}
+
+func TestForEachRawDataScan(t *testing.T) {
+
+	rd := writeRawTestData(t, []rawTestScan{
+		{level: 1, time: 10.0, peaks: [][2]float32{{400.25, 1000}, {500.5, 2000}, {650.75, 500}}},
+		{level: 2, time: 10.1, precursor: 500.5, peaks: [][2]float32{{120.5, 10}, {350.25, 20}}},
+		{level: 2, time: 10.2, precursor: 650.75, peaks: [][2]float32{{200.5, 30}}},
+		{level: 1, time: 10.3, peaks: [][2]float32{{500.5, 1500}}},
+		{level: 2, time: 10.4, precursor: 400.25, peaks: [][2]float32{{150.5, 5}, {250.5, 15}, {300.5, 25}}},
+	})
+	defer os.Remove(rd.FileName)
+	defer rd.Close()
+
+	var spectra Spectra
+	forEachRawDataScan(rd, SpectrumFilter{}, func(spec Spectrum) {
+		spectra = append(spectra, spec)
+	})
+
+	if len(spectra) != 5 {
+		t.Fatalf("got %d spectra, want 5", len(spectra))
+	}
+
+	tests := []struct {
+		scan, level, parent string
+		precursor           float64
+		peaks               int
+		first               float64
+	}{
+		{"1", "1", "", 0, 3, 400.25},
+		{"2", "2", "1", 500.5, 2, 120.5},
+		{"3", "2", "1", 650.75, 1, 200.5},
+		{"4", "1", "", 0, 1, 500.5},
+		{"5", "2", "4", 400.25, 3, 150.5},
+	}
+
+	for n, tt := range tests {
+
+		i := spectra[n]
+
+		if i.Scan != tt.scan || i.Index != strconv.Itoa(n) || i.Level != tt.level || i.Precursor.ParentScan != tt.parent {
+			t.Errorf("scan %d = %s, index %s, level %s, parent %s", n+1, i.Scan, i.Index, i.Level, i.Precursor.ParentScan)
+		}
+
+		if i.Precursor.TargetIon != tt.precursor || i.Precursor.ChargeState != 0 {
+			t.Errorf("scan %s precursor = %+v", i.Scan, i.Precursor)
+		}
+
+		if len(i.Mz.DecodedStream) != tt.peaks || len(i.Intensity.DecodedStream) != tt.peaks || i.Mz.DecodedStream[0] != tt.first {
+			t.Errorf("scan %s peaks = %v %v", i.Scan, i.Mz.DecodedStream, i.Intensity.DecodedStream)
+		}
+	}
+
+	// the filter selects the fragment scans of a time range, the parents are still tracked
+	spectra = nil
+	forEachRawDataScan(rd, SpectrumFilter{Levels: []string{"2"}, MinRT: 10.15, MaxRT: 10.5}, func(spec Spectrum) {
+		spectra = append(spectra, spec)
+	})
+
+	if len(spectra) != 2 || spectra[0].Scan != "3" || spectra[1].Scan != "5" || spectra[1].Precursor.ParentScan != "4" {
+		t.Errorf("filtered spectra = %+v", spectra)
+	}
+}
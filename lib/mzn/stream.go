@@ -0,0 +1,169 @@
+package mzn
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/psi"
+
+	"github.com/rogpeppe/go-charset/charset"
+
+	// anon charset
+	_ "github.com/rogpeppe/go-charset/data"
+)
+
+// SpectrumFilter defines which spectra are kept when streaming a file.
+// Zero values are ignored, so an empty filter accepts every spectrum.
+// Retention times are in the same unit as Spectrum.ScanStartTime (minutes)
+type SpectrumFilter struct {
+	Levels  []string
+	MinScan int
+	MaxScan int
+	MinRT   float64
+	MaxRT   float64
+}
+
+// Accept reports if a spectrum passes the filter criteria
+func (f SpectrumFilter) Accept(s Spectrum) bool {
+
+	if len(f.Levels) > 0 {
+		var found bool
+		for _, i := range f.Levels {
+			if i == s.Level {
+				found = true
+				break
+			}
+		}
+		if found == false {
+			return false
+		}
+	}
+
+	scan, _ := strconv.Atoi(s.Scan)
+	if !f.acceptScan(scan) {
+		return false
+	}
+
+	if f.MinRT > 0 && s.ScanStartTime < f.MinRT {
+		return false
+	}
+
+	if f.MaxRT > 0 && s.ScanStartTime > f.MaxRT {
+		return false
+	}
+
+	return true
+}
+
+// acceptScan checks the scan number against the scan range
+func (f SpectrumFilter) acceptScan(scan int) bool {
+
+	if f.MinScan > 0 && scan < f.MinScan {
+		return false
+	}
+
+	if f.MaxScan > 0 && scan > f.MaxScan {
+		return false
+	}
+
+	return true
+}
+
+// ForEachSpectrum streams an mzML, mzXML, MGF or Thermo RAW file and calls fn for
+// every spectrum that passes the filter. Spectra are decoded one at a time and are
+// never held in memory by the reader. The mzML binary arrays are left encoded
+// for the caller
+func ForEachSpectrum(f string, filter SpectrumFilter, fn func(Spectrum)) {
+
+	if strings.EqualFold(filepath.Ext(f), ".mzXML") {
+		forEachMzXMLScan(f, filter, fn)
+		return
+	} else if strings.EqualFold(filepath.Ext(f), ".mgf") {
+		forEachMGFSpectrum(f, filter, fn)
+		return
+	} else if strings.EqualFold(filepath.Ext(f), ".raw") {
+		forEachRawScan(f, filter, fn)
+		return
+	}
+
+	forEachMzMLSpectrum(f, filter, func(_ psi.Spectrum, spectrum Spectrum) {
+		fn(spectrum)
+	})
+
+	return
+}
+
+// forEachMzMLSpectrum streams an mzML file and calls fn with both the parsed element
+// and the processed spectrum for every spectrum that passes the filter
+func forEachMzMLSpectrum(f string, filter SpectrumFilter, fn func(psi.Spectrum, Spectrum)) {
+
+	xmlFile, e := os.Open(f)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+	defer xmlFile.Close()
+
+	decoder := xml.NewDecoder(bufio.NewReader(xmlFile))
+	decoder.CharsetReader = charset.NewReader
+
+	for {
+
+		t, e := decoder.Token()
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		se, ok := t.(xml.StartElement)
+		if !ok || se.Name.Local != "spectrum" {
+			continue
+		}
+
+		// the scan number is derived from the spectrum index, out of range
+		// spectra can be skipped without decoding them
+		scan := scanFromAttr(se.Attr)
+		if scan > 0 && !filter.acceptScan(scan) {
+			if filter.MaxScan > 0 && scan > filter.MaxScan {
+				break
+			}
+			decoder.Skip()
+			continue
+		}
+
+		var mzSpec psi.Spectrum
+		if e := decoder.DecodeElement(&mzSpec, &se); e != nil {
+			msg.DecodeMsgPck(e, "fatal")
+		}
+
+		spectrum := processSpectrum(mzSpec)
+
+		if filter.Accept(spectrum) {
+			fn(mzSpec, spectrum)
+		}
+	}
+
+	return
+}
+
+// scanFromAttr returns the scan number from the spectrum index attribute
+func scanFromAttr(attr []xml.Attr) int {
+
+	for _, i := range attr {
+		if i.Name.Local == "index" {
+			index, e := strconv.Atoi(i.Value)
+			if e != nil {
+				return 0
+			}
+			return index + 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,43 @@
+package mzn
+
+import (
+	"philosopher/lib/msg"
+	"philosopher/lib/psi"
+)
+
+// WriteMzML copies the spectra of an mzML file that pass the filter and the keep function
+// into a new indexedmzML file, and returns the number of spectra written. The header of the
+// source file is kept, and the selected spectra stay encoded in memory until the spectrum
+// count is known. A nil keep function accepts every spectrum
+func WriteMzML(source, output string, filter SpectrumFilter, keep func(Spectrum) bool) int {
+
+	header, e := psi.ReadMzMLHeader(source)
+	if e != nil {
+		msg.ReadFile(e, "fatal")
+	}
+
+	var selected []psi.Spectrum
+
+	forEachMzMLSpectrum(source, filter, func(mzSpec psi.Spectrum, spectrum Spectrum) {
+		if keep == nil || keep(spectrum) {
+			selected = append(selected, mzSpec)
+		}
+	})
+
+	w, e := psi.NewMzMLWriter(output, header, len(selected))
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	for _, i := range selected {
+		if e := w.WriteSpectrum(i); e != nil {
+			msg.WriteToFile(e, "fatal")
+		}
+	}
+
+	if e := w.Close(); e != nil {
+		msg.WriteToFile(e, "fatal")
+	}
+
+	return len(selected)
+}
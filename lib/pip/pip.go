@@ -71,6 +71,7 @@ type DatabaseSearch struct {
 	SearchEngine    string        `yaml:"search_engine"`
 	ProteinDatabase string        `yaml:"protein_database"`
 	DecoyTag        string        `yaml:"decoy_tag"`
+	ContamTag       string        `yaml:"contam_tag"`
 	MSFragger       met.MSFragger `yaml:"msfragger"`
 	Comet           met.Comet     `yaml:"comet"`
 }
@@ -117,6 +118,7 @@ func InitializeWorkspaces(meta met.Data, p Directives, dir, Version, Build strin
 		//if p.Commands.Database == "yes" {
 		meta.Database.Annot = p.DatabaseSearch.ProteinDatabase
 		meta.Database.Tag = p.DatabaseSearch.DecoyTag
+		meta.Database.ContamTag = p.DatabaseSearch.ContamTag
 		dat.Run(meta)
 		meta.Serialize()
 		//}
@@ -254,6 +256,7 @@ func PeptideProphet(meta met.Data, p Directives, dir string, data []string) met.
 		meta.Restore(sys.Meta())
 		meta.Database.Annot = p.DatabaseSearch.ProteinDatabase
 		meta.Database.Tag = p.DatabaseSearch.DecoyTag
+		meta.Database.ContamTag = p.DatabaseSearch.ContamTag
 
 		for _, ds := range data {
 
@@ -478,7 +481,9 @@ func FreeQuant(meta met.Data, p Directives, dir string, data []string) met.Data
 
 		meta.Quantify = p.Freequant
 		meta.Quantify.Dir = dsAbs
-		meta.Quantify.Format = "mzML"
+		if len(meta.Quantify.Format) < 1 {
+			meta.Quantify.Format = "mzML"
+		}
 		meta.Quantify.Pex = fmt.Sprintf("%s%sinteract.pep.xml", dsAbs, string(filepath.Separator))
 		meta.Quantify.Tag = "rev_"
 
@@ -517,7 +522,9 @@ func LabelQuant(meta met.Data, p Directives, dir string, data []string) met.Data
 
 		meta.Quantify = p.LabelQuant
 		meta.Quantify.Dir = dsAbs
-		meta.Quantify.Format = "mzML"
+		if len(meta.Quantify.Format) < 1 {
+			meta.Quantify.Format = "mzML"
+		}
 		meta.Quantify.Annot = fullAnnotation
 		meta.Quantify.Brand = p.LabelQuant.Brand
 		meta.Quantify.Pex = fmt.Sprintf("%s%sinteract.pep.xml", dsAbs, string(filepath.Separator))
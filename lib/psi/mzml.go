@@ -6,9 +6,33 @@ import (
 
 // IndexedMzML is the root level tag
 type IndexedMzML struct {
-	XMLName xml.Name `xml:"indexedmzML"`
-	Name    string
-	MzML    MzML `xml:"mzML"`
+	XMLName         xml.Name `xml:"indexedmzML"`
+	Name            string
+	MzML            MzML      `xml:"mzML"`
+	IndexList       IndexList `xml:"indexList"`
+	IndexListOffset int64     `xml:"indexListOffset"`
+	FileChecksum    string    `xml:"fileChecksum"`
+}
+
+// IndexList is the list of indices with the byte offsets of the spectrum and chromatogram elements
+type IndexList struct {
+	XMLName xml.Name `xml:"indexList"`
+	Count   int      `xml:"count,attr"`
+	Index   []Index  `xml:"index"`
+}
+
+// Index is the byte offset list for one element type, either spectrum or chromatogram
+type Index struct {
+	XMLName xml.Name `xml:"index"`
+	Name    string   `xml:"name,attr"`
+	Offset  []Offset `xml:"offset"`
+}
+
+// Offset is the byte position of an element start tag, referenced by the element native ID
+type Offset struct {
+	XMLName xml.Name `xml:"offset"`
+	IDRef   string   `xml:"idRef,attr"`
+	Value   int64    `xml:",chardata"`
 }
 
 // MzML This is the root element for the Proteomics Standards Initiative (PSI) mzML schema, which is intended to
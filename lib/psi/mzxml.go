@@ -0,0 +1,64 @@
+package psi
+
+import (
+	"encoding/xml"
+)
+
+// MzXML is the root level tag of the ISB mzXML format
+type MzXML struct {
+	XMLName xml.Name `xml:"mzXML"`
+	MsRun   MsRun    `xml:"msRun"`
+}
+
+// MsRun is a single acquisition run, it contains all scans
+type MsRun struct {
+	XMLName   xml.Name    `xml:"msRun"`
+	ScanCount int         `xml:"scanCount,attr"`
+	StartTime string      `xml:"startTime,attr"`
+	EndTime   string      `xml:"endTime,attr"`
+	Scan      []MzXMLScan `xml:"scan"`
+}
+
+// MzXMLScan tag, older versions of the format nest the fragment scans inside the precursor scan
+type MzXMLScan struct {
+	XMLName         xml.Name      `xml:"scan"`
+	Num             int           `xml:"num,attr"`
+	MsLevel         string        `xml:"msLevel,attr"`
+	PeaksCount      int           `xml:"peaksCount,attr"`
+	Polarity        string        `xml:"polarity,attr"`
+	ScanType        string        `xml:"scanType,attr"`
+	Centroided      string        `xml:"centroided,attr"`
+	RetentionTime   string        `xml:"retentionTime,attr"`
+	LowMz           float64       `xml:"lowMz,attr"`
+	HighMz          float64       `xml:"highMz,attr"`
+	BasePeakMz      float64       `xml:"basePeakMz,attr"`
+	BasePeakInt     float64       `xml:"basePeakIntensity,attr"`
+	TotIonCurrent   float64       `xml:"totIonCurrent,attr"`
+	CollisionEnergy float64       `xml:"collisionEnergy,attr"`
+	PrecursorMz     []PrecursorMz `xml:"precursorMz"`
+	Peaks           Peaks         `xml:"peaks"`
+	Scan            []MzXMLScan   `xml:"scan"`
+}
+
+// PrecursorMz contains the m/z of the precursor ion and its selection details
+type PrecursorMz struct {
+	XMLName            xml.Name `xml:"precursorMz"`
+	PrecursorScanNum   int      `xml:"precursorScanNum,attr"`
+	PrecursorIntensity float64  `xml:"precursorIntensity,attr"`
+	PrecursorCharge    int      `xml:"precursorCharge,attr"`
+	ActivationMethod   string   `xml:"activationMethod,attr"`
+	WindowWideness     float64  `xml:"windowWideness,attr"`
+	Value              float64  `xml:",chardata"`
+}
+
+// Peaks is the base64 encoded list of m/z and intensity pairs
+type Peaks struct {
+	XMLName         xml.Name `xml:"peaks"`
+	Precision       string   `xml:"precision,attr"`
+	ByteOrder       string   `xml:"byteOrder,attr"`
+	PairOrder       string   `xml:"pairOrder,attr"`
+	ContentType     string   `xml:"contentType,attr"`
+	CompressionType string   `xml:"compressionType,attr"`
+	CompressedLen   int      `xml:"compressedLen,attr"`
+	Value           []byte   `xml:",chardata"`
+}
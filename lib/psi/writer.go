@@ -0,0 +1,262 @@
+package psi
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MzMLHeader is the content of an mzML file that comes before the spectra. It is copied
+// as-is into new files so the instrument, software and processing descriptions are kept
+type MzMLHeader struct {
+	MzML                     []byte
+	DefaultDataProcessingRef string
+}
+
+// MzMLWriter creates indexedmzML files. Spectra are written one at a time and their
+// byte offsets are collected for the index at the end of the file
+type MzMLWriter struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	hash    hash.Hash
+	out     io.Writer
+	pos     int64
+	count   int
+	offsets []Offset
+	err     error
+}
+
+// ReadMzMLHeader reads an mzML file up to the spectrumList start tag
+func ReadMzMLHeader(f string) (MzMLHeader, error) {
+
+	var header MzMLHeader
+
+	file, e := os.Open(f)
+	if e != nil {
+		return header, e
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	var content []byte
+	var start = -1
+
+	// every read ends on a tag closing bracket, so the spectrumList start tag is never split
+	for start < 0 {
+
+		chunk, e := reader.ReadBytes('>')
+
+		if i := bytes.Index(chunk, []byte("<spectrumList")); i >= 0 {
+			start = len(content) + i
+		}
+
+		content = append(content, chunk...)
+
+		if start < 0 && e == io.EOF {
+			return header, errors.New("cannot find the spectrumList element in " + f)
+		} else if start < 0 && e != nil {
+			return header, e
+		}
+	}
+
+	mzml := bytes.Index(content, []byte("<mzML"))
+	if mzml < 0 {
+		return header, errors.New("cannot find the mzML element in " + f)
+	}
+
+	header.MzML = content[mzml:start]
+
+	ref := regexp.MustCompile(`defaultDataProcessingRef="([^"]*)"`).FindSubmatch(content[start:])
+	if len(ref) > 1 {
+		header.DefaultDataProcessingRef = string(ref[1])
+	}
+
+	return header, nil
+}
+
+// NewMzMLWriter creates an indexedmzML file for the given number of spectra
+func NewMzMLWriter(f string, header MzMLHeader, count int) (*MzMLWriter, error) {
+
+	file, e := os.Create(f)
+	if e != nil {
+		return nil, e
+	}
+
+	w := &MzMLWriter{file: file, buffer: bufio.NewWriter(file), hash: sha1.New()}
+	w.out = io.MultiWriter(w.buffer, w.hash)
+
+	w.printf("%s\n", strings.TrimSpace(xml.Header))
+	w.printf("<indexedmzML xmlns=\"http://psi.hupo.org/ms/mzml\" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" xsi:schemaLocation=\"http://psi.hupo.org/ms/mzml http://psidev.info/files/ms/mzML/xsd/mzML1.1.2_idx.xsd\">\n")
+	w.printf("%s", header.MzML)
+	w.printf("<spectrumList count=\"%d\" defaultDataProcessingRef=\"%s\">\n", count, escape(header.DefaultDataProcessingRef))
+
+	return w, nil
+}
+
+// WriteSpectrum adds a spectrum to the file, the index attribute is renumbered to keep the
+// spectrum list consecutive. The native ID is kept, it carries the original scan number
+func (w *MzMLWriter) WriteSpectrum(s Spectrum) error {
+
+	w.offsets = append(w.offsets, Offset{IDRef: s.ID, Value: w.pos})
+
+	w.printf("<spectrum index=\"%d\" id=\"%s\" defaultArrayLength=\"%d\"", w.count, escape(s.ID), int(s.DefaultArrayLength))
+	if len(s.SpotID) > 0 {
+		w.printf(" spotID=\"%s\"", escape(s.SpotID))
+	}
+	w.printf(">\n")
+
+	w.writeParams(s.CVParam, nil)
+
+	if len(s.ScanList.Scan) > 0 {
+		w.printf("<scanList count=\"%d\">\n", len(s.ScanList.Scan))
+		w.writeParams(s.ScanList.CVParam, nil)
+		for _, i := range s.ScanList.Scan {
+			if len(i.InstConfigurationRef) > 0 {
+				w.printf("<scan instrumentConfigurationRef=\"%s\">\n", escape(i.InstConfigurationRef))
+			} else {
+				w.printf("<scan>\n")
+			}
+			w.writeParams(i.CVParam, i.UserParam)
+			if len(i.ScanWindowList.ScanWindow) > 0 {
+				w.printf("<scanWindowList count=\"%d\">\n", len(i.ScanWindowList.ScanWindow))
+				for _, j := range i.ScanWindowList.ScanWindow {
+					w.printf("<scanWindow>\n")
+					w.writeParams(j.CVParam, nil)
+					w.printf("</scanWindow>\n")
+				}
+				w.printf("</scanWindowList>\n")
+			}
+			w.printf("</scan>\n")
+		}
+		w.printf("</scanList>\n")
+	}
+
+	if len(s.PrecursorList.Precursor) > 0 {
+		w.printf("<precursorList count=\"%d\">\n", len(s.PrecursorList.Precursor))
+		for _, i := range s.PrecursorList.Precursor {
+			if len(i.SpectrumRef) > 0 {
+				w.printf("<precursor spectrumRef=\"%s\">\n", escape(i.SpectrumRef))
+			} else {
+				w.printf("<precursor>\n")
+			}
+			if len(i.IsolationWindow.CVParam) > 0 || len(i.IsolationWindow.UserParam) > 0 {
+				w.printf("<isolationWindow>\n")
+				w.writeParams(i.IsolationWindow.CVParam, i.IsolationWindow.UserParam)
+				w.printf("</isolationWindow>\n")
+			}
+			if len(i.SelectedIonList.SelectedIon) > 0 {
+				w.printf("<selectedIonList count=\"%d\">\n", len(i.SelectedIonList.SelectedIon))
+				for _, j := range i.SelectedIonList.SelectedIon {
+					w.printf("<selectedIon>\n")
+					w.writeParams(j.CVParam, nil)
+					w.printf("</selectedIon>\n")
+				}
+				w.printf("</selectedIonList>\n")
+			}
+			w.printf("<activation>\n")
+			w.writeParams(i.Activation.CVParam, nil)
+			w.printf("</activation>\n")
+			w.printf("</precursor>\n")
+		}
+		w.printf("</precursorList>\n")
+	}
+
+	w.printf("<binaryDataArrayList count=\"%d\">\n", len(s.BinaryDataArrayList.BinaryDataArray))
+	for _, i := range s.BinaryDataArrayList.BinaryDataArray {
+		w.printf("<binaryDataArray encodedLength=\"%d\">\n", len(i.Binary.Value))
+		w.writeParams(i.CVParam, nil)
+		w.printf("<binary>%s</binary>\n", i.Binary.Value)
+		w.printf("</binaryDataArray>\n")
+	}
+	w.printf("</binaryDataArrayList>\n")
+
+	w.printf("</spectrum>\n")
+
+	w.count++
+
+	return w.err
+}
+
+// Close writes the offset index and the file checksum, and closes the file
+func (w *MzMLWriter) Close() error {
+
+	defer w.file.Close()
+
+	w.printf("</spectrumList>\n</run>\n</mzML>\n")
+
+	indexListOffset := w.pos
+
+	w.printf("<indexList count=\"1\">\n<index name=\"spectrum\">\n")
+	for _, i := range w.offsets {
+		w.printf("<offset idRef=\"%s\">%d</offset>\n", escape(i.IDRef), i.Value)
+	}
+	w.printf("</index>\n</indexList>\n")
+	w.printf("<indexListOffset>%d</indexListOffset>\n", indexListOffset)
+
+	// the checksum covers the whole file up to and including the fileChecksum start tag
+	w.printf("<fileChecksum>")
+	fmt.Fprintf(w.buffer, "%x</fileChecksum>\n</indexedmzML>\n", w.hash.Sum(nil))
+
+	if w.err != nil {
+		return w.err
+	}
+
+	return w.buffer.Flush()
+}
+
+// writeParams prints the cvParam and userParam elements
+func (w *MzMLWriter) writeParams(cv []CVParam, user []UserParam) {
+
+	for _, i := range cv {
+
+		cvRef := i.CVRef
+		if len(cvRef) == 0 {
+			cvRef = strings.Split(i.Accession, ":")[0]
+		}
+
+		w.printf("<cvParam cvRef=\"%s\" accession=\"%s\" name=\"%s\" value=\"%s\"", escape(cvRef), escape(i.Accession), escape(i.Name), escape(i.Value))
+		if len(i.UnitAccession) > 0 {
+			unitCvRef := i.UnitCvRef
+			if len(unitCvRef) == 0 {
+				unitCvRef = strings.Split(i.UnitAccession, ":")[0]
+			}
+			w.printf(" unitCvRef=\"%s\" unitAccession=\"%s\" unitName=\"%s\"", escape(unitCvRef), escape(i.UnitAccession), escape(i.UnitName))
+		}
+		w.printf("/>\n")
+	}
+
+	for _, i := range user {
+		w.printf("<userParam name=\"%s\"", escape(i.Name))
+		if len(i.Type) > 0 {
+			w.printf(" type=\"%s\"", escape(i.Type))
+		}
+		w.printf(" value=\"%s\"/>\n", escape(i.Value))
+	}
+
+	return
+}
+
+// printf writes to the file keeping track of the byte position and the checksum
+func (w *MzMLWriter) printf(format string, a ...interface{}) {
+	n, e := fmt.Fprintf(w.out, format, a...)
+	if e != nil && w.err == nil {
+		w.err = e
+	}
+	w.pos += int64(n)
+}
+
+// escape prepares a string to be used as an attribute value
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
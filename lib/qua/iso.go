@@ -103,10 +103,20 @@ func calculateIonPurity(d, f string, mz mzn.MsData, evi []rep.PSMEvidence) []rep
 				}
 			}
 
+			// RAW scans have no precursor charge state, the charge of the PSM is used instead
+			charge := v2.Precursor.ChargeState
+			if charge == 0 {
+				charge = int(evi[i].AssumedCharge)
+			}
+
+			if charge == 0 {
+				continue
+			}
+
 			// create the list of mz differences for each peak
 			var mzRatio []float64
 			for k := 1; k <= 6; k++ {
-				r := float64(k) * (float64(1) / float64(v2.Precursor.ChargeState))
+				r := float64(k) * (float64(1) / float64(charge))
 				mzRatio = append(mzRatio, uti.ToFixed(r, 2))
 			}
 
@@ -1426,8 +1436,13 @@ func NormToTotalProteins(evi rep.Evidence) rep.Evidence {
 	var channelSum = [16]float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 	var normFactors = [16]float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 
-	// sum TMT singal for each column
+	// sum TMT singal for each column, contaminants don't take part in the normalization
 	for _, i := range evi.Proteins {
+
+		if i.IsDecoy || i.IsContaminant {
+			continue
+		}
+
 		channelSum[0] += i.URazorLabels.Channel1.Intensity
 		channelSum[1] += i.URazorLabels.Channel2.Intensity
 		channelSum[2] += i.URazorLabels.Channel3.Intensity
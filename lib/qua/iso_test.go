@@ -0,0 +1,39 @@
+package qua
+
+import (
+	"testing"
+
+	"philosopher/lib/mzn"
+	"philosopher/lib/rep"
+)
+
+func TestCalculateIonPurity(t *testing.T) {
+
+	var ms1 mzn.Spectrum
+	ms1.Level = "1"
+	ms1.Index = "0"
+	ms1.Scan = "1"
+	// the precursor, an interference and the second isotope of a doubly charged precursor
+	ms1.Mz.DecodedStream = []float64{500.0, 500.3, 500.5}
+	ms1.Intensity.DecodedStream = []float64{100, 20, 50}
+
+	// RAW scans have no precursor charge state
+	var ms2 mzn.Spectrum
+	ms2.Level = "2"
+	ms2.Index = "1"
+	ms2.Scan = "2"
+	ms2.Precursor.ParentScan = "1"
+	ms2.Precursor.ParentIndex = "0"
+	ms2.Precursor.TargetIon = 500.0
+
+	var mz mzn.MsData
+	mz.Spectra = mzn.Spectra{ms1, ms2}
+
+	evi := []rep.PSMEvidence{{Spectrum: "run.00002.00002.2", AssumedCharge: 2}}
+
+	evi = calculateIonPurity("", "", mz, evi)
+
+	if evi[0].Purity != 0.88 {
+		t.Errorf("Purity = %v, want 0.88", evi[0].Purity)
+	}
+}
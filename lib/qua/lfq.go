@@ -33,7 +33,9 @@ func NewLFQ() LFQ {
 	return self
 }
 
-func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float64, isIso bool) rep.Evidence {
+// peakIntensity traces the precursor of each PSM on the MS1 scans and reports the apex intensity. When a mobility
+// tolerance is given, only the peaks within the tolerance of the PSM 1/K0 are traced and the apex 1/K0 is reported
+func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol, mobTol float64, isIso bool, threads int) rep.Evidence {
 
 	logrus.Info("Indexing PSM information")
 
@@ -43,22 +45,41 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 	var mzMap = make(map[string]float64)
 	var minRT = make(map[string]float64)
 	var maxRT = make(map[string]float64)
+	var sourceMinRT = make(map[string]float64)
+	var sourceMaxRT = make(map[string]float64)
 	var retentionTime = make(map[string]float64)
 	var intensity = make(map[string]float64)
+	var mobility = make(map[string]float64)
+	var apexMobility = make(map[string]float64)
 
 	var charges = make(map[string]int)
+	var scanSpectra = make(map[string][]string)
 
 	// collect attributes from PSM
 	for _, i := range evi.PSM {
 		partName := strings.Split(i.Spectrum, ".")
 		sourceMap[partName[0]] = 0
 		spectra[partName[0]] = append(spectra[partName[0]], i.Spectrum)
+		if len(partName) > 1 {
+			scanSpectra[partName[0]+"."+partName[1]] = append(scanSpectra[partName[0]+"."+partName[1]], i.Spectrum)
+		}
 
 		ppmPrecision[i.Spectrum] = tol / math.Pow(10, 6)
 		mzMap[i.Spectrum] = ((i.PrecursorNeutralMass + (float64(i.AssumedCharge) * bio.Proton)) / float64(i.AssumedCharge))
 		minRT[i.Spectrum] = (i.RetentionTime / 60) - rTWin
 		maxRT[i.Spectrum] = (i.RetentionTime / 60) + rTWin
 		retentionTime[i.Spectrum] = i.RetentionTime
+		mobility[i.Spectrum] = i.IonMobility
+
+		// the retention time range covered by the PSMs of each source file
+		v, ok := sourceMinRT[partName[0]]
+		if !ok || minRT[i.Spectrum] < v {
+			sourceMinRT[partName[0]] = minRT[i.Spectrum]
+		}
+
+		if maxRT[i.Spectrum] > sourceMaxRT[partName[0]] {
+			sourceMaxRT[partName[0]] = maxRT[i.Spectrum]
+		}
 
 		charges[i.Spectrum] = int(i.AssumedCharge)
 	}
@@ -76,33 +97,30 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 		logrus.Info("Processing ", s)
 		var mz mzn.MsData
 
-		fileName := fmt.Sprintf("%s%s%s.mzML", dir, string(filepath.Separator), s)
-
-		// load MS1, ignore MS2 and MS3
-		mz.Read(fileName)
-
-		for i := range mz.Spectra {
-			if mz.Spectra[i].Level == "1" {
-				mz.Spectra[i].Decode()
-			} else if mz.Spectra[i].Level == "2" {
-				spectrum := fmt.Sprintf("%s.%05s.%05s.%d", s, mz.Spectra[i].Scan, mz.Spectra[i].Scan, mz.Spectra[i].Precursor.ChargeState)
-				_, ok := mzMap[spectrum]
-				if ok {
-					// update the MZ with the desired Precursor value from mzML
-					if isIso == true {
-						mzMap[spectrum] = mz.Spectra[i].Precursor.TargetIon
-					} else {
-						mzMap[spectrum] = mz.Spectra[i].Precursor.SelectedIon
-					}
-				}
-			}
+		fileName := fmt.Sprintf("%s%s%s.%s", dir, string(filepath.Separator), s, format)
+
+		// stream MS1 and MS2 inside the PSMs time range, ignore MS3. Only the MS1 are kept
+		filter := mzn.SpectrumFilter{
+			Levels: []string{"1", "2"},
+			MinRT:  sourceMinRT[s],
+			MaxRT:  sourceMaxRT[s],
 		}
 
+		mzn.ForEachSpectrum(fileName, filter, func(spec mzn.Spectrum) {
+			if spec.Level == "1" {
+				mz.Spectra = append(mz.Spectra, spec)
+			} else if spec.Level == "2" {
+				updatePrecursorMz(mzMap, scanSpectra[fmt.Sprintf("%s.%05s", s, spec.Scan)], charges, spec, isIso)
+			}
+		})
+
+		mz.Spectra.DecodeAll(threads)
+
 		v, ok := spectra[s]
 		if ok {
 			for _, j := range v {
 
-				measured, retrieved := xic(mz.Spectra, minRT[j], maxRT[j], ppmPrecision[j], mzMap[j])
+				measured, mobilities, retrieved := xic(mz.Spectra, minRT[j], maxRT[j], ppmPrecision[j], mzMap[j], mobility[j], mobTol)
 
 				// if j == "20180209_03_TP_1A.03130.03130.2#interact.pep.xml" {
 				// 	fmt.Println(measured)
@@ -117,18 +135,7 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 						mzRatio = append(mzRatio, uti.ToFixed(r, 2))
 					}
 
-					var timeW = retentionTime[j] / 60
-					var topI = 0.0
-
-					for k, v := range measured {
-						if k > (timeW-pTWin) && k < (timeW+pTWin) {
-							if v > topI {
-								topI = v
-							}
-						}
-					}
-
-					intensity[j] = topI
+					intensity[j], apexMobility[j] = apex(measured, mobilities, retentionTime[j]/60, pTWin)
 				}
 			}
 		}
@@ -139,16 +146,60 @@ func peakIntensity(evi rep.Evidence, dir, format string, rTWin, pTWin, tol float
 		_, ok := spectra[partName[0]]
 		if ok {
 			evi.PSM[i].Intensity = intensity[evi.PSM[i].Spectrum]
+			evi.PSM[i].ApexIonMobility = apexMobility[evi.PSM[i].Spectrum]
 		}
 	}
 
 	return evi
 }
 
-// xic extract ion chomatograms
-func xic(mz mzn.Spectra, minRT, maxRT, ppmPrecision, mzValue float64) (map[float64]float64, bool) {
+// updatePrecursorMz replaces the m/z of the PSMs identified on an MS2 scan with the precursor value of the
+// scan. The PSMs are matched on the scan number, and on the charge state when the scan reports one; RAW scans
+// have no precursor charge, so all the PSMs of the scan are updated
+func updatePrecursorMz(mzMap map[string]float64, psms []string, charges map[string]int, spec mzn.Spectrum, isIso bool) {
+
+	for _, j := range psms {
+
+		if spec.Precursor.ChargeState > 0 && spec.Precursor.ChargeState != charges[j] {
+			continue
+		}
+
+		// update the MZ with the desired Precursor value from the spectra
+		if isIso == true {
+			mzMap[j] = spec.Precursor.TargetIon
+		} else {
+			mzMap[j] = spec.Precursor.SelectedIon
+		}
+	}
+
+	return
+}
+
+// apex returns the highest intensity of the chromatogram within the peak window around the
+// retention time, and the 1/K0 of the peak traced on that scan
+func apex(measured, mobilities map[float64]float64, timeW, pTWin float64) (float64, float64) {
+
+	var topI = 0.0
+	var topMob = 0.0
+
+	for k, v := range measured {
+		if k > (timeW-pTWin) && k < (timeW+pTWin) {
+			if v > topI {
+				topI = v
+				topMob = mobilities[k]
+			}
+		}
+	}
+
+	return topI, topMob
+}
+
+// xic extract ion chomatograms, the peaks are restricted to the mobility window when the
+// spectra carry an ion mobility array. The 1/K0 of the most intense peak of each scan is returned
+func xic(mz mzn.Spectra, minRT, maxRT, ppmPrecision, mzValue, mobility, mobTol float64) (map[float64]float64, map[float64]float64, bool) {
 
 	var list = make(map[float64]float64)
+	var mobList = make(map[float64]float64)
 
 	for j := range mz {
 		if mz[j].Level == "1" {
@@ -159,19 +210,33 @@ func xic(mz mzn.Spectra, minRT, maxRT, ppmPrecision, mzValue float64) (map[float
 				highi := sort.Search(len(mz[j].Mz.DecodedStream), func(i int) bool { return mz[j].Mz.DecodedStream[i] >= mzValue+ppmPrecision*mzValue })
 
 				var maxI = 0.0
+				var maxMob = 0.0
 
 				// if mz[j].Index == "3106" {
 				// 	spew.Dump(mzValue, mz[j].Intensity.DecodedStream[lowi:highi])
 				// }
 
-				for _, k := range mz[j].Intensity.DecodedStream[lowi:highi] {
-					if k > maxI {
-						maxI = k
+				hasMobility := len(mz[j].IonMobility.DecodedStream) == len(mz[j].Mz.DecodedStream)
+
+				for k := lowi; k < highi; k++ {
+
+					if hasMobility && mobTol > 0 && mobility > 0 {
+						if math.Abs(mz[j].IonMobility.DecodedStream[k]-mobility) > mobTol {
+							continue
+						}
+					}
+
+					if mz[j].Intensity.DecodedStream[k] > maxI {
+						maxI = mz[j].Intensity.DecodedStream[k]
+						if hasMobility {
+							maxMob = mz[j].IonMobility.DecodedStream[k]
+						}
 					}
 				}
 
 				if maxI > 0 {
 					list[mz[j].ScanStartTime] = maxI
+					mobList[mz[j].ScanStartTime] = maxMob
 				}
 
 			}
@@ -179,10 +244,10 @@ func xic(mz mzn.Spectra, minRT, maxRT, ppmPrecision, mzValue float64) (map[float
 	}
 
 	if len(list) >= 5 {
-		return list, true
+		return list, mobList, true
 	}
 
-	return list, false
+	return list, mobList, false
 }
 
 func calculateIntensities(e rep.Evidence) rep.Evidence {
@@ -195,6 +260,7 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 
 	var peptideIntMap = make(map[string]float64)
 	var ionIntMap = make(map[string]float64)
+	var ionMobMap = make(map[string]float64)
 
 	for _, i := range e.PSM {
 
@@ -211,9 +277,11 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 		if ok {
 			if i.Intensity > ionV {
 				ionIntMap[i.IonForm] = i.Intensity
+				ionMobMap[i.IonForm] = i.ApexIonMobility
 			}
 		} else {
 			ionIntMap[i.IonForm] = i.Intensity
+			ionMobMap[i.IonForm] = i.ApexIonMobility
 		}
 
 	}
@@ -229,6 +297,7 @@ func calculateIntensities(e rep.Evidence) rep.Evidence {
 		v, ok := ionIntMap[e.Ions[i].IonForm]
 		if ok {
 			e.Ions[i].Intensity = v
+			e.Ions[i].ApexIonMobility = ionMobMap[e.Ions[i].IonForm]
 		}
 	}
 
@@ -0,0 +1,95 @@
+package qua
+
+import (
+	"testing"
+
+	"philosopher/lib/mzn"
+)
+
+func TestUpdatePrecursorMz(t *testing.T) {
+
+	psms := []string{"run.00010.00010.2", "run.00010.00010.3"}
+	charges := map[string]int{"run.00010.00010.2": 2, "run.00010.00010.3": 3}
+
+	// RAW scans carry no charge state, both PSMs of the scan are updated
+	var raw mzn.Spectrum
+	raw.Scan = "10"
+	raw.Precursor.TargetIon = 500.25
+	raw.Precursor.SelectedIon = 500.26
+
+	mzMap := map[string]float64{"run.00010.00010.2": 500, "run.00010.00010.3": 333}
+	updatePrecursorMz(mzMap, psms, charges, raw, false)
+
+	if mzMap["run.00010.00010.2"] != 500.26 || mzMap["run.00010.00010.3"] != 500.26 {
+		t.Errorf("RAW precursors = %v", mzMap)
+	}
+
+	// mzML scans report the charge state, only the PSM with the same charge is updated
+	mzml := raw
+	mzml.Precursor.ChargeState = 3
+
+	mzMap = map[string]float64{"run.00010.00010.2": 500, "run.00010.00010.3": 333}
+	updatePrecursorMz(mzMap, psms, charges, mzml, true)
+
+	if mzMap["run.00010.00010.2"] != 500 || mzMap["run.00010.00010.3"] != 500.25 {
+		t.Errorf("mzML precursors = %v", mzMap)
+	}
+}
+
+func TestXic(t *testing.T) {
+
+	var spectra mzn.Spectra
+
+	for k := 0; k < 6; k++ {
+		var s mzn.Spectrum
+		s.Level = "1"
+		s.ScanStartTime = 10 + float64(k)/10
+		s.Mz.DecodedStream = []float64{499.9, 500.0, 500.001, 500.1}
+		s.Intensity.DecodedStream = []float64{5000, 100 + float64(k), 1000, 5000}
+		// the most intense peak in the m/z window belongs to another ion mobility
+		s.IonMobility.DecodedStream = []float64{0.91, 0.90 + float64(k)/1000, 1.20, 0.91}
+		spectra = append(spectra, s)
+	}
+
+	// an MS2 scan and an MS1 scan outside the retention time window are ignored
+	ms2 := spectra[0]
+	ms2.Level = "2"
+	late := spectra[0]
+	late.ScanStartTime = 20
+	spectra = append(spectra, ms2, late)
+
+	measured, mobilities, ok := xic(spectra, 9.9, 10.6, 10e-6, 500.0, 0.91, 0.05)
+	if !ok || len(measured) != 6 {
+		t.Fatalf("got %d peaks, retrieved %v", len(measured), ok)
+	}
+
+	for k := 0; k < 6; k++ {
+		rt := 10 + float64(k)/10
+		if measured[rt] != 100+float64(k) || mobilities[rt] != 0.90+float64(k)/1000 {
+			t.Errorf("scan at %v: intensity %v, 1/K0 %v", rt, measured[rt], mobilities[rt])
+		}
+	}
+
+	// the apex is the most intense scan inside the peak window, reported with its 1/K0
+	top, mob := apex(measured, mobilities, 10.3, 0.25)
+	if top != 105 || mob != 0.905 {
+		t.Errorf("apex() = %v %v, want 105 0.905", top, mob)
+	}
+
+	top, mob = apex(measured, mobilities, 10.1, 0.15)
+	if top != 102 || mob != 0.902 {
+		t.Errorf("apex() = %v %v, want 102 0.902", top, mob)
+	}
+
+	// without a mobility tolerance the most intense peak is traced, whatever its 1/K0
+	measured, mobilities, _ = xic(spectra, 9.9, 10.6, 10e-6, 500.0, 0.91, 0)
+	if measured[10] != 1000 || mobilities[10] != 1.20 {
+		t.Errorf("no mobility window: intensity %v, 1/K0 %v", measured[10], mobilities[10])
+	}
+
+	// the peaks outside the mobility window are never traced
+	measured, _, ok = xic(spectra, 9.9, 10.6, 10e-6, 500.0, 1.5, 0.05)
+	if ok || len(measured) != 0 {
+		t.Errorf("got %d peaks outside the mobility window", len(measured))
+	}
+}
@@ -38,10 +38,14 @@ func RunLabelFreeQuantification(p met.Quantify) {
 	// This parameter is hardcoded now because of the changes in the latest msconvert version 3.20.
 	p.Isolated = true
 
+	if strings.EqualFold(p.Format, "mgf") {
+		msg.Custom(errors.New("MGF files have no MS1 scans, the label-free quantification traces the precursors on the MS1 scans of mzML, mzXML or RAW files"), "fatal")
+	}
+
 	var evi rep.Evidence
 	evi.RestoreGranular()
 
-	evi = peakIntensity(evi, p.Dir, p.Format, p.RTWin, p.PTWin, p.Tol, p.Isolated)
+	evi = peakIntensity(evi, p.Dir, p.Format, p.RTWin, p.PTWin, p.Tol, p.MobTol, p.Isolated, p.Threads)
 
 	evi = calculateIntensities(evi)
 
@@ -61,6 +65,19 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 		msg.NoParametersFound(errors.New("You need to specify a brand type (tmt or itraq)"), "fatal")
 	}
 
+	// the reporter ions are read from the MS2 spectra, MGF files have no MS1 or MS3 scans
+	if strings.EqualFold(p.Format, "mgf") {
+
+		if p.Level == 3 {
+			msg.Custom(errors.New("MGF files have no MS3 scans, the MS3 reporter ions require mzML, mzXML or RAW files"), "fatal")
+		}
+
+		if p.Purity > 0 {
+			msg.Custom(errors.New("MGF files have no MS1 scans, the ion purity cannot be calculated and the purity filter is disabled"), "warning")
+			p.Purity = 0
+		}
+	}
+
 	var evi rep.Evidence
 	evi.RestoreGranular()
 
@@ -92,16 +109,10 @@ func RunIsobaricLabelQuantification(p met.Quantify, mods bool) met.Quantify {
 
 	for i := range sourceList {
 
-		var mz mzn.MsData
-
 		logrus.Info("Processing ", sourceList[i])
-		fileName := fmt.Sprintf("%s%s%s.mzML", p.Dir, string(filepath.Separator), sourceList[i])
+		fileName := fmt.Sprintf("%s%s%s.%s", p.Dir, string(filepath.Separator), sourceList[i], p.Format)
 
-		mz.Read(fileName)
-
-		for i := range mz.Spectra {
-			mz.Spectra[i].Decode()
-		}
+		mz := readIsobaricSpectra(fileName, p.Level, p.Threads, sourceMap[sourceList[i]])
 
 		mappedPurity := calculateIonPurity(p.Dir, p.Format, mz, sourceMap[sourceList[i]])
 
@@ -210,6 +221,55 @@ func RunBioQuantification(c met.Data) {
 	return
 }
 
+// readIsobaricSpectra streams the spectra needed for the isobaric quantification, all MS1 scans are
+// kept for the purity calculation but only the MS2 and MS3 scans related to the PSMs are decoded
+func readIsobaricSpectra(f string, level, threads int, evi []rep.PSMEvidence) mzn.MsData {
+
+	var mz mzn.MsData
+	var scans = make(map[string]uint8)
+
+	for _, i := range evi {
+		split := strings.Split(i.Spectrum, ".")
+		scans[split[1]] = i.AssumedCharge
+		scans[split[2]] = i.AssumedCharge
+	}
+
+	filter := mzn.SpectrumFilter{Levels: []string{"1", "2"}}
+	if level == 3 {
+		filter.Levels = append(filter.Levels, "3")
+	}
+
+	mz.FileName = f
+
+	mzn.ForEachSpectrum(f, filter, func(spec mzn.Spectrum) {
+
+		if spec.Level == "2" {
+			charge, ok := scans[fmt.Sprintf("%05s", spec.Scan)]
+			if !ok {
+				return
+			}
+			// RAW files do not carry the precursor charge, take it from the identification
+			if spec.Precursor.ChargeState == 0 {
+				spec.Precursor.ChargeState = int(charge)
+			}
+		} else if spec.Level == "3" {
+			if _, ok := scans[fmt.Sprintf("%05s", spec.Precursor.ParentScan)]; !ok {
+				return
+			}
+		}
+
+		mz.Spectra = append(mz.Spectra, spec)
+	})
+
+	mz.Spectra.DecodeAll(threads)
+
+	if len(mz.Spectra) == 0 {
+		msg.NoSpectraFound(errors.New(f), "fatal")
+	}
+
+	return mz
+}
+
 // cleanPreviousData cleans previous label quantifications
 func cleanPreviousData(evi rep.Evidence, brand, plex string) rep.Evidence {
 
@@ -0,0 +1,66 @@
+package qua
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"philosopher/lib/rep"
+)
+
+const reporterMGF = `BEGIN IONS
+TITLE=run.00002.00002.2
+PEPMASS=500.25
+CHARGE=2+
+SCANS=2
+126.127726 1000.0
+127.124761 2000.0
+350.2 50.0
+END IONS
+BEGIN IONS
+TITLE=run.00003.00003.2
+PEPMASS=600.3
+CHARGE=2+
+SCANS=3
+126.127726 10.0
+END IONS
+`
+
+func TestReadIsobaricSpectraMGF(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "labelquant")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "run.mgf")
+	if e := ioutil.WriteFile(f, []byte(reporterMGF), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	evi := []rep.PSMEvidence{{Spectrum: "run.00002.00002.2", AssumedCharge: 2}}
+
+	mz := readIsobaricSpectra(f, 2, 1, evi)
+
+	if len(mz.Spectra) != 1 || mz.Spectra[0].Scan != "2" {
+		t.Fatalf("got %d spectra", len(mz.Spectra))
+	}
+
+	labels := prepareLabelStructureWithMS2(dir, "mgf", "tmt", "10", 20, mz)
+
+	l, ok := labels["00002"]
+	if !ok {
+		t.Fatalf("no reporter ions for scan 2")
+	}
+
+	if l.Channel1.Intensity != 1000 || l.Channel2.Intensity != 2000 || l.ChargeState != 2 {
+		t.Errorf("reporter ions = %v %v, charge %d", l.Channel1.Intensity, l.Channel2.Intensity, l.ChargeState)
+	}
+
+	// without MS1 scans the purity of the PSMs is zero
+	if p := calculateIonPurity(dir, "mgf", mz, evi); p[0].Purity != 0 {
+		t.Errorf("Purity = %v, want 0", p[0].Purity)
+	}
+}
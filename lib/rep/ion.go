@@ -135,7 +135,22 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys, hasLabe
 		}
 	}
 
-	header = "Peptide Sequence\tModified Sequence\tPeptide Length\tM/Z\tCharge\tObserved Mass\tProbability\tExpectation\tSpectral Count\tIntensity\tAssigned Modifications\tObserved Modifications\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
+	// the apex ion mobility is only available after an ion mobility aware quantification
+	var hasApexMobility bool
+	for _, i := range printSet {
+		if i.ApexIonMobility > 0 {
+			hasApexMobility = true
+			break
+		}
+	}
+
+	header = "Peptide Sequence\tModified Sequence\tPeptide Length\tM/Z\tCharge\tObserved Mass\tProbability\tExpectation\tSpectral Count\tIntensity"
+
+	if hasApexMobility == true {
+		header += "\tApex Ion Mobility"
+	}
+
+	header += "\tAssigned Modifications\tObserved Modifications\tProtein\tProtein ID\tEntry Name\tGene\tProtein Description\tMapped Genes\tMapped Proteins"
 
 	if brand == "tmt" {
 		switch channels {
@@ -236,7 +251,7 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys, hasLabe
 		sort.Strings(assL)
 		sort.Strings(obs)
 
-		line := fmt.Sprintf("%s\t%s\t%d\t%.4f\t%d\t%.4f\t%.4f\t%.4f\t%d\t%.4f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+		line := fmt.Sprintf("%s\t%s\t%d\t%.4f\t%d\t%.4f\t%.4f\t%.4f\t%d\t%.4f",
 			i.Sequence,
 			i.ModifiedSequence,
 			len(i.Sequence),
@@ -247,6 +262,17 @@ func (evi Evidence) MetaIonReport(brand string, channels int, hasDecoys, hasLabe
 			i.Expectation,
 			len(i.Spectra),
 			i.Intensity,
+		)
+
+		if hasApexMobility == true {
+			line = fmt.Sprintf("%s\t%.4f",
+				line,
+				i.ApexIonMobility,
+			)
+		}
+
+		line = fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+			line,
 			strings.Join(assL, ", "),
 			strings.Join(obs, ", "),
 			i.Protein,
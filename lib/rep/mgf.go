@@ -0,0 +1,135 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/bio"
+	"philosopher/lib/msg"
+	"philosopher/lib/mzn"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MGFReport exports the fragment spectra behind the reported PSMs as an MGF file
+// annotated with the peptide sequence, charge state and probability
+func (evi Evidence) MGFReport(dir string, hasDecoys bool) {
+
+	output := fmt.Sprintf("%s%spsm.mgf", sys.MetaDir(), string(filepath.Separator))
+
+	sourceMap, sources := groupPSMsByScan(evi.PSM, hasDecoys)
+
+	var entries []mzn.MGFEntry
+
+	for _, s := range sources {
+
+		f := findSpectraFile(dir, s)
+		if len(f) == 0 {
+			msg.InputNotFound(errors.New("Cannot find the spectra file for "+s), "error")
+			continue
+		}
+
+		logrus.Info("Exporting spectra from ", filepath.Base(f))
+
+		psms := sourceMap[s]
+
+		mzn.ForEachSpectrum(f, mzn.SpectrumFilter{Levels: []string{"2"}}, func(spec mzn.Spectrum) {
+
+			list, ok := psms[fmt.Sprintf("%05s", spec.Scan)]
+			if !ok {
+				return
+			}
+
+			spec.Decode()
+
+			// one entry for each PSM of the spectrum, the lower-ranked hits get the rank in the title
+			for _, psm := range list {
+
+				entry := mzn.MGFEntry{
+					Title:           psm.Spectrum,
+					Peptide:         psm.Peptide,
+					ModifiedPeptide: psm.ModifiedPeptide,
+					Charge:          int(psm.AssumedCharge),
+					Probability:     psm.Probability,
+					Spectrum:        spec,
+				}
+
+				if psm.HitRank > 1 {
+					entry.Title = fmt.Sprintf("%s_%d", psm.Spectrum, psm.HitRank)
+				}
+
+				if psm.AssumedCharge > 0 {
+					entry.PrecursorMz = (psm.PrecursorNeutralMass + (float64(psm.AssumedCharge) * bio.Proton)) / float64(psm.AssumedCharge)
+				}
+
+				entries = append(entries, entry)
+			}
+		})
+	}
+
+	mzn.WriteMGF(output, entries)
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
+
+// groupPSMsByScan groups the PSMs by source file and scan, a spectrum can have several PSMs
+// with different charge states or hit ranks. The PSMs of a scan are sorted by rank and name
+func groupPSMsByScan(psms PSMEvidenceList, hasDecoys bool) (map[string]map[string]PSMEvidenceList, []string) {
+
+	var sourceMap = make(map[string]map[string]PSMEvidenceList)
+	var sources []string
+
+	for _, i := range psms {
+
+		if hasDecoys == false && i.IsDecoy == true {
+			continue
+		}
+
+		name := strings.Split(i.Spectrum, "#")[0]
+		part := strings.Split(name, ".")
+
+		if _, ok := sourceMap[part[0]]; !ok {
+			sourceMap[part[0]] = make(map[string]PSMEvidenceList)
+			sources = append(sources, part[0])
+		}
+
+		i.Spectrum = name
+		sourceMap[part[0]][part[1]] = append(sourceMap[part[0]][part[1]], i)
+	}
+
+	sort.Strings(sources)
+
+	for _, s := range sourceMap {
+		for _, list := range s {
+			sort.Slice(list, func(a, b int) bool {
+				if list[a].HitRank != list[b].HitRank {
+					return list[a].HitRank < list[b].HitRank
+				}
+				return list[a].Spectrum < list[b].Spectrum
+			})
+		}
+	}
+
+	return sourceMap, sources
+}
+
+// findSpectraFile looks for the spectra file of a given source in the supported formats
+func findSpectraFile(dir, source string) string {
+
+	for _, i := range []string{"mzML", "mzXML", "mgf"} {
+		f := fmt.Sprintf("%s%s%s.%s", dir, string(filepath.Separator), source, i)
+		if _, e := os.Stat(f); e == nil {
+			return f
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,46 @@
+package rep
+
+import (
+	"testing"
+)
+
+func TestGroupPSMsByScan(t *testing.T) {
+
+	psms := PSMEvidenceList{
+		{Spectrum: "run.00010.00010.2#interact.pep.xml", Peptide: "SAMPLEK", HitRank: 2},
+		{Spectrum: "run.00010.00010.3#interact.pep.xml", Peptide: "PEPTIDEKR", HitRank: 1},
+		{Spectrum: "run.00010.00010.2#interact.pep.xml", Peptide: "PEPTIDEK", HitRank: 1},
+		{Spectrum: "run.00011.00011.2#interact.pep.xml", Peptide: "KEDITPEP", IsDecoy: true, HitRank: 1},
+		{Spectrum: "other.00010.00010.2#interact.pep.xml", Peptide: "MAGICK", HitRank: 1},
+	}
+
+	sourceMap, sources := groupPSMsByScan(psms, false)
+
+	if len(sources) != 2 || sources[0] != "other" || sources[1] != "run" {
+		t.Fatalf("sources = %v", sources)
+	}
+
+	if _, ok := sourceMap["run"]["00011"]; ok {
+		t.Errorf("decoy PSM exported")
+	}
+
+	list := sourceMap["run"]["00010"]
+
+	if len(list) != 3 {
+		t.Fatalf("got %d PSMs for scan 10, want 3", len(list))
+	}
+
+	for n, want := range []string{"PEPTIDEK", "PEPTIDEKR", "SAMPLEK"} {
+		if list[n].Peptide != want {
+			t.Errorf("PSM %d = %s, want %s", n, list[n].Peptide, want)
+		}
+	}
+
+	if list[0].Spectrum != "run.00010.00010.2" {
+		t.Errorf("spectrum name = %s", list[0].Spectrum)
+	}
+
+	if sourceMap, _ = groupPSMsByScan(psms, true); len(sourceMap["run"]["00011"]) != 1 {
+		t.Errorf("decoy PSM missing")
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
+	"philosopher/lib/dat"
 	"philosopher/lib/msg"
 
 	"philosopher/lib/obo"
@@ -276,7 +280,24 @@ func (evi *Evidence) ModificationReport() {
 	}
 	defer file.Close()
 
-	line := fmt.Sprintf("Mass Bin\tPSMs with Assigned Modifications\tPSMs with Observed Modifications\n")
+	// the known modification sites come from the UniProt annotation of the database
+	var dtb dat.Base
+	dtb.Restore()
+
+	var annotatedMap = make(map[string]dat.Record)
+	for _, i := range dtb.Records {
+		if i.IsDecoy == false && len(i.Annotation.PTMSites) > 0 {
+			annotatedMap[i.PartHeader] = i
+		}
+	}
+
+	line := "Mass Bin\tPSMs with Assigned Modifications\tPSMs with Observed Modifications"
+
+	if len(annotatedMap) > 0 {
+		line += "\tPSMs on Known Sites\tKnown Modifications"
+	}
+
+	line += "\n"
 
 	_, e = io.WriteString(file, line)
 	if e != nil {
@@ -291,6 +312,15 @@ func (evi *Evidence) ModificationReport() {
 			len(i.ObservedMods),
 		)
 
+		if len(annotatedMap) > 0 {
+			psms, known := knownModificationSites(i, annotatedMap)
+			line = fmt.Sprintf("%s\t%d\t%s",
+				line,
+				psms,
+				strings.Join(known, "; "),
+			)
+		}
+
 		line += "\n"
 		_, e = io.WriteString(file, line)
 		if e != nil {
@@ -305,6 +335,65 @@ func (evi *Evidence) ModificationReport() {
 	return
 }
 
+// knownModificationSites maps the assigned modifications of the bin to the protein sequences and
+// looks for the UniProt modification sites at the same positions. It returns the number of PSMs
+// with a modification on a known site and the site descriptions with their PSM counts
+func knownModificationSites(bin MassBin, annotatedMap map[string]dat.Record) (int, []string) {
+
+	var psms int
+	var descriptions = make(map[string]int)
+
+	for _, i := range bin.AssignedMods {
+
+		r, ok := annotatedMap[i.Protein]
+		if !ok || i.IsDecoy {
+			continue
+		}
+
+		start := strings.Index(r.Sequence, i.Peptide)
+		if start < 0 {
+			continue
+		}
+
+		// a PSM is counted once for each site description
+		var found = make(map[string]uint8)
+
+		for _, j := range i.Modifications.Index {
+
+			if j.Type != "Assigned" || j.MassDiff == 0 || j.MassDiff <= bin.LowerMass || j.MassDiff > bin.HigherRight {
+				continue
+			}
+
+			position, e := strconv.Atoi(j.Position)
+			if e != nil {
+				continue
+			}
+
+			for _, k := range r.Annotation.PTMSites {
+				if k.Begin == start+position {
+					found[k.Description] = 0
+				}
+			}
+		}
+
+		if len(found) > 0 {
+			psms++
+		}
+
+		for k := range found {
+			descriptions[k]++
+		}
+	}
+
+	var known []string
+	for k, v := range descriptions {
+		known = append(known, fmt.Sprintf("%s (%d)", k, v))
+	}
+	sort.Strings(known)
+
+	return psms, known
+}
+
 // PlotMassHist plots the delta mass histogram
 func (evi *Evidence) PlotMassHist() {
 
@@ -0,0 +1,60 @@
+package rep
+
+import (
+	"reflect"
+	"testing"
+
+	"philosopher/lib/dat"
+	"philosopher/lib/mod"
+)
+
+func TestKnownModificationSites(t *testing.T) {
+
+	annotatedMap := map[string]dat.Record{
+		"sp|P1|A_HUMAN": {
+			Sequence: "MKSPEPTIDEK",
+			Annotation: dat.Annotation{PTMSites: []dat.Feature{
+				{Description: "Phosphoserine", Begin: 3, End: 3},
+				{Description: "Phosphothreonine", Begin: 7, End: 7},
+				{Description: "N6-acetyllysine", Begin: 2, End: 2},
+			}},
+		},
+	}
+
+	phospho := func(aa, position string) mod.Modification {
+		return mod.Modification{Type: "Assigned", AminoAcid: aa, Position: position, MassDiff: 79.9663}
+	}
+
+	bin := MassBin{LowerMass: 79.95, HigherRight: 80.05, AssignedMods: PSMEvidenceList{
+		// S3 and T7 of the protein
+		{Protein: "sp|P1|A_HUMAN", Peptide: "SPEPTIDEK", Modifications: mod.Modifications{Index: map[string]mod.Modification{
+			"S#1#166.9984": phospho("S", "1"),
+			"T#5#181.0140": phospho("T", "5"),
+		}}},
+		// a phosphorylation on an unknown site, the known acetylation is in another bin
+		{Protein: "sp|P1|A_HUMAN", Peptide: "KSPEPTIDEK", Modifications: mod.Modifications{Index: map[string]mod.Modification{
+			"E#9#209.0090": phospho("E", "9"),
+			"K#1#170.1055": {Type: "Assigned", AminoAcid: "K", Position: "1", MassDiff: 42.0106},
+		}}},
+		{Protein: "sp|P1|A_HUMAN", Peptide: "SPEPTIDEK", Modifications: mod.Modifications{Index: map[string]mod.Modification{
+			"S#1#166.9984": phospho("S", "1"),
+		}}},
+		// decoys and unannotated proteins are ignored
+		{Protein: "rev_sp|P1|A_HUMAN", Peptide: "SPEPTIDEK", IsDecoy: true, Modifications: mod.Modifications{Index: map[string]mod.Modification{
+			"S#1#166.9984": phospho("S", "1"),
+		}}},
+		{Protein: "sp|P2|B_HUMAN", Peptide: "SAMPLEK", Modifications: mod.Modifications{Index: map[string]mod.Modification{
+			"S#1#166.9984": phospho("S", "1"),
+		}}},
+	}}
+
+	psms, known := knownModificationSites(bin, annotatedMap)
+
+	if psms != 2 {
+		t.Errorf("got %d PSMs on known sites, want 2", psms)
+	}
+
+	if want := []string{"Phosphoserine (2)", "Phosphothreonine (1)"}; !reflect.DeepEqual(known, want) {
+		t.Errorf("known modifications = %v, want %v", known, want)
+	}
+}
@@ -0,0 +1,68 @@
+package rep
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"philosopher/lib/msg"
+	"philosopher/lib/mzn"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MzMLReport writes one indexed mzML file per source file, containing only
+// the fragment spectra matched to the reported PSMs
+func (evi Evidence) MzMLReport(dir string, hasDecoys bool) {
+
+	// collect the scans of each source file
+	var sourceMap = make(map[string]map[string]uint8)
+	var sources []string
+
+	for _, i := range evi.PSM {
+
+		if hasDecoys == false && i.IsDecoy == true {
+			continue
+		}
+
+		part := strings.Split(strings.Split(i.Spectrum, "#")[0], ".")
+
+		if _, ok := sourceMap[part[0]]; !ok {
+			sourceMap[part[0]] = make(map[string]uint8)
+			sources = append(sources, part[0])
+		}
+
+		sourceMap[part[0]][part[1]] = 0
+	}
+
+	sort.Strings(sources)
+
+	for _, s := range sources {
+
+		f := fmt.Sprintf("%s%s%s.mzML", dir, string(filepath.Separator), s)
+		if _, e := os.Stat(f); e != nil {
+			msg.InputNotFound(errors.New("Cannot find the mzML file for "+s), "error")
+			continue
+		}
+
+		logrus.Info("Exporting spectra from ", filepath.Base(f))
+
+		output := fmt.Sprintf("%s%s%s.psm.mzML", sys.MetaDir(), string(filepath.Separator), s)
+
+		scans := sourceMap[s]
+
+		mzn.WriteMzML(f, output, mzn.SpectrumFilter{Levels: []string{"2"}}, func(spec mzn.Spectrum) bool {
+			_, ok := scans[fmt.Sprintf("%05s", spec.Scan)]
+			return ok
+		})
+
+		// copy to work directory
+		sys.CopyFile(output, filepath.Base(output))
+	}
+
+	return
+}
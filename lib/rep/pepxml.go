@@ -0,0 +1,323 @@
+package rep
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"philosopher/lib/dat"
+	"philosopher/lib/mod"
+	"philosopher/lib/msg"
+	"philosopher/lib/spc"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PepXMLReport writes the filtered PSMs as pepXML with their updated protein mappings
+// and analysis results, prophet is the analysis that produced the PSM probabilities
+func (evi Evidence) PepXMLReport(searchEngine, database, prophet string, hasDecoys, hasLoc bool) {
+
+	output := fmt.Sprintf("%s%spsm.pepXML", sys.MetaDir(), string(filepath.Separator))
+
+	// collect database information
+	var dtb dat.Base
+	dtb.Restore()
+
+	var proteinDescription = make(map[string]string)
+	for _, j := range dtb.Records {
+		proteinDescription[j.PartHeader] = j.Description
+	}
+
+	t := time.Now()
+
+	var mpa spc.MsmsPipelineAnalysis
+	mpa.Date = []byte(t.Format(time.RFC3339))
+	mpa.SummaryXML = []byte(filepath.Base(output))
+
+	// the Percolator and mokapot probabilities are written as PeptideProphet results, the
+	// pepXML schema has no element for them
+	mpa.AnalysisSummary = append(mpa.AnalysisSummary, spc.AnalysisSummary{Analysis: []byte("peptideprophet"), Time: mpa.Date})
+	if prophet == "interprophet" {
+		mpa.AnalysisSummary = append(mpa.AnalysisSummary, spc.AnalysisSummary{Analysis: []byte("interprophet"), Time: mpa.Date})
+	}
+	if hasLoc == true {
+		mpa.AnalysisSummary = append(mpa.AnalysisSummary, spc.AnalysisSummary{Analysis: []byte("ptmprophet"), Time: mpa.Date})
+	}
+
+	ss := pepXMLSearchSummary(evi.Mods.Index, searchEngine, database)
+
+	// the PSMs are grouped by source file, and the hits of a spectrum in a single query
+	var runMap = make(map[string]*spc.MsmsRunSummary)
+	var queries = make(map[string]int)
+	var sources []string
+
+	for _, i := range evi.PSM {
+
+		if hasDecoys == false && i.IsDecoy == true {
+			continue
+		}
+
+		name := strings.Split(i.Spectrum, "#")[0]
+		source := strings.Split(name, ".")[0]
+
+		run, ok := runMap[source]
+		if !ok {
+			run = &spc.MsmsRunSummary{BaseName: []byte(source)}
+			run.SearchSummary = ss
+			run.SearchSummary.BaseName = []byte(source)
+			runMap[source] = run
+			sources = append(sources, source)
+		}
+
+		hit := pepXMLSearchHit(i, prophet, hasLoc, proteinDescription)
+
+		if q, ok := queries[name]; ok {
+			run.SpectrumQuery[q].SearchResult.SearchHit = append(run.SpectrumQuery[q].SearchResult.SearchHit, hit)
+			continue
+		}
+
+		sq := pepXMLSpectrumQuery(i, name)
+		sq.SearchResult.SearchHit = append(sq.SearchResult.SearchHit, hit)
+
+		queries[name] = len(run.SpectrumQuery)
+		run.SpectrumQuery = append(run.SpectrumQuery, sq)
+	}
+
+	sort.Strings(sources)
+
+	var runs []spc.MsmsRunSummary
+	for _, i := range sources {
+
+		run := runMap[i]
+
+		for _, j := range run.SpectrumQuery {
+			hits := j.SearchResult.SearchHit
+			sort.SliceStable(hits, func(a, b int) bool { return hits[a].HitRank < hits[b].HitRank })
+		}
+
+		runs = append(runs, *run)
+	}
+
+	e := spc.WritePepXML(output, mpa, runs)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	logrus.Info("Exporting PSMs to ", filepath.Base(output))
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
+
+// pepXMLSearchSummary lists the search engine, the database and the assigned modifications
+func pepXMLSearchSummary(mods map[string]mod.Modification, searchEngine, database string) spc.SearchSummary {
+
+	var ss spc.SearchSummary
+
+	ss.SearchEngine = []byte(searchEngine)
+	ss.SearchDatabase.LocalPath = []byte(database)
+
+	var keys []string
+	for k, v := range mods {
+		if v.Type == "Assigned" {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+
+		m := mods[k]
+
+		if len(m.Terminus) > 0 {
+			ss.TerminalModifications = append(ss.TerminalModifications, spc.TerminalModification{
+				Terminus:        []byte(m.Terminus),
+				MassDiff:        m.MassDiff,
+				Mass:            m.MonoIsotopicMass,
+				Variable:        []byte(m.Variable),
+				ProteinTerminus: []byte(m.IsProteinTerminus),
+			})
+		} else {
+			ss.AminoAcidModifications = append(ss.AminoAcidModifications, spc.AminoacidModification{
+				AminoAcid: []byte(m.AminoAcid),
+				MassDiff:  m.MassDiff,
+				Mass:      m.MonoIsotopicMass,
+				Variable:  []byte(m.Variable),
+			})
+		}
+	}
+
+	return ss
+}
+
+// pepXMLSpectrumQuery converts the spectrum information of a PSM into a spectrum query
+func pepXMLSpectrumQuery(i PSMEvidence, name string) spc.SpectrumQuery {
+
+	return spc.SpectrumQuery{
+		Spectrum:                         []byte(name),
+		StartScan:                        i.Scan,
+		EndScan:                          i.Scan,
+		UncalibratedPrecursorNeutralMass: i.UncalibratedPrecursorNeutralMass,
+		PrecursorNeutralMass:             i.PrecursorNeutralMass,
+		AssumedCharge:                    i.AssumedCharge,
+		Index:                            i.Index,
+		RetentionTimeSec:                 i.RetentionTime,
+		IonMobility:                      i.IonMobility,
+	}
+}
+
+// pepXMLSearchHit converts a PSM into a search hit
+func pepXMLSearchHit(i PSMEvidence, prophet string, hasLoc bool, proteinDescription map[string]string) spc.SearchHit {
+
+	hit := spc.SearchHit{
+		HitRank:            i.HitRank,
+		Peptide:            []byte(i.Peptide),
+		PrevAA:             []byte(i.PrevAA),
+		NextAA:             []byte(i.NextAA),
+		Protein:            []byte(i.Protein),
+		ProteinDescr:       []byte(i.ProteinDescription),
+		CalcNeutralPepMass: i.CalcNeutralPepMass,
+		Massdiff:           i.Massdiff,
+		TotalTerm:          uint8(i.NumberOfEnzymaticTermini),
+		MissedCleavages:    uint8(i.NumberOfMissedCleavages),
+	}
+
+	if hit.HitRank == 0 {
+		hit.HitRank = 1
+	}
+
+	var alternatives []string
+	for j := range i.MappedProteins {
+		if j != i.Protein && len(j) > 0 {
+			alternatives = append(alternatives, j)
+		}
+	}
+
+	sort.Strings(alternatives)
+
+	for _, j := range alternatives {
+		hit.AlternativeProteins = append(hit.AlternativeProteins, spc.AlternativeProtein{
+			Protein:     []byte(j),
+			Description: []byte(proteinDescription[j]),
+		})
+	}
+
+	hit.TotalProteins = uint16(len(alternatives) + 1)
+
+	hit.ModificationInfo = pepXMLModificationInfo(i)
+
+	for _, j := range []struct {
+		name  string
+		value float64
+	}{
+		{"expect", i.Expectation},
+		{"hyperscore", i.Hyperscore},
+		{"nextscore", i.Nextscore},
+		{"xcorr", i.Xcorr},
+		{"deltacn", i.DeltaCN},
+		{"deltacnstar", i.DeltaCNStar},
+		{"spscore", i.SPScore},
+		{"sprank", i.SPRank},
+	} {
+		if j.value != 0 {
+			hit.Score = append(hit.Score, spc.SearchScore{Name: []byte(j.name), Value: strconv.FormatFloat(j.value, 'g', -1, 64)})
+		}
+	}
+
+	hit.AnalysisResult = pepXMLAnalysisResults(i, prophet, hasLoc)
+
+	return hit
+}
+
+// pepXMLAnalysisResults lists the results of every analysis that validated the PSM. iProphet
+// results keep the PeptideProphet result they were computed from
+func pepXMLAnalysisResults(i PSMEvidence, prophet string, hasLoc bool) []spc.AnalysisResult {
+
+	var results []spc.AnalysisResult
+
+	peptideProphet := spc.AnalysisResult{Analysis: []byte("peptideprophet")}
+	peptideProphet.PeptideProphetResult.Probability = i.Probability
+	if prophet == "interprophet" {
+		peptideProphet.PeptideProphetResult.Probability = i.PeptideProphetProbability
+	}
+	results = append(results, peptideProphet)
+
+	if prophet == "interprophet" {
+		interProphet := spc.AnalysisResult{Analysis: []byte("interprophet")}
+		interProphet.InterProphetResult.Probability = i.InterProphetProbability
+		results = append(results, interProphet)
+	}
+
+	if hasLoc == true && len(i.LocalizedPTMMassDiff) > 0 {
+
+		var ptms []string
+		for k := range i.LocalizedPTMMassDiff {
+			ptms = append(ptms, k)
+		}
+
+		sort.Strings(ptms)
+
+		ptm := spc.AnalysisResult{Analysis: []byte("ptmprophet")}
+		for _, k := range ptms {
+
+			result := spc.PTMProphetResult{
+				PTM:        []byte(k),
+				PTMPeptide: []byte(i.LocalizedPTMMassDiff[k]),
+			}
+
+			for position, probability := range i.LocalizedPTMProbabilities[k] {
+				result.ModAminoAcidProbability = append(result.ModAminoAcidProbability, spc.ModAminoAcidProbability{
+					Position:    position,
+					Probability: float32(probability),
+				})
+			}
+
+			sort.Slice(result.ModAminoAcidProbability, func(a, b int) bool {
+				return result.ModAminoAcidProbability[a].Position < result.ModAminoAcidProbability[b].Position
+			})
+
+			ptm.PTMProphetResult = append(ptm.PTMProphetResult, result)
+		}
+		results = append(results, ptm)
+	}
+
+	return results
+}
+
+// pepXMLModificationInfo lists the assigned modifications of a PSM
+func pepXMLModificationInfo(i PSMEvidence) spc.ModificationInfo {
+
+	var m spc.ModificationInfo
+
+	for _, j := range i.Modifications.Index {
+
+		if j.Type != "Assigned" {
+			continue
+		}
+
+		if j.AminoAcid == "N-term" {
+			m.ModNTermMass = j.MonoIsotopicMass
+		} else if j.AminoAcid == "C-term" {
+			m.ModCTermMass = j.MonoIsotopicMass
+		} else if position, e := strconv.Atoi(j.Position); e == nil && position > 0 {
+			m.ModAminoacidMass = append(m.ModAminoacidMass, spc.ModAminoacidMass{Position: position, Mass: j.MonoIsotopicMass})
+		}
+	}
+
+	sort.Slice(m.ModAminoacidMass, func(a, b int) bool {
+		return m.ModAminoacidMass[a].Position < m.ModAminoacidMass[b].Position
+	})
+
+	if len(m.ModAminoacidMass) > 0 || m.ModNTermMass != 0 || m.ModCTermMass != 0 {
+		m.ModifiedPeptide = []byte(i.ModifiedPeptide)
+	}
+
+	return m
+}
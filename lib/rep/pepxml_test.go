@@ -0,0 +1,49 @@
+package rep
+
+import (
+	"testing"
+)
+
+func TestPepXMLAnalysisResults(t *testing.T) {
+
+	psm := PSMEvidence{
+		Probability:               0.99,
+		PeptideProphetProbability: 0.95,
+		InterProphetProbability:   0.99,
+		LocalizedPTMMassDiff:      map[string]string{"STY:79.9663": "MS(0.75)T(0.25)YK"},
+		LocalizedPTMProbabilities: map[string]map[int]float64{"STY:79.9663": {3: 0.25, 2: 0.75}},
+	}
+
+	// iProphet results keep the PeptideProphet result
+	results := pepXMLAnalysisResults(psm, "interprophet", true)
+
+	if len(results) != 3 {
+		t.Fatalf("got %d analysis results, want 3", len(results))
+	}
+
+	if string(results[0].Analysis) != "peptideprophet" || results[0].PeptideProphetResult.Probability != 0.95 {
+		t.Errorf("peptideprophet result = %+v", results[0])
+	}
+
+	if string(results[1].Analysis) != "interprophet" || results[1].InterProphetResult.Probability != 0.99 {
+		t.Errorf("interprophet result = %+v", results[1])
+	}
+
+	ptm := results[2].PTMProphetResult
+	if string(results[2].Analysis) != "ptmprophet" || len(ptm) != 1 || string(ptm[0].PTMPeptide) != "MS(0.75)T(0.25)YK" {
+		t.Fatalf("ptmprophet result = %+v", results[2])
+	}
+
+	sites := ptm[0].ModAminoAcidProbability
+	if len(sites) != 2 || sites[0].Position != 2 || sites[0].Probability != 0.75 || sites[1].Position != 3 || sites[1].Probability != 0.25 {
+		t.Errorf("mod_aminoacid_probability = %+v", sites)
+	}
+
+	// the Percolator probabilities are written as PeptideProphet results
+	psm.Probability = 0.8
+	results = pepXMLAnalysisResults(psm, "percolator", false)
+
+	if len(results) != 1 || string(results[0].Analysis) != "peptideprophet" || results[0].PeptideProphetResult.Probability != 0.8 {
+		t.Errorf("percolator results = %+v", results)
+	}
+}
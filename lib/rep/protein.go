@@ -201,6 +201,9 @@ func (evi *Evidence) AssembleProteinReport(pro id.ProtIDList, weight float64, de
 					list[i].Sequence = j.Sequence
 					list[i].ProteinName = j.ProteinName
 					list[i].Organism = j.Organism
+					list[i].Annotation = j.Annotation
+					list[i].IsContaminant = j.IsContaminant
+					list[i].Aliases = j.Aliases
 
 					// uniprot entries have the description on ProteinName
 					if len(j.Description) < 1 {
@@ -253,8 +256,34 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 		}
 	}
 
+	// the UniProt annotation is only available when the database command received an annotation file
+	var hasAnnotation bool
+	for _, i := range printSet {
+		if len(i.Annotation.GO) > 0 || len(i.Annotation.Keywords) > 0 || len(i.Annotation.PTMSites) > 0 || len(i.Annotation.SignalPeptides) > 0 || len(i.Annotation.Chains) > 0 {
+			hasAnnotation = true
+			break
+		}
+	}
+
+	// aliases are the database entries collapsed into the reported protein
+	var hasAliases bool
+	for _, i := range printSet {
+		if len(i.Aliases) > 0 {
+			hasAliases = true
+			break
+		}
+	}
+
 	header = fmt.Sprintf("Group\tSubGroup\tProtein\tProtein ID\tEntry Name\tGene\tLength\tPercent Coverage\tOrganism\tProtein Description\tProtein Existence\tProtein Probability\tTop Peptide Probability\tStripped Peptides\tTotal Peptide Ions\tUnique Peptide Ions\tRazor Peptide Ions\tTotal Spectral Count\tUnique Spectral Count\tRazor Spectral Count\tTotal Intensity\tUnique Intensity\tRazor Intensity\tRazor Assigned Modifications\tRazor Observed Modifications\tIndistinguishable Proteins")
 
+	if hasAliases == true {
+		header += "\tProtein Aliases"
+	}
+
+	if hasAnnotation == true {
+		header += "\tGO Terms\tKeywords\tKnown Modification Sites\tSignal Peptide\tChains"
+	}
+
 	if brand == "tmt" {
 		switch channels {
 		case 6:
@@ -429,6 +458,24 @@ func (evi Evidence) MetaProteinReport(brand string, channels int, hasDecoys, has
 			strings.Join(ip, ", "),   // Indistinguishable Proteins
 		)
 
+		if hasAliases == true {
+			line = fmt.Sprintf("%s\t%s",
+				line,
+				strings.Join(i.Aliases, ", "), // Protein Aliases
+			)
+		}
+
+		if hasAnnotation == true {
+			line = fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s",
+				line,
+				strings.Join(i.Annotation.GO, "; "), // GO Terms
+				strings.Join(i.Annotation.Keywords, "; "),                        // Keywords
+				strings.Join(i.Annotation.PTMSiteList(i.Sequence, 0, 0), "; "),   // Known Modification Sites
+				strings.Join(dat.FeatureList(i.Annotation.SignalPeptides), "; "), // Signal Peptide
+				strings.Join(dat.FeatureList(i.Annotation.Chains), "; "),         // Chains
+			)
+		}
+
 		switch channels {
 		case 4:
 			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f",
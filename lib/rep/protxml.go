@@ -0,0 +1,255 @@
+package rep
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"philosopher/lib/dat"
+	"philosopher/lib/msg"
+	"philosopher/lib/spc"
+	"philosopher/lib/sys"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ProtXMLReport writes the filtered protein groups as protXML with their indistinguishable
+// proteins and peptide weights. The protein inference of the filter does not group the
+// proteins, so without a protXML input each protein is written as its own group
+func (evi Evidence) ProtXMLReport(version, database string, hasDecoys, hasGroups bool) {
+
+	output := fmt.Sprintf("%s%sprotein.protXML", sys.MetaDir(), string(filepath.Separator))
+
+	// collect database information
+	var dtb dat.Base
+	dtb.Restore()
+
+	var proteinDescription = make(map[string]string)
+	for _, j := range dtb.Records {
+		proteinDescription[j.PartHeader] = j.Description
+	}
+
+	var ps spc.ProteinSummary
+
+	ps.ProteinSummaryHeader.ReferenceDatabase = []byte(database)
+	ps.ProteinSummaryHeader.ProgramDetails.Analysis = []byte("proteinprophet")
+	ps.ProteinSummaryHeader.ProgramDetails.Time = []byte(time.Now().Format(time.RFC3339))
+	ps.ProteinSummaryHeader.ProgramDetails.Version = []byte("Philosopher " + version)
+
+	var spectra int
+	ps.ProteinGroup, spectra = protXMLGroups(evi.Proteins, proteinDescription, hasDecoys, hasGroups)
+
+	ps.ProteinSummaryHeader.TotalNumberSpectrumIDs = float32(spectra)
+
+	e := spc.WriteProtXML(output, ps)
+	if e != nil {
+		msg.WriteFile(e, "fatal")
+	}
+
+	logrus.Info("Exporting protein groups to ", filepath.Base(output))
+
+	// copy to work directory
+	sys.CopyFile(output, filepath.Base(output))
+
+	return
+}
+
+// protXMLGroups builds the protein groups and counts the spectra supporting them. The proteins
+// are grouped by their group number when the groups come from a protXML file, otherwise each
+// protein and its indistinguishable proteins are a group, numbered by decreasing probability
+func protXMLGroups(proteins ProteinEvidenceList, proteinDescription map[string]string, hasDecoys, hasGroups bool) ([]spc.ProteinGroup, int) {
+
+	var list ProteinEvidenceList
+	for _, i := range proteins {
+		if hasDecoys == false && i.IsDecoy == true {
+			continue
+		}
+		list = append(list, i)
+	}
+
+	if hasGroups == false {
+		sort.SliceStable(list, func(a, b int) bool {
+			if list[a].Probability != list[b].Probability {
+				return list[a].Probability > list[b].Probability
+			}
+			return list[a].PartHeader < list[b].PartHeader
+		})
+
+		for i := range list {
+			list[i].ProteinGroup = uint32(i + 1)
+			list[i].ProteinSubGroup = "a"
+		}
+	}
+
+	var groupMap = make(map[uint32]*spc.ProteinGroup)
+	var groupNumbers []uint32
+	var spectra = make(map[string]uint8)
+
+	for _, i := range list {
+
+		group, ok := groupMap[i.ProteinGroup]
+		if !ok {
+			group = &spc.ProteinGroup{GroupNumber: i.ProteinGroup}
+			groupMap[i.ProteinGroup] = group
+			groupNumbers = append(groupNumbers, i.ProteinGroup)
+		}
+
+		// the group probability is the one of its best protein
+		if i.Probability > group.Probability {
+			group.Probability = i.Probability
+		}
+
+		group.Protein = append(group.Protein, protXMLProtein(i, proteinDescription))
+
+		for j := range i.SupportingSpectra {
+			spectra[j] = 0
+		}
+	}
+
+	sort.Slice(groupNumbers, func(i, j int) bool { return groupNumbers[i] < groupNumbers[j] })
+
+	var groups []spc.ProteinGroup
+
+	for _, i := range groupNumbers {
+
+		group := groupMap[i]
+
+		sort.SliceStable(group.Protein, func(a, b int) bool {
+			return string(group.Protein[a].GroupSiblingID) < string(group.Protein[b].GroupSiblingID)
+		})
+
+		groups = append(groups, *group)
+	}
+
+	return groups, len(spectra)
+}
+
+// protXMLProtein converts a protein and its peptide ions into a protXML protein entry
+func protXMLProtein(i ProteinEvidence, proteinDescription map[string]string) spc.Protein {
+
+	name := i.PartHeader
+	if len(name) == 0 {
+		name = i.ProteinName
+	}
+
+	p := spc.Protein{
+		ProteinName:     []byte(name),
+		Probability:     i.Probability,
+		PercentCoverage: i.Coverage,
+		GroupSiblingID:  []byte(i.ProteinSubGroup),
+		TopPepProb:      i.TopPepProb,
+	}
+
+	p.Annotation.ProteinDescription = []byte(i.Description)
+
+	if i.Length > 0 {
+		p.Parameter = spc.Parameter{Name: "prot_length", Value: strconv.Itoa(i.Length)}
+	}
+
+	var indistinguishable []string
+	for j := range i.IndiProtein {
+		if j != name && len(j) > 0 {
+			indistinguishable = append(indistinguishable, j)
+		}
+	}
+
+	sort.Strings(indistinguishable)
+
+	for _, j := range indistinguishable {
+		p.IndistinguishableProtein = append(p.IndistinguishableProtein, spc.IndistinguishableProtein{
+			ProteinName: j,
+			Annotation:  spc.Annotation{ProteinDescription: []byte(proteinDescription[j])},
+		})
+	}
+
+	p.NumberIndistinguishableProteins = int16(len(indistinguishable) + 1)
+
+	var ions []string
+	for j := range i.TotalPeptideIons {
+		ions = append(ions, j)
+	}
+
+	sort.Strings(ions)
+
+	var stripped = make(map[string]uint8)
+	var instances int
+
+	for _, j := range ions {
+
+		pep := protXMLPeptide(i.TotalPeptideIons[j], name)
+
+		stripped[string(pep.PeptideSequence)] = 0
+		instances += pep.NIstances
+
+		p.Peptide = append(p.Peptide, pep)
+	}
+
+	var sequences []string
+	for j := range stripped {
+		sequences = append(sequences, j)
+	}
+
+	sort.Strings(sequences)
+
+	p.UniqueStrippedPeptides = []byte(strings.Join(sequences, "+"))
+	p.TotalNumberIndPeptides = len(sequences)
+	p.TotalNumberPeptides = instances
+
+	return p
+}
+
+// protXMLPeptide converts a peptide ion of a protein, the ions counted towards the protein
+// as unique or razor are reported as contributing evidence
+func protXMLPeptide(i IonEvidence, protein string) spc.Peptide {
+
+	p := spc.Peptide{
+		PeptideSequence:         []byte(i.Sequence),
+		Charge:                  i.ChargeState,
+		InitialProbability:      i.Probability,
+		Weight:                  i.Weight,
+		GroupWeight:             i.GroupWeight,
+		NEnzymaticTermini:       i.NumberOfEnzymaticTermini,
+		NIstances:               len(i.Spectra),
+		CalcNeutralPepMass:      i.PeptideMass,
+		IsNondegenerateEvidence: []byte("N"),
+		IsContributingEvidence:  []byte("N"),
+	}
+
+	// ions assembled from the protein inference only carry the mass in their key
+	if p.CalcNeutralPepMass == 0 {
+		parts := strings.Split(i.IonForm, "#")
+		if len(parts) == 3 {
+			p.CalcNeutralPepMass, _ = strconv.ParseFloat(parts[2], 64)
+		}
+	}
+
+	if i.IsUnique == true {
+		p.IsNondegenerateEvidence = []byte("Y")
+	}
+
+	if i.IsUnique == true || i.IsURazor == true {
+		p.IsContributingEvidence = []byte("Y")
+	}
+
+	var parents []string
+	for j := range i.MappedProteins {
+		if j != protein && len(j) > 0 {
+			parents = append(parents, j)
+		}
+	}
+
+	sort.Strings(parents)
+
+	for _, j := range parents {
+		p.PeptideParentProtein = append(p.PeptideParentProtein, spc.PeptideParentProtein{ProteinName: []byte(j)})
+	}
+
+	if len(i.ModifiedSequence) > 0 && i.ModifiedSequence != i.Sequence {
+		p.ModificationInfo.ModifiedPeptide = []byte(i.ModifiedSequence)
+	}
+
+	return p
+}
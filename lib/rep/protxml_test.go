@@ -0,0 +1,52 @@
+package rep
+
+import (
+	"testing"
+)
+
+func TestProtXMLGroups(t *testing.T) {
+
+	// the protein inference of the filter sets the same group and sibling on every protein
+	proteins := ProteinEvidenceList{
+		{PartHeader: "sp|P1|A_HUMAN", Probability: 0.9, ProteinGroup: 0, ProteinSubGroup: "a", SupportingSpectra: map[string]int{"run.00010.00010.2": 0}},
+		{PartHeader: "sp|P2|B_HUMAN", Probability: 0.99, ProteinGroup: 0, ProteinSubGroup: "a", SupportingSpectra: map[string]int{"run.00011.00011.2": 0},
+			IndiProtein: map[string]uint8{"sp|P3|C_HUMAN": 0}},
+		{PartHeader: "rev_sp|P4|D_HUMAN", Probability: 0.5, ProteinGroup: 0, ProteinSubGroup: "a", IsDecoy: true, SupportingSpectra: map[string]int{"run.00012.00012.2": 0}},
+	}
+
+	groups, spectra := protXMLGroups(proteins, nil, false, false)
+
+	if len(groups) != 2 || spectra != 2 {
+		t.Fatalf("got %d groups and %d spectra, want 2 and 2", len(groups), spectra)
+	}
+
+	for n, want := range []string{"sp|P2|B_HUMAN", "sp|P1|A_HUMAN"} {
+		g := groups[n]
+		if g.GroupNumber != uint32(n+1) || len(g.Protein) != 1 || string(g.Protein[0].ProteinName) != want || string(g.Protein[0].GroupSiblingID) != "a" {
+			t.Errorf("group %d = %d %+v", n, g.GroupNumber, g.Protein)
+		}
+	}
+
+	if groups[0].Probability != 0.99 || groups[1].Probability != 0.9 {
+		t.Errorf("group probabilities = %v %v", groups[0].Probability, groups[1].Probability)
+	}
+
+	if len(groups[0].Protein[0].IndistinguishableProtein) != 1 || groups[0].Protein[0].NumberIndistinguishableProteins != 2 {
+		t.Errorf("indistinguishable proteins = %+v", groups[0].Protein[0].IndistinguishableProtein)
+	}
+
+	// the groups and siblings of a protXML input are kept
+	proteins[0].ProteinGroup, proteins[0].ProteinSubGroup = 7, "b"
+	proteins[1].ProteinGroup, proteins[1].ProteinSubGroup = 7, "a"
+	proteins[2].ProteinGroup = 8
+
+	groups, spectra = protXMLGroups(proteins, nil, true, true)
+
+	if len(groups) != 2 || spectra != 3 || groups[0].GroupNumber != 7 || len(groups[0].Protein) != 2 || groups[0].Probability != 0.99 {
+		t.Fatalf("protXML groups = %+v", groups)
+	}
+
+	if string(groups[0].Protein[0].ProteinName) != "sp|P2|B_HUMAN" || string(groups[0].Protein[1].GroupSiblingID) != "b" {
+		t.Errorf("siblings = %s %s", groups[0].Protein[0].ProteinName, groups[0].Protein[1].GroupSiblingID)
+	}
+}
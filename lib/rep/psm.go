@@ -60,7 +60,10 @@ func (evi *Evidence) AssemblePSMReport(pep id.PepIDList, decoyTag string) {
 		p.Massdiff = i.Massdiff
 		p.LocalizedPTMSites = i.LocalizedPTMSites
 		p.LocalizedPTMMassDiff = i.LocalizedPTMMassDiff
+		p.LocalizedPTMProbabilities = i.LocalizedPTMProbabilities
 		p.Probability = i.Probability
+		p.PeptideProphetProbability = i.PeptideProphetProbability
+		p.InterProphetProbability = i.InterProphetProbability
 		p.Expectation = i.Expectation
 		p.Xcorr = i.Xcorr
 		p.DeltaCN = i.DeltaCN
@@ -145,13 +148,54 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 		}
 	}
 
+	// the apex ion mobility is only available after an ion mobility aware quantification
+	var hasApexMobility bool
+	for _, i := range printSet {
+		if i.ApexIonMobility > 0 {
+			hasApexMobility = true
+			break
+		}
+	}
+
+	// variant sites come from the variant entries of the database
+	var hasVariants bool
+	for _, i := range printSet {
+		if len(i.Variants) > 0 {
+			hasVariants = true
+			break
+		}
+	}
+
+	// lower-ranked hits are only kept by the filter with a policy for secondary identifications
+	var hasRanks bool
+	for _, i := range printSet {
+		if i.HitRank > 1 {
+			hasRanks = true
+			break
+		}
+	}
+
 	header = "Spectrum\tSpectrum File\tPeptide\tModified Peptide\tPeptide Length\tCharge\tRetention\tObserved Mass\tCalibrated Observed Mass\tObserved M/Z\tCalibrated Observed M/Z\tCalculated Peptide Mass\tCalculated M/Z\tDelta Mass"
 
+	if hasRanks == true {
+		header += "\tHit Rank"
+	}
+
 	if isComet == true {
 		header += "\tXCorr\tDeltaCN\tDeltaCNStar\tSPScore\tSPRank"
 	}
 
-	header += "\tExpectation\tHyperscore\tNextscore\tPeptideProphet Probability\tNumber of Enzymatic Termini\tNumber of Missed Cleavages\tIntensity\tIon Mobility\tAssigned Modifications\tObserved Modifications"
+	header += "\tExpectation\tHyperscore\tNextscore\tPeptideProphet Probability\tNumber of Enzymatic Termini\tNumber of Missed Cleavages\tIntensity\tIon Mobility"
+
+	if hasApexMobility == true {
+		header += "\tApex Ion Mobility"
+	}
+
+	header += "\tAssigned Modifications\tObserved Modifications"
+
+	if hasVariants == true {
+		header += "\tVariants"
+	}
 
 	if hasLoc == true {
 		header += "\tNumber of Phospho Sites\tPhospho Site Localization"
@@ -275,6 +319,13 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			i.Massdiff,
 		)
 
+		if hasRanks == true {
+			line = fmt.Sprintf("%s\t%d",
+				line,
+				i.HitRank,
+			)
+		}
+
 		if isComet == true {
 			line = fmt.Sprintf("%s\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f",
 				line,
@@ -286,7 +337,7 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			)
 		}
 
-		line = fmt.Sprintf("%s\t%.14f\t%.4f\t%.4f\t%.4f\t%d\t%d\t%.4f\t%.4f\t%s\t%s",
+		line = fmt.Sprintf("%s\t%.14f\t%.4f\t%.4f\t%.4f\t%d\t%d\t%.4f\t%.4f",
 			line,
 			i.Expectation,
 			i.Hyperscore,
@@ -296,10 +347,28 @@ func (evi Evidence) MetaPSMReport(brand string, channels int, hasDecoys, isComet
 			i.NumberOfMissedCleavages,
 			i.Intensity,
 			i.IonMobility,
+		)
+
+		if hasApexMobility == true {
+			line = fmt.Sprintf("%s\t%.4f",
+				line,
+				i.ApexIonMobility,
+			)
+		}
+
+		line = fmt.Sprintf("%s\t%s\t%s",
+			line,
 			strings.Join(assL, ", "),
 			strings.Join(obs, ", "),
 		)
 
+		if hasVariants == true {
+			line = fmt.Sprintf("%s\t%s",
+				line,
+				strings.Join(i.Variants, "; "),
+			)
+		}
+
 		if hasLoc == true {
 
 			var sites int
@@ -495,94 +564,3 @@ func (evi *Evidence) PSMLocalizationReport(decoyTag string, hasRazor, hasDecoys
 
 	return
 }
-
-// PepXMLReport report PSMs in pep.xml format
-// func (evi *Evidence) PepXMLReport() {
-
-// 	// collect database information
-// 	var dtb dat.Base
-// 	dtb.Restore()
-
-// 	var proteinDescription = make(map[string]string)
-// 	for _, j := range dtb.Records {
-// 		proteinDescription[j.PartHeader] = j.Description
-// 	}
-
-// 	t := time.Now()
-
-// 	// collect source file names
-// 	var sourceMap = make(map[string]uint8)
-// 	var sources []string
-// 	for _, i := range evi.PSM {
-// 		s := strings.Split(i.Spectrum, ".")
-// 		sourceMap[s[0]]++
-// 	}
-
-// 	for i := range sourceMap {
-// 		sources = append(sources, i)
-// 	}
-
-// 	sort.Strings(sources)
-
-// 	var p spc.PepXML
-
-// 	p.MsmsPipelineAnalysis.Date = t.Format(time.ANSIC)
-
-// 	as := &spc.AnalysisSummary{
-// 		Analysis: "philosopher",
-// 		Time:     t.Format(time.ANSIC),
-// 	}
-// 	p.MsmsPipelineAnalysis.AnalysisSummary = append(p.MsmsPipelineAnalysis.AnalysisSummary, *as)
-
-// 	for _, i := range evi.PSM {
-
-// 		spectrumName := strings.Split(i.Spectrum, "#")
-
-// 		sq := &spc.SpectrumQuery{
-// 			StartScan:            i.Scan,
-// 			EndScan:              i.Scan,
-// 			AssumedCharge:        i.AssumedCharge,
-// 			Spectrum:             spectrumName[0],
-// 			Index:                i.Index,
-// 			PrecursorNeutralMass: i.PrecursorNeutralMass,
-// 			RetentionTimeSec:     i.RetentionTime,
-// 			PrecursorIntensity:   i.Intensity,
-// 			SearchResult: spc.SearchResult{
-// 				SearchHit: []spc.SearchHit{
-// 					{
-// 						Peptide:            i.Peptide,
-// 						Massdiff:           i.Massdiff,
-// 						CalcNeutralPepMass: i.CalcNeutralPepMass,
-// 						NextAA:             i.NextAA,
-// 						PrevAA:             i.PrevAA,
-// 						IsRejected:         0,
-// 						ProteinDescr:       i.ProteinDescription,
-// 						HitRank:            i.HitRank,
-// 						Protein:            i.Protein,
-// 						AnalysisResult: []spc.AnalysisResult{
-// 							{
-// 								Analysis: "peptideprophet",
-// 								PeptideProphetResult: spc.PeptideProphetResult{
-// 									Probability: i.Probability,
-// 								},
-// 							},
-// 						},
-// 					},
-// 				},
-// 			},
-// 		}
-
-// 		for j := range i.MappedProteins {
-// 			ap := &spc.AlternativeProtein{
-// 				Protein:     j,
-// 				Description: proteinDescription[j],
-// 			}
-// 			sq.SearchResult.SearchHit[0].AlternativeProteins = append(sq.SearchResult.SearchHit[0].AlternativeProteins, *ap)
-// 		}
-
-// 		p.MsmsPipelineAnalysis.MsmsRunSummary.SpectrumQuery = append(p.MsmsPipelineAnalysis.MsmsRunSummary.SpectrumQuery, *sq)
-// 	}
-
-// 	p.Write()
-
-// }
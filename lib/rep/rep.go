@@ -1,13 +1,17 @@
 package rep
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
+	"philosopher/lib/dat"
 	"philosopher/lib/id"
 	"philosopher/lib/iso"
 	"philosopher/lib/met"
 	"philosopher/lib/mod"
+	"philosopher/lib/msg"
 
 	"github.com/sirupsen/logrus"
 )
@@ -138,7 +142,10 @@ type PSMEvidence struct {
 	Massdiff                         float64
 	LocalizedPTMSites                map[string]int
 	LocalizedPTMMassDiff             map[string]string
+	LocalizedPTMProbabilities        map[string]map[int]float64
 	Probability                      float64
+	PeptideProphetProbability        float64
+	InterProphetProbability          float64
 	Expectation                      float64
 	Xcorr                            float64
 	DeltaCN                          float64
@@ -150,12 +157,14 @@ type PSMEvidence struct {
 	DiscriminantValue                float64
 	Intensity                        float64
 	IonMobility                      float64
+	ApexIonMobility                  float64
 	Purity                           float64
 	IsDecoy                          bool
 	IsUnique                         bool
 	IsURazor                         bool
 	Labels                           iso.Labels
 	Modifications                    mod.Modifications
+	Variants                         []string
 }
 
 // PSMEvidenceList ...
@@ -188,6 +197,7 @@ type IonEvidence struct {
 	Weight                   float64
 	GroupWeight              float64
 	Intensity                float64
+	ApexIonMobility          float64
 	Probability              float64
 	Expectation              float64
 	SummedLabelIntensity     float64
@@ -290,6 +300,8 @@ type ProteinEvidence struct {
 	PhosphoUniqueLabels    iso.Labels
 	PhosphoURazorLabels    iso.Labels // Unique + razor
 	Modifications          mod.Modifications
+	Annotation             dat.Annotation
+	Aliases                []string
 }
 
 // ProteinEvidenceList list
@@ -464,6 +476,57 @@ func Run(m met.Data) {
 		repo.MzIdentMLReport(m.Version, m.Database.Annot)
 	}
 
+	// pepXML
+	if m.Report.PepXML == true {
+
+		// the analysis that produced the probabilities is recorded by the filter
+		prophet := strings.ToLower(m.Prophet)
+		if prophet != "interprophet" && prophet != "percolator" {
+			prophet = "peptideprophet"
+		}
+
+		repo.PepXMLReport(m.SearchEngine, m.Database.Annot, prophet, m.Report.Decoys, hasLoc)
+	}
+
+	// protXML
+	if m.Report.ProtXML == true {
+		if len(m.Filter.Pox) > 0 || m.Filter.Inference == true {
+			repo.ProtXMLReport(m.Version, m.Database.Annot, m.Report.Decoys, len(m.Filter.Pox) > 0)
+		} else {
+			msg.Custom(errors.New("No protein groups were filtered, the protXML output requires a protXML file or the protein inference"), "warning")
+		}
+	}
+
+	// MGF
+	if m.Report.MGF == true {
+
+		dir := m.Report.Dir
+		if len(dir) == 0 {
+			dir = m.Quantify.Dir
+		}
+
+		if len(dir) == 0 {
+			msg.InputNotFound(errors.New("You need to provide the path to the raw files to create the MGF output"), "error")
+		} else {
+			repo.MGFReport(dir, m.Report.Decoys)
+		}
+	}
+
+	// mzML
+	if m.Report.MzML == true {
+
+		dir := m.Report.Dir
+		if len(dir) == 0 {
+			dir = m.Quantify.Dir
+		}
+
+		if len(dir) == 0 {
+			msg.InputNotFound(errors.New("You need to provide the path to the mzML files to create the mzML output"), "error")
+		} else {
+			repo.MzMLReport(dir, m.Report.Decoys)
+		}
+	}
+
 	return
 }
 
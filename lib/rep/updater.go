@@ -2,8 +2,10 @@ package rep
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
+	"philosopher/lib/bio"
 	"philosopher/lib/dat"
 	"philosopher/lib/id"
 )
@@ -19,8 +21,14 @@ type PeptideMap struct {
 }
 
 // UpdateNumberOfEnzymaticTermini collects the NTT from ProteinProphet
-// and passes along to the final Protein structure.
-func (evi *Evidence) UpdateNumberOfEnzymaticTermini() {
+// and passes along to the final Protein structure. PSMs missing from the
+// ProteinProphet results get their NTT recomputed with the database enzyme.
+func (evi *Evidence) UpdateNumberOfEnzymaticTermini(enz string) {
+
+	var enzyme bio.Enzyme
+	if len(enz) > 0 {
+		enzyme.Synth(enz)
+	}
 
 	// restore the original prot.xml output
 	var p id.ProtIDList
@@ -44,6 +52,8 @@ func (evi *Evidence) UpdateNumberOfEnzymaticTermini() {
 		ntt, ok := nttPeptidetoProptein[key]
 		if ok {
 			evi.PSM[i].NumberOfEnzymaticTermini = int(ntt)
+		} else if len(enzyme.Rules) > 0 {
+			evi.PSM[i].NumberOfEnzymaticTermini = enzyme.NTT(evi.PSM[i].PrevAA, evi.PSM[i].Peptide, evi.PSM[i].NextAA)
 		}
 	}
 
@@ -236,6 +246,7 @@ func (evi *Evidence) UpdateLayerswithDatabase(decoyTag string) {
 	var entryNameMap = make(map[string]string)
 	var geneMap = make(map[string]string)
 	var descriptionMap = make(map[string]string)
+	var variantMap = make(map[string]dat.Record)
 
 	for _, j := range dtb.Records {
 		if j.IsDecoy == false {
@@ -243,6 +254,10 @@ func (evi *Evidence) UpdateLayerswithDatabase(decoyTag string) {
 			entryNameMap[j.PartHeader] = j.EntryName
 			geneMap[j.PartHeader] = j.GeneNames
 			descriptionMap[j.PartHeader] = j.Description
+
+			if len(j.Variant) > 0 {
+				variantMap[j.PartHeader] = j
+			}
 		}
 	}
 
@@ -258,6 +273,11 @@ func (evi *Evidence) UpdateLayerswithDatabase(decoyTag string) {
 		evi.PSM[i].GeneName = geneMap[id]
 		evi.PSM[i].ProteinDescription = descriptionMap[id]
 
+		// variant entries with the changed residues covered by the peptide
+		if len(variantMap) > 0 && !evi.PSM[i].IsDecoy {
+			evi.PSM[i].Variants = variantList(variantMap, evi.PSM[i].Peptide, id, evi.PSM[i].MappedProteins)
+		}
+
 		// update mapped genes
 		for k := range evi.PSM[i].MappedProteins {
 			if !strings.Contains(k, decoyTag) {
@@ -432,3 +452,35 @@ func (evi *Evidence) UpdatePeptideModCount() {
 
 	return
 }
+
+// variantList lists the variants covered by a peptide among the protein and its mapped
+// proteins, e.g. P01308:R54C
+func variantList(variantMap map[string]dat.Record, peptide, protein string, mapped map[string]int) []string {
+
+	var proteins = []string{protein}
+	for k := range mapped {
+		if k != protein {
+			proteins = append(proteins, k)
+		}
+	}
+
+	var list []string
+	var seen = make(map[string]bool)
+
+	for _, i := range proteins {
+		r, ok := variantMap[i]
+		if !ok || !r.CoversVariant(peptide) {
+			continue
+		}
+
+		v := r.ID + ":" + r.Variant
+		if !seen[v] {
+			seen[v] = true
+			list = append(list, v)
+		}
+	}
+
+	sort.Strings(list)
+
+	return list
+}
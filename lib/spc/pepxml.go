@@ -147,9 +147,10 @@ type SearchSummary struct {
 
 // SearchDatabase tag
 type SearchDatabase struct {
-	XMLName   xml.Name `xml:"search_database"`
-	LocalPath []byte   `xml:"local_path,attr"`
-	Type      []byte   `xml:"type,attr"`
+	XMLName         xml.Name `xml:"search_database"`
+	LocalPath       []byte   `xml:"local_path,attr"`
+	Type            []byte   `xml:"type,attr"`
+	SizeInDBEntries int      `xml:"size_in_db_entries,attr"`
 }
 
 // EnzymaticSearchConstraint tag
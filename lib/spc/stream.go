@@ -0,0 +1,118 @@
+package spc
+
+import (
+	"bufio"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// PepXMLReader reads a pepXML file one spectrum query at a time. The analysis and search
+// summaries are collected as they appear in the file, so they are complete once the first
+// spectrum query is returned
+type PepXMLReader struct {
+	Name                 string
+	MsmsPipelineAnalysis MsmsPipelineAnalysis
+	file                 *os.File
+	decoder              *xml.Decoder
+}
+
+// OpenPepXML opens a pepXML file for streaming
+func OpenPepXML(f string) (*PepXMLReader, error) {
+
+	file, e := os.Open(f)
+	if e != nil {
+		return nil, e
+	}
+
+	decoder := xml.NewDecoder(bufio.NewReader(file))
+	decoder.CharsetReader = charset.NewReader
+
+	r := &PepXMLReader{
+		Name:    filepath.Base(f),
+		file:    file,
+		decoder: decoder,
+	}
+
+	return r, nil
+}
+
+// Next returns the next spectrum query, io.EOF is returned when there are no more queries
+func (r *PepXMLReader) Next() (SpectrumQuery, error) {
+
+	var sq SpectrumQuery
+	mpa := &r.MsmsPipelineAnalysis
+
+	for {
+		t, e := r.decoder.Token()
+		if e != nil {
+			return sq, e
+		}
+
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch se.Name.Local {
+		case "msms_pipeline_analysis":
+			mpa.Date = attr(se, "date")
+			mpa.SummaryXML = attr(se, "summary_xml")
+
+		case "analysis_summary":
+			var as AnalysisSummary
+			if e = r.decoder.DecodeElement(&as, &se); e != nil {
+				return sq, e
+			}
+			mpa.AnalysisSummary = append(mpa.AnalysisSummary, as)
+
+		case "msms_run_summary":
+			mpa.MsmsRunSummary.BaseName = attr(se, "base_name")
+			mpa.MsmsRunSummary.SearchEngine = attr(se, "search_engine")
+			mpa.MsmsRunSummary.MsManufacturer = attr(se, "msManufacturer")
+			mpa.MsmsRunSummary.MsModel = attr(se, "msModel")
+			mpa.MsmsRunSummary.MsIonization = attr(se, "msIonization")
+			mpa.MsmsRunSummary.MsMassAnalyzer = attr(se, "msMassAnalyzer")
+			mpa.MsmsRunSummary.MsDetector = attr(se, "msDetector")
+			mpa.MsmsRunSummary.RawDataType = attr(se, "raw_data_type")
+			mpa.MsmsRunSummary.RawData = attr(se, "raw_data")
+
+		case "sample_enzyme":
+			var enzyme SampleEnzyme
+			if e = r.decoder.DecodeElement(&enzyme, &se); e != nil {
+				return sq, e
+			}
+			mpa.MsmsRunSummary.SampleEnzyme = enzyme
+
+		case "search_summary":
+			var ss SearchSummary
+			if e = r.decoder.DecodeElement(&ss, &se); e != nil {
+				return sq, e
+			}
+			mpa.MsmsRunSummary.SearchSummary = ss
+
+		case "spectrum_query":
+			e = r.decoder.DecodeElement(&sq, &se)
+			return sq, e
+		}
+	}
+}
+
+// Close closes the underlying file
+func (r *PepXMLReader) Close() error {
+	return r.file.Close()
+}
+
+// attr returns the value of an attribute from an element
+func attr(se xml.StartElement, name string) []byte {
+
+	for _, i := range se.Attr {
+		if i.Name.Local == name {
+			return []byte(i.Value)
+		}
+	}
+
+	return nil
+}
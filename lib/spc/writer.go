@@ -0,0 +1,525 @@
+package spc
+
+import (
+	"bufio"
+	"encoding/xml"
+	"os"
+	"strconv"
+)
+
+// pepXMLNamespace is the namespace of the pepXML schema
+const pepXMLNamespace = "http://regis-web.systemsbiology.net/pepXML"
+
+// protXMLNamespace is the namespace of the protXML schema
+const protXMLNamespace = "http://regis-web.systemsbiology.net/protXML"
+
+// WritePepXML writes the analysis summaries and the given runs as a pepXML file. The
+// elements are written from the tags that carry a value, so that a search hit only
+// contains the analysis results it was given
+func WritePepXML(f string, mpa MsmsPipelineAnalysis, runs []MsmsRunSummary) error {
+
+	return writeXML(f, func(w *xmlWriter) {
+
+		var a attrs
+		a.text("xmlns", pepXMLNamespace)
+		a.bytes("date", mpa.Date)
+		a.bytes("summary_xml", mpa.SummaryXML)
+		w.start("msms_pipeline_analysis", a)
+
+		for _, i := range mpa.AnalysisSummary {
+			var a attrs
+			a.bytes("analysis", i.Analysis)
+			a.bytes("time", i.Time)
+			w.empty("analysis_summary", a)
+		}
+
+		for _, i := range runs {
+			w.runSummary(i)
+		}
+
+		w.end("msms_pipeline_analysis")
+	})
+}
+
+// writeXML creates the file and writes the XML declaration followed by the elements
+func writeXML(f string, elements func(w *xmlWriter)) error {
+
+	file, e := os.Create(f)
+	if e != nil {
+		return e
+	}
+	defer file.Close()
+
+	b := bufio.NewWriter(file)
+
+	if _, e := b.WriteString(xml.Header); e != nil {
+		return e
+	}
+
+	w := &xmlWriter{enc: xml.NewEncoder(b)}
+	w.enc.Indent("", " ")
+
+	elements(w)
+
+	if w.e != nil {
+		return w.e
+	}
+
+	if e := w.enc.Flush(); e != nil {
+		return e
+	}
+
+	return b.Flush()
+}
+
+// xmlWriter writes elements token by token and keeps the first encoding error
+type xmlWriter struct {
+	enc *xml.Encoder
+	e   error
+}
+
+// attrs is a list of attributes
+type attrs []xml.Attr
+
+func (w *xmlWriter) start(name string, a attrs) {
+	if w.e == nil {
+		w.e = w.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: a})
+	}
+}
+
+func (w *xmlWriter) end(name string) {
+	if w.e == nil {
+		w.e = w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+	}
+}
+
+func (w *xmlWriter) empty(name string, a attrs) {
+	w.start(name, a)
+	w.end(name)
+}
+
+// text adds a text attribute when it is not empty
+func (a *attrs) text(name, v string) {
+	if len(v) > 0 {
+		*a = append(*a, xml.Attr{Name: xml.Name{Local: name}, Value: v})
+	}
+}
+
+// bytes adds a text attribute when it is not empty
+func (a *attrs) bytes(name string, v []byte) {
+	a.text(name, string(v))
+}
+
+// number adds a numeric attribute when it is not zero
+func (a *attrs) number(name string, v float64) {
+	if v != 0 {
+		a.value(name, v)
+	}
+}
+
+// value adds a numeric attribute
+func (a *attrs) value(name string, v float64) {
+	*a = append(*a, xml.Attr{Name: xml.Name{Local: name}, Value: strconv.FormatFloat(v, 'f', -1, 64)})
+}
+
+// float adds a single precision numeric attribute
+func (a *attrs) float(name string, v float32) {
+	*a = append(*a, xml.Attr{Name: xml.Name{Local: name}, Value: strconv.FormatFloat(float64(v), 'f', -1, 32)})
+}
+
+func (w *xmlWriter) runSummary(r MsmsRunSummary) {
+
+	var a attrs
+	a.bytes("base_name", r.BaseName)
+	a.bytes("msManufacturer", r.MsManufacturer)
+	a.bytes("msModel", r.MsModel)
+	a.bytes("msIonization", r.MsIonization)
+	a.bytes("msMassAnalyzer", r.MsMassAnalyzer)
+	a.bytes("msDetector", r.MsDetector)
+	a.bytes("raw_data_type", r.RawDataType)
+	a.bytes("raw_data", r.RawData)
+	w.start("msms_run_summary", a)
+
+	if len(r.SampleEnzyme.Name) > 0 {
+		var a attrs
+		a.bytes("name", r.SampleEnzyme.Name)
+		w.start("sample_enzyme", a)
+
+		if len(r.SampleEnzyme.Specificity.Cut) > 0 {
+			var a attrs
+			a.bytes("cut", r.SampleEnzyme.Specificity.Cut)
+			a.bytes("no_cut", r.SampleEnzyme.Specificity.NoCut)
+			a.bytes("sense", r.SampleEnzyme.Specificity.Sense)
+			w.empty("specificity", a)
+		}
+
+		w.end("sample_enzyme")
+	}
+
+	w.searchSummary(r.SearchSummary)
+
+	for _, i := range r.SpectrumQuery {
+		w.spectrumQuery(i)
+	}
+
+	w.end("msms_run_summary")
+}
+
+func (w *xmlWriter) searchSummary(s SearchSummary) {
+
+	searchID := s.SearchID
+	if searchID == 0 {
+		searchID = 1
+	}
+
+	var a attrs
+	a.bytes("base_name", s.BaseName)
+	a.bytes("search_engine", s.SearchEngine)
+	a.bytes("search_engine_version", s.SearchEngineVersion)
+	a.text("precursor_mass_type", "monoisotopic")
+	a.text("fragment_mass_type", "monoisotopic")
+	a.value("search_id", float64(searchID))
+	w.start("search_summary", a)
+
+	if len(s.SearchDatabase.LocalPath) > 0 {
+		var a attrs
+		a.bytes("local_path", s.SearchDatabase.LocalPath)
+		a.text("type", "AA")
+		a.number("size_in_db_entries", float64(s.SearchDatabase.SizeInDBEntries))
+		w.empty("search_database", a)
+	}
+
+	for _, i := range s.EnzymaticSearchConstraint {
+		var a attrs
+		a.bytes("enzyme", i.Enzyme)
+		a.value("max_num_internal_cleavages", float64(i.MaxNumInternalCleavages))
+		a.value("min_number_termini", float64(i.MinNumTermini))
+		w.empty("enzymatic_search_constraint", a)
+	}
+
+	for _, i := range s.AminoAcidModifications {
+		var a attrs
+		a.bytes("aminoacid", i.AminoAcid)
+		a.value("massdiff", i.MassDiff)
+		a.value("mass", i.Mass)
+		a.bytes("variable", i.Variable)
+		w.empty("aminoacid_modification", a)
+	}
+
+	for _, i := range s.TerminalModifications {
+		var a attrs
+		a.bytes("terminus", i.Terminus)
+		a.value("massdiff", i.MassDiff)
+		a.value("mass", i.Mass)
+		a.bytes("variable", i.Variable)
+		a.bytes("protein_terminus", i.ProteinTerminus)
+		w.empty("terminal_modification", a)
+	}
+
+	for _, i := range s.Parameter {
+		var a attrs
+		a.text("name", i.Name)
+		a.text("value", i.Value)
+		w.empty("parameter", a)
+	}
+
+	w.end("search_summary")
+}
+
+func (w *xmlWriter) spectrumQuery(sq SpectrumQuery) {
+
+	var a attrs
+	a.bytes("spectrum", sq.Spectrum)
+	a.bytes("spectrumNativeID", sq.SpectrumNativeID)
+	a.value("start_scan", float64(sq.StartScan))
+	a.value("end_scan", float64(sq.EndScan))
+	a.number("uncalibrated_precursor_neutral_mass", sq.UncalibratedPrecursorNeutralMass)
+	a.value("precursor_neutral_mass", sq.PrecursorNeutralMass)
+	a.value("assumed_charge", float64(sq.AssumedCharge))
+	a.value("index", float64(sq.Index))
+	a.number("retention_time_sec", sq.RetentionTimeSec)
+	a.number("ion_mobility", sq.IonMobility)
+	w.start("spectrum_query", a)
+
+	w.start("search_result", nil)
+	for _, i := range sq.SearchResult.SearchHit {
+		w.searchHit(i)
+	}
+	w.end("search_result")
+
+	w.end("spectrum_query")
+}
+
+func (w *xmlWriter) searchHit(h SearchHit) {
+
+	var a attrs
+	a.value("hit_rank", float64(h.HitRank))
+	a.bytes("peptide", h.Peptide)
+	a.bytes("peptide_prev_aa", h.PrevAA)
+	a.bytes("peptide_next_aa", h.NextAA)
+	a.bytes("protein", h.Protein)
+	a.bytes("protein_descr", h.ProteinDescr)
+	a.value("num_tot_proteins", float64(h.TotalProteins))
+	a.number("num_matched_ions", float64(h.MatchedIons))
+	a.number("tot_num_ions", float64(h.TotalIons))
+	a.value("calc_neutral_pep_mass", h.CalcNeutralPepMass)
+	a.value("massdiff", h.Massdiff)
+	a.value("num_tol_term", float64(h.TotalTerm))
+	a.value("num_missed_cleavages", float64(h.MissedCleavages))
+	a.number("num_matched_peptides", float64(h.MatchedPeptides))
+	a.value("is_rejected", float64(h.IsRejected))
+	w.start("search_hit", a)
+
+	for _, i := range h.AlternativeProteins {
+		var a attrs
+		a.bytes("protein", i.Protein)
+		a.bytes("protein_descr", i.Description)
+		a.bytes("peptide_prev_aa", i.PepPrevAA)
+		a.bytes("peptide_next_aa", i.PepNextAA)
+		w.empty("alternative_protein", a)
+	}
+
+	m := h.ModificationInfo
+	if len(m.ModifiedPeptide) > 0 || len(m.ModAminoacidMass) > 0 || m.ModNTermMass != 0 || m.ModCTermMass != 0 {
+		w.modificationInfo(m)
+	}
+
+	for _, i := range h.Score {
+		var a attrs
+		a.bytes("name", i.Name)
+		a.text("value", i.Value)
+		w.empty("search_score", a)
+	}
+
+	for _, i := range h.AnalysisResult {
+		w.analysisResult(i)
+	}
+
+	w.end("search_hit")
+}
+
+func (w *xmlWriter) modificationInfo(m ModificationInfo) {
+
+	var a attrs
+	a.number("mod_nterm_mass", m.ModNTermMass)
+	a.number("mod_cterm_mass", m.ModCTermMass)
+	a.bytes("modified_peptide", m.ModifiedPeptide)
+	w.start("modification_info", a)
+
+	for _, i := range m.ModAminoacidMass {
+		var a attrs
+		a.value("position", float64(i.Position))
+		a.value("mass", i.Mass)
+		w.empty("mod_aminoacid_mass", a)
+	}
+
+	w.end("modification_info")
+}
+
+func (w *xmlWriter) analysisResult(r AnalysisResult) {
+
+	var a attrs
+	a.bytes("analysis", r.Analysis)
+	w.start("analysis_result", a)
+
+	switch string(r.Analysis) {
+	case "peptideprophet":
+		var a attrs
+		a.value("probability", r.PeptideProphetResult.Probability)
+		a.bytes("all_ntt_prob", r.PeptideProphetResult.AllNttProb)
+		w.empty("peptideprophet_result", a)
+
+	case "interprophet":
+		var a attrs
+		a.value("probability", r.InterProphetResult.Probability)
+		a.bytes("all_ntt_prob", r.InterProphetResult.AllNttProb)
+		w.empty("interprophet_result", a)
+
+	case "ptmprophet":
+		for _, i := range r.PTMProphetResult {
+			var a attrs
+			a.number("prior", i.Prior)
+			a.bytes("ptm", i.PTM)
+			a.bytes("ptm_peptide", i.PTMPeptide)
+			w.start("ptmprophet_result", a)
+
+			for _, j := range i.ModAminoAcidProbability {
+				var a attrs
+				a.value("position", float64(j.Position))
+				a.float("probability", j.Probability)
+				w.empty("mod_aminoacid_probability", a)
+			}
+
+			w.end("ptmprophet_result")
+		}
+	}
+
+	w.end("analysis_result")
+}
+
+// WriteProtXML writes the summary header and the protein groups as a protXML file
+func WriteProtXML(f string, ps ProteinSummary) error {
+
+	return writeXML(f, func(w *xmlWriter) {
+
+		var a attrs
+		a.text("xmlns", protXMLNamespace)
+		w.start("protein_summary", a)
+
+		w.proteinSummaryHeader(ps.ProteinSummaryHeader)
+
+		for _, i := range ps.ProteinGroup {
+			w.proteinGroup(i)
+		}
+
+		w.end("protein_summary")
+	})
+}
+
+func (w *xmlWriter) proteinSummaryHeader(h ProteinSummaryHeader) {
+
+	var a attrs
+	a.bytes("reference_database", h.ReferenceDatabase)
+	a.bytes("residue_substitution_list", h.ResidueSubstitutionList)
+	a.float("min_peptide_probability", h.MinPeptideProbability)
+	a.float("min_peptide_weight", h.MinPeptideWeight)
+	a.float("num_predicted_correct_prots", h.NumPredictedCorrectProteins)
+	a.value("num_input_1_spectra", float64(h.NumInput1Spectra))
+	a.value("num_input_2_spectra", float64(h.NumInput2Spectra))
+	a.value("num_input_3_spectra", float64(h.NumInput3Spectra))
+	a.value("num_input_4_spectra", float64(h.NumInput4Spectra))
+	a.value("num_input_5_spectra", float64(h.NumInput5Spectra))
+	a.float("total_no_spectrum_ids", h.TotalNumberSpectrumIDs)
+	a.bytes("sample_enzyme", h.SampleEnzyme)
+	w.start("protein_summary_header", a)
+
+	p := h.ProgramDetails
+	if len(p.Analysis) > 0 {
+		var a attrs
+		a.bytes("analysis", p.Analysis)
+		a.bytes("time", p.Time)
+		a.bytes("version", p.Version)
+		w.start("program_details", a)
+
+		d := p.ProteinProphetDetails
+		var da attrs
+		da.bytes("occam_flag", d.OccamFlag)
+		da.bytes("groups_flag", d.GroupsFlag)
+		da.bytes("degen_flag", d.DegenFlag)
+		da.bytes("nsp_flag", d.NSPFlag)
+		da.bytes("fpkm_flag", d.FPKMFlag)
+		da.bytes("initial_peptide_wt_iters", d.InitialPeptideWtIters)
+		da.bytes("nsp_distribution_iters", d.NspDistributionIters)
+		da.bytes("final_peptide_wt_iters", d.FinalPeptideWtIters)
+		da.bytes("run_options", d.RunOptions)
+		if len(da) > 0 {
+			w.empty("proteinprophet_details", da)
+		}
+
+		w.end("program_details")
+	}
+
+	w.end("protein_summary_header")
+}
+
+func (w *xmlWriter) proteinGroup(g ProteinGroup) {
+
+	var a attrs
+	a.value("group_number", float64(g.GroupNumber))
+	a.value("probability", g.Probability)
+	w.start("protein_group", a)
+
+	for _, i := range g.Protein {
+		w.protein(i)
+	}
+
+	w.end("protein_group")
+}
+
+func (w *xmlWriter) protein(p Protein) {
+
+	var a attrs
+	a.bytes("protein_name", p.ProteinName)
+	a.value("n_indistinguishable_proteins", float64(p.NumberIndistinguishableProteins))
+	a.value("probability", p.Probability)
+	a.float("percent_coverage", p.PercentCoverage)
+	a.bytes("unique_stripped_peptides", p.UniqueStrippedPeptides)
+	a.bytes("group_sibling_id", p.GroupSiblingID)
+	a.value("total_number_peptides", float64(p.TotalNumberPeptides))
+	a.value("total_number_distinct_peptides", float64(p.TotalNumberIndPeptides))
+	a.float("pct_spectrum_ids", p.PctSpectrumIDs)
+	w.start("protein", a)
+
+	if len(p.Parameter.Name) > 0 {
+		var a attrs
+		a.text("name", p.Parameter.Name)
+		a.text("value", p.Parameter.Value)
+		w.empty("parameter", a)
+	}
+
+	w.annotation(p.Annotation)
+
+	for _, i := range p.IndistinguishableProtein {
+		var a attrs
+		a.text("protein_name", i.ProteinName)
+		w.start("indistinguishable_protein", a)
+		w.annotation(i.Annotation)
+		w.end("indistinguishable_protein")
+	}
+
+	for _, i := range p.Peptide {
+		w.peptide(i)
+	}
+
+	w.end("protein")
+}
+
+func (w *xmlWriter) annotation(n Annotation) {
+	if len(n.ProteinDescription) > 0 {
+		var a attrs
+		a.bytes("protein_description", n.ProteinDescription)
+		w.empty("annotation", a)
+	}
+}
+
+func (w *xmlWriter) peptide(p Peptide) {
+
+	var a attrs
+	a.bytes("peptide_sequence", p.PeptideSequence)
+	a.value("charge", float64(p.Charge))
+	a.value("initial_probability", p.InitialProbability)
+	a.float("nsp_adjusted_probability", p.NSPAdjustedPprobability)
+	a.float("fpkm_adjusted_probability", p.FPKMAdjustedProbability)
+	a.value("weight", p.Weight)
+	a.value("group_weight", p.GroupWeight)
+	a.bytes("is_nondegenerate_evidence", p.IsNondegenerateEvidence)
+	a.value("n_enzymatic_termini", float64(p.NEnzymaticTermini))
+	a.float("n_sibling_peptides", p.NSiblingPeptides)
+	a.float("n_sibling_peptides_bin", p.NSiblingPeptidesBin)
+	a.value("n_instances", float64(p.NIstances))
+	a.float("exp_tot_instances", p.ExpTotInstances)
+	a.bytes("is_contributing_evidence", p.IsContributingEvidence)
+	a.value("calc_neutral_pep_mass", p.CalcNeutralPepMass)
+	w.start("peptide", a)
+
+	for _, i := range p.PeptideParentProtein {
+		var a attrs
+		a.bytes("protein_name", i.ProteinName)
+		w.empty("peptide_parent_protein", a)
+	}
+
+	for _, i := range p.IndistinguishablePeptide {
+		var a attrs
+		a.bytes("peptide_sequence", i.PeptideSequence)
+		a.value("charge", float64(i.Charge))
+		a.float("calc_neutral_pep_mass", i.CalcNeutralPepMass)
+		w.empty("indistinguishable_peptide", a)
+	}
+
+	m := p.ModificationInfo
+	if len(m.ModifiedPeptide) > 0 || len(m.ModAminoacidMass) > 0 || m.ModNTermMass != 0 || m.ModCTermMass != 0 {
+		w.modificationInfo(m)
+	}
+
+	w.end("peptide")
+}
@@ -0,0 +1,229 @@
+package spc
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWritePepXML(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "pepxml")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "psm.pepXML")
+
+	mpa := MsmsPipelineAnalysis{
+		Date:            []byte("2020-01-01T00:00:00Z"),
+		AnalysisSummary: []AnalysisSummary{{Analysis: []byte("interprophet")}, {Analysis: []byte("ptmprophet")}},
+	}
+
+	hit := SearchHit{
+		HitRank:            1,
+		Peptide:            []byte("MSTYK"),
+		Protein:            []byte("sp|P1|A_HUMAN"),
+		TotalProteins:      2,
+		CalcNeutralPepMass: 628.2716,
+		Massdiff:           0.0012,
+		AlternativeProteins: []AlternativeProtein{
+			{Protein: []byte("sp|P2|B_HUMAN"), Description: []byte("Protein <B> & co")},
+		},
+		ModificationInfo: ModificationInfo{
+			ModNTermMass:     43.0184,
+			ModifiedPeptide:  []byte("n[43]M[147]STYK"),
+			ModAminoacidMass: []ModAminoacidMass{{Position: 1, Mass: 147.0354}},
+		},
+		Score: []SearchScore{{Name: []byte("hyperscore"), Value: "32.1"}},
+		AnalysisResult: []AnalysisResult{
+			{Analysis: []byte("interprophet"), InterProphetResult: InterProphetResult{Probability: 0.9987}},
+			{Analysis: []byte("ptmprophet"), PTMProphetResult: []PTMProphetResult{{PTM: []byte("STY:79.9663"), PTMPeptide: []byte("MS(0.95)T(0.05)YK")}}},
+		},
+	}
+
+	run := MsmsRunSummary{
+		BaseName: []byte("run"),
+		SearchSummary: SearchSummary{
+			SearchEngine:           []byte("X! Tandem"),
+			SearchDatabase:         SearchDatabase{LocalPath: []byte("/data/db.fas")},
+			AminoAcidModifications: []AminoacidModification{{AminoAcid: []byte("M"), MassDiff: 15.9949, Mass: 147.0354, Variable: []byte("Y")}},
+		},
+		SpectrumQuery: []SpectrumQuery{{
+			Spectrum:             []byte("run.00010.00010.2"),
+			StartScan:            10,
+			EndScan:              10,
+			PrecursorNeutralMass: 628.2728,
+			AssumedCharge:        2,
+			Index:                1,
+			RetentionTimeSec:     600.5,
+			SearchResult:         SearchResult{SearchHit: []SearchHit{hit}},
+		}},
+	}
+
+	if e := WritePepXML(f, mpa, []MsmsRunSummary{run}); e != nil {
+		t.Fatal(e)
+	}
+
+	b, e := ioutil.ReadFile(f)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for _, i := range []string{"peptideprophet_result", "search_score_summary", "mod_cterm_mass", "ion_mobility"} {
+		if strings.Contains(string(b), i) {
+			t.Errorf("empty %s written", i)
+		}
+	}
+
+	r, e := OpenPepXML(f)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer r.Close()
+
+	sq, e := r.Next()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if string(sq.Spectrum) != "run.00010.00010.2" || sq.AssumedCharge != 2 || sq.RetentionTimeSec != 600.5 {
+		t.Errorf("spectrum query = %s %d %f", sq.Spectrum, sq.AssumedCharge, sq.RetentionTimeSec)
+	}
+
+	got := sq.SearchResult.SearchHit[0]
+
+	if string(got.Peptide) != "MSTYK" || got.Massdiff != 0.0012 || got.TotalProteins != 2 {
+		t.Errorf("search hit = %s %f %d", got.Peptide, got.Massdiff, got.TotalProteins)
+	}
+
+	if len(got.AlternativeProteins) != 1 || string(got.AlternativeProteins[0].Description) != "Protein <B> & co" {
+		t.Errorf("alternative proteins = %+v", got.AlternativeProteins)
+	}
+
+	if got.ModificationInfo.ModNTermMass != 43.0184 || len(got.ModificationInfo.ModAminoacidMass) != 1 || got.ModificationInfo.ModAminoacidMass[0].Mass != 147.0354 {
+		t.Errorf("modification info = %+v", got.ModificationInfo)
+	}
+
+	if len(got.AnalysisResult) != 2 || got.AnalysisResult[0].InterProphetResult.Probability != 0.9987 || string(got.AnalysisResult[1].PTMProphetResult[0].PTMPeptide) != "MS(0.95)T(0.05)YK" {
+		t.Errorf("analysis results = %+v", got.AnalysisResult)
+	}
+
+	mods := r.MsmsPipelineAnalysis.MsmsRunSummary.SearchSummary.AminoAcidModifications
+	if len(mods) != 1 || mods[0].Mass != 147.0354 || len(r.MsmsPipelineAnalysis.AnalysisSummary) != 2 {
+		t.Errorf("summaries = %+v", r.MsmsPipelineAnalysis)
+	}
+
+	if _, e := r.Next(); e != io.EOF {
+		t.Errorf("got %v after the last spectrum query", e)
+	}
+}
+
+func TestWriteProtXML(t *testing.T) {
+
+	dir, e := ioutil.TempDir("", "protxml")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "protein.protXML")
+
+	var ps ProteinSummary
+	ps.ProteinSummaryHeader.ReferenceDatabase = []byte("/data/db.fas")
+	ps.ProteinSummaryHeader.ProgramDetails.Analysis = []byte("proteinprophet")
+
+	ps.ProteinGroup = []ProteinGroup{{
+		GroupNumber: 1,
+		Probability: 0.99,
+		Protein: []Protein{{
+			ProteinName:                     []byte("sp|P1|A_HUMAN"),
+			NumberIndistinguishableProteins: 2,
+			Probability:                     0.99,
+			PercentCoverage:                 12.5,
+			UniqueStrippedPeptides:          []byte("MSTYK+PEPTIDEK"),
+			GroupSiblingID:                  []byte("a"),
+			TotalNumberPeptides:             3,
+			TotalNumberIndPeptides:          2,
+			Parameter:                       Parameter{Name: "prot_length", Value: "120"},
+			Annotation:                      Annotation{ProteinDescription: []byte("Protein A")},
+			IndistinguishableProtein:        []IndistinguishableProtein{{ProteinName: "sp|P2|B_HUMAN"}},
+			Peptide: []Peptide{
+				{
+					PeptideSequence:         []byte("MSTYK"),
+					Charge:                  2,
+					InitialProbability:      0.98,
+					Weight:                  0.5,
+					GroupWeight:             0.5,
+					IsNondegenerateEvidence: []byte("N"),
+					NIstances:               2,
+					CalcNeutralPepMass:      628.2716,
+					PeptideParentProtein:    []PeptideParentProtein{{ProteinName: []byte("sp|P3|C_HUMAN")}},
+					ModificationInfo:        ModificationInfo{ModifiedPeptide: []byte("M[147]STYK")},
+				},
+				{
+					PeptideSequence:         []byte("PEPTIDEK"),
+					Charge:                  2,
+					InitialProbability:      0.95,
+					Weight:                  1,
+					GroupWeight:             1,
+					IsNondegenerateEvidence: []byte("Y"),
+					NIstances:               1,
+					CalcNeutralPepMass:      927.4549,
+				},
+			},
+		}},
+	}}
+
+	if e := WriteProtXML(f, ps); e != nil {
+		t.Fatal(e)
+	}
+
+	var p ProtXML
+	p.Parse(f)
+
+	h := p.ProteinSummary.ProteinSummaryHeader
+	if string(h.ReferenceDatabase) != "/data/db.fas" || string(h.ProgramDetails.Analysis) != "proteinprophet" {
+		t.Errorf("header = %+v", h)
+	}
+
+	if len(p.ProteinSummary.ProteinGroup) != 1 || len(p.ProteinSummary.ProteinGroup[0].Protein) != 1 {
+		t.Fatalf("groups = %+v", p.ProteinSummary.ProteinGroup)
+	}
+
+	g := p.ProteinSummary.ProteinGroup[0]
+	pro := g.Protein[0]
+
+	if g.GroupNumber != 1 || g.Probability != 0.99 || string(pro.ProteinName) != "sp|P1|A_HUMAN" || string(pro.GroupSiblingID) != "a" {
+		t.Errorf("protein = %d %f %s %s", g.GroupNumber, g.Probability, pro.ProteinName, pro.GroupSiblingID)
+	}
+
+	if pro.PercentCoverage != 12.5 || pro.Parameter.Value != "120" || string(pro.Annotation.ProteinDescription) != "Protein A" {
+		t.Errorf("protein details = %f %s %s", pro.PercentCoverage, pro.Parameter.Value, pro.Annotation.ProteinDescription)
+	}
+
+	if len(pro.IndistinguishableProtein) != 1 || pro.IndistinguishableProtein[0].ProteinName != "sp|P2|B_HUMAN" {
+		t.Errorf("indistinguishable proteins = %+v", pro.IndistinguishableProtein)
+	}
+
+	if len(pro.Peptide) != 2 {
+		t.Fatalf("got %d peptides, want 2", len(pro.Peptide))
+	}
+
+	pep := pro.Peptide[0]
+	if pep.Weight != 0.5 || pep.NIstances != 2 || pep.CalcNeutralPepMass != 628.2716 || string(pep.ModificationInfo.ModifiedPeptide) != "M[147]STYK" {
+		t.Errorf("peptide = %+v", pep)
+	}
+
+	if len(pep.PeptideParentProtein) != 1 || string(pep.PeptideParentProtein[0].ProteinName) != "sp|P3|C_HUMAN" {
+		t.Errorf("parent proteins = %+v", pep.PeptideParentProtein)
+	}
+
+	if string(pro.Peptide[1].IsNondegenerateEvidence) != "Y" || pro.Peptide[1].Weight != 1 {
+		t.Errorf("peptide = %+v", pro.Peptide[1])
+	}
+}
@@ -3,7 +3,9 @@ package wmm
 import (
 	"fmt"
 	"os"
+	"philosopher/lib/bio"
 	"philosopher/lib/met"
+	"strings"
 )
 
 // Run executes the Filter processing
@@ -45,7 +47,27 @@ func writeDatabase(d met.Database, text string) string {
 		text = fmt.Sprintf("%s A list of 153 common contaminants was also added to the database.", text)
 	}
 
-	text = fmt.Sprintf("%s Decoy entries were generated by reversing the protein sequences and adding the %s prefix to their headers.", text, d.Tag)
+	if len(d.ContamFiles) > 0 {
+		text = fmt.Sprintf("%s Contaminant sequences from %s were also added to the database.", text, strings.Replace(d.ContamFiles, ",", ", ", -1))
+	}
+
+	if d.Crap == true || len(d.ContamFiles) > 0 {
+		text = fmt.Sprintf("%s Contaminant entries replaced the entries with the same accession and were marked with the %s prefix.", text, d.ContamTag)
+	}
+
+	var enzyme bio.Enzyme
+	if d.DecoyMethod == "pseudo-reverse" || d.DecoyMethod == "shuffle" {
+		enzyme.Synth(d.Enz)
+	}
+
+	switch d.DecoyMethod {
+	case "pseudo-reverse":
+		text = fmt.Sprintf("%s Decoy entries were generated by reversing the %s peptide sequences while keeping the cleavage residues in place, and adding the %s prefix to their headers.", text, enzyme.Description(), d.Tag)
+	case "shuffle":
+		text = fmt.Sprintf("%s Decoy entries were generated by shuffling the %s peptide sequences while keeping the cleavage residues in place (random seed %d), reshuffling peptides that matched a target peptide, and adding the %s prefix to their headers.", text, enzyme.Description(), d.DecoySeed, d.Tag)
+	default:
+		text = fmt.Sprintf("%s Decoy entries were generated by reversing the protein sequences and adding the %s prefix to their headers.", text, d.Tag)
+	}
 
 	// appending new line before returning
 	text = text + "\n"